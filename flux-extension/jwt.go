@@ -55,6 +55,7 @@ func (f *JWTFilter) Init(config *flux.Configuration) error {
 	if "" == f.Config.AttKeyPrefix {
 		f.Config.AttKeyPrefix = cast.ToString(config.GetOrDefault(ConfigKeyAttachmentKey, "jwt"))
 	}
+	common.SetClaimAttributeKeyPrefix(f.Config.AttKeyPrefix)
 	fluxpkg.AssertNotNil(f.Config.SecretKeyLoader, "<secret-loader> must not nil")
 	return nil
 }
@@ -0,0 +1,26 @@
+package fluxinspect
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	fluxhttp "github.com/bytepowered/flux/flux-node/transporter/http"
+)
+
+const (
+	upsQueryKeyHost = "host"
+)
+
+// UpstreamsHandler 查询Http后端服务实例的负载均衡与健康状态
+func UpstreamsHandler(ctx flux.ServerWebContext) error {
+	query := ctx.QueryVar(upsQueryKeyHost)
+	states := fluxhttp.UpstreamStates()
+	if "" == query {
+		return send(ctx, flux.StatusOK, states)
+	}
+	outs := make([]fluxhttp.TargetState, 0, len(states))
+	for _, state := range states {
+		if queryMatch(query, state.Host) || queryMatch(query, state.Upstream) {
+			outs = append(outs, state)
+		}
+	}
+	return send(ctx, flux.StatusOK, outs)
+}
@@ -1,6 +1,9 @@
 package flux
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // Resolve 解析Argument参数值
 func (a Argument) Resolve(ctx *Context) (interface{}, error) {
@@ -29,7 +32,19 @@ func (a Argument) Resolve(ctx *Context) (interface{}, error) {
 		}
 		return a.ValueResolver(mtv, a.Class, a.Generic)
 	}
-	// POJO Values
+	// POJO Values: bind from body
+	if a.Type == ArgumentTypeComplex && strings.ToUpper(a.HttpScope) == ScopeBody && a.GetAttr(ArgumentAttributeTagJSONBinding).GetBool() {
+		key := a.HttpName
+		if "" == key {
+			key = a.Name
+		}
+		mtv, err := a.LookupFunc(a.HttpScope, key, ctx)
+		if nil != err {
+			return nil, err
+		}
+		return a.ValueResolver(mtv, a.Class, a.Generic)
+	}
+	// POJO Values: lookup fields
 	sm := make(map[string]interface{}, len(a.Fields))
 	sm["class"] = a.Class
 	for _, field := range a.Fields {
@@ -0,0 +1,175 @@
+package common
+
+import (
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/ext"
+	"io"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	// ConfigKeyKeepUnknownFields JSON绑定时，是否保留参数结构中未声明的字段
+	ConfigKeyKeepUnknownFields = "json_binding.keep_unknown_fields"
+)
+
+var keepUnknownFields int32
+
+// ConfigureJSONBinding 配置JSON请求体绑定复杂参数的默认行为
+func ConfigureJSONBinding(config *flux.Configuration) {
+	if config.GetBool(ConfigKeyKeepUnknownFields) {
+		atomic.StoreInt32(&keepUnknownFields, 1)
+	} else {
+		atomic.StoreInt32(&keepUnknownFields, 0)
+	}
+}
+
+// IsJSONBindingArgument 判断参数是否从请求体绑定：值域为BODY，定义了子结构字段，并且开启 json_binding 属性的复杂参数
+func IsJSONBindingArgument(arg flux.Argument) bool {
+	return arg.Type == flux.ArgumentTypeComplex && strings.ToUpper(arg.HttpScope) == flux.ScopeBody && len(arg.Fields) > 0 &&
+		arg.GetAttr(flux.ArgumentAttributeTagJSONBinding).GetBool()
+}
+
+// NewJSONBindingResolver 构建复杂参数的JSON绑定解析函数：
+// 1. 请求体解析为JSON对象，参数属性 bind_path 指定绑定子树的JSONPath路径；
+// 2. 按参数的Fields结构递归绑定，字段值由字段类型的解析函数转换；
+// 3. 嵌套对象，以及元素为对象的泛型集合，添加 class 类型标记；
+func NewJSONBindingResolver(arg flux.Argument) flux.MTValueResolver {
+	path := arg.GetAttr(flux.ArgumentAttributeTagBindPath).GetString()
+	return func(mtValue flux.MTValue, class string, generic []string) (interface{}, error) {
+		if !mtValue.Valid || isEmptyOrNil(mtValue.Value) {
+			return bindObject(arg.Fields, class, map[string]interface{}{}, false)
+		}
+		node, err := bindingNodeOf(arg, path, mtValue)
+		if nil != err {
+			return nil, err
+		}
+		return bindJSONNode(arg, class, generic, node)
+	}
+}
+
+// bindJSONNode 按参数的Fields结构绑定JSON子树
+func bindJSONNode(arg flux.Argument, class string, generic []string, node interface{}) (interface{}, error) {
+	keep := atomic.LoadInt32(&keepUnknownFields) == 1
+	if attr, ok := arg.GetAttrEx(flux.ArgumentAttributeTagKeepUnknown); ok {
+		keep = attr.GetBool()
+	}
+	return bindValue(flux.Argument{Name: arg.Name, Class: class, Generic: generic, Fields: arg.Fields}, node, keep)
+}
+
+// bindingNodeOf 解析请求体，返回绑定路径对应的JSON子树；子树不存在时，返回空对象；
+// 请求体为JSON数组时，只能绑定到集合类型(定义了泛型)的参数；
+func bindingNodeOf(arg flux.Argument, path string, mtValue flux.MTValue) (interface{}, error) {
+	root, err := bindingRootOf(mtValue)
+	if nil != err {
+		return nil, fmt.Errorf("cannot decode body to bind argument, name: %s, error: %w", arg.Name, err)
+	}
+	node, ok := LookupJSONPath(root, path)
+	if !ok || nil == node {
+		node = map[string]interface{}{}
+	}
+	if _, isArray := node.([]interface{}); isArray && len(arg.Generic) == 0 {
+		return nil, fmt.Errorf("cannot bind json array to non-collection argument, name: %s, class: %s", arg.Name, arg.Class)
+	}
+	return node, nil
+}
+
+// bindingRootOf 解析JSON请求体的根节点：JSON对象或者JSON数组
+func bindingRootOf(mtValue flux.MTValue) (interface{}, error) {
+	switch v := mtValue.Value.(type) {
+	case []interface{}:
+		return v, nil
+	case io.Reader:
+		// 请求体Reader只能读取一次
+		data, err := toByteArray(v)
+		if nil != err {
+			return nil, err
+		}
+		mtValue.Value = data
+	}
+	// 顶层为JSON数组的请求体
+	if data, err := toByteArray0(mtValue.Value); nil == err && strings.HasPrefix(strings.TrimSpace(string(data)), "[") {
+		list := make([]interface{}, 0)
+		if err := ext.JSONUnmarshal(data, &list); nil != err {
+			return nil, err
+		}
+		return list, nil
+	}
+	return ToStringMapE(mtValue)
+}
+
+// LookupJSONPath 按JSONPath路径查找JSON对象的子树；路径为空时，返回根节点；
+func LookupJSONPath(root interface{}, path string) (interface{}, bool) {
+	node, ok, err := EvalJSONPath(root, path)
+	return node, ok && nil == err
+}
+
+func bindValue(field flux.Argument, raw interface{}, keep bool) (interface{}, error) {
+	// 字段值
+	if len(field.Fields) == 0 {
+		resolver := field.ValueResolver
+		if nil == resolver {
+			resolver = ext.MTValueResolverByType(field.Class)
+		}
+		mtv := flux.WrapObjectMTValue(raw)
+		if nil == raw {
+			if attr, ok := field.GetAttrEx(flux.ArgumentAttributeTagDefault); ok {
+				mtv = flux.WrapStringMTValue(attr.GetString())
+			}
+		}
+		return resolver(mtv, field.Class, field.Generic)
+	}
+	// 嵌套对象或者对象集合
+	switch v := raw.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		return bindObject(field.Fields, field.Class, v, keep)
+	case []interface{}:
+		class := field.Class
+		if len(field.Generic) > 0 {
+			class = field.Generic[0]
+		}
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			obj, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("cannot bind non-object element to class: %s, field: %s, index: %d", class, field.Name, i)
+			}
+			value, err := bindObject(field.Fields, class, obj, keep)
+			if nil != err {
+				return nil, err
+			}
+			out[i] = value
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("cannot bind value to class: %s, field: %s, value.type: %T", field.Class, field.Name, raw)
+	}
+}
+
+func bindObject(fields []flux.Argument, class string, node map[string]interface{}, keep bool) (map[string]interface{}, error) {
+	out := make(map[string]interface{}, len(fields)+1)
+	if keep {
+		for k, v := range node {
+			out[k] = v
+		}
+	}
+	for _, field := range fields {
+		name := field.HttpName
+		if "" == name {
+			name = field.Name
+		}
+		if keep && name != field.Name {
+			delete(out, name)
+		}
+		value, err := bindValue(field, node[name], keep)
+		if nil != err {
+			return nil, err
+		}
+		out[field.Name] = value
+	}
+	out["class"] = class
+	return out, nil
+}
@@ -0,0 +1,113 @@
+package common
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/ext"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	assert2 "github.com/stretchr/testify/assert"
+)
+
+func newBindingArgument() flux.Argument {
+	items := ext.NewComplexArgument(flux.JavaUtilListClassName, "items")
+	items.Generic = []string{"com.foo.Item"}
+	items.Fields = []flux.Argument{
+		ext.NewPrimitiveArgument(flux.JavaLangStringClassName, "sku"),
+		ext.NewPrimitiveArgument(flux.JavaLangIntegerClassName, "count"),
+	}
+	buyer := ext.NewComplexArgument("com.foo.Buyer", "buyer")
+	buyer.Fields = []flux.Argument{
+		ext.NewPrimitiveArgument(flux.JavaLangLongClassName, "id"),
+	}
+	order := ext.NewComplexArgument("com.foo.Order", "order")
+	order.HttpScope = flux.ScopeBody
+	order.LookupFunc = LookupMTValue
+	order.Attributes = []flux.Attribute{{Name: flux.ArgumentAttributeTagJSONBinding, Value: true}}
+	order.Fields = []flux.Argument{
+		ext.NewPrimitiveArgument(flux.JavaLangStringClassName, "orderNo"),
+		buyer, items,
+	}
+	return order
+}
+
+func TestJSONBindingResolver(t *testing.T) {
+	ext.RegisterSerializer(ext.TypeNameSerializerJson, flux.NewJsonSerializer())
+	assert := assert2.New(t)
+	arg := newBindingArgument()
+	assert.True(IsJSONBindingArgument(arg))
+	body := `{"data":{"orderNo":"N001","remark":"x","buyer":{"id":"1001"},"items":[{"sku":"A","count":"2"}]}}`
+	arg.Attributes = append(arg.Attributes, flux.Attribute{Name: flux.ArgumentAttributeTagBindPath, Value: "data"})
+	resolver := NewJSONBindingResolver(arg)
+	mtv := flux.MTValue{Valid: true, Value: body, MediaType: flux.ValueMediaTypeGoString}
+	v, err := resolver(mtv, arg.Class, arg.Generic)
+	assert.NoError(err)
+	assert.Equal(map[string]interface{}{
+		"class":   "com.foo.Order",
+		"orderNo": "N001",
+		"buyer":   map[string]interface{}{"class": "com.foo.Buyer", "id": int64(1001)},
+		"items": []interface{}{
+			map[string]interface{}{"class": "com.foo.Item", "sku": "A", "count": 2},
+		},
+	}, v)
+	// keep unknown fields
+	arg.Attributes = append(arg.Attributes, flux.Attribute{Name: flux.ArgumentAttributeTagKeepUnknown, Value: true})
+	v, err = NewJSONBindingResolver(arg)(mtv, arg.Class, arg.Generic)
+	assert.NoError(err)
+	assert.Equal("x", v.(map[string]interface{})["remark"])
+}
+
+func TestJSONBindingResolver_Invalid(t *testing.T) {
+	ext.RegisterSerializer(ext.TypeNameSerializerJson, flux.NewJsonSerializer())
+	assert := assert2.New(t)
+	arg := newBindingArgument()
+	resolver := NewJSONBindingResolver(arg)
+	_, err := resolver(flux.WrapStringMTValue(`{"items":[1]}`), arg.Class, arg.Generic)
+	assert.Error(err)
+	_, err = resolver(flux.WrapStringMTValue(`{"buyer":{"id":"abc"}}`), arg.Class, arg.Generic)
+	assert.Error(err)
+}
+
+func TestIsJSONBindingArgument_OptIn(t *testing.T) {
+	arg := newBindingArgument()
+	arg.Attributes = nil
+	assert2.False(t, IsJSONBindingArgument(arg))
+}
+
+func TestJSONBindingResolver_ArrayBody(t *testing.T) {
+	ext.RegisterSerializer(ext.TypeNameSerializerJson, flux.NewJsonSerializer())
+	assert := assert2.New(t)
+	body := flux.MTValue{Valid: true, Value: ioutil.NopCloser(strings.NewReader(`[{"sku":"A","count":"2"}]`)), MediaType: flux.MIMEApplicationJSON}
+	// 集合参数
+	items := newBindingArgument().Fields[2]
+	items.HttpScope = flux.ScopeBody
+	items.Attributes = []flux.Attribute{{Name: flux.ArgumentAttributeTagJSONBinding, Value: true}}
+	v, err := NewJSONBindingResolver(items)(body, items.Class, items.Generic)
+	assert.NoError(err)
+	assert.Equal([]interface{}{
+		map[string]interface{}{"class": "com.foo.Item", "sku": "A", "count": 2},
+	}, v)
+	// 非集合参数
+	order := newBindingArgument()
+	_, err = NewJSONBindingResolver(order)(flux.WrapStringMTValue(`[{"orderNo":"N1"}]`), order.Class, order.Generic)
+	assert.Error(err)
+	ctx := newValidateContext("", `[{"orderNo":"N1"}]`)
+	serr := ValidateArguments(ctx, []flux.Argument{order})
+	assert.NotNil(serr)
+	assert.Equal(flux.StatusBadRequest, serr.StatusCode)
+	assert.Equal([]FieldError{{Field: "order", Reason: "invalid json body"}}, serr.Details)
+}
+
+func TestLookupJSONPath(t *testing.T) {
+	assert := assert2.New(t)
+	root := map[string]interface{}{"a": map[string]interface{}{"b": "c"}}
+	v, ok := LookupJSONPath(root, "a.b")
+	assert.True(ok)
+	assert.Equal("c", v)
+	_, ok = LookupJSONPath(root, "a.x")
+	assert.False(ok)
+	v, ok = LookupJSONPath(root, "")
+	assert.True(ok)
+	assert.Equal(root, v)
+}
@@ -0,0 +1,475 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/spf13/cast"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 表达式的扩展值域
+const (
+	ExprScopeNow = "NOW" // 当前时间：now.unix, now.millis, now.rfc3339, now.date
+)
+
+var errExprUnexpectedEnd = errors.New("unexpected end of expression")
+
+// ValueExpr 参数值表达式；由文本和 ${...} 表达式片段组成；
+// 表达式支持：
+// 1. 值域引用：scope.key，例如 header.X-Uid, query.id, attr.jwt.sub, claim.sub, now.unix；
+// 2. 字面量：'text', "text", 123, true, false, null；
+// 3. 运算符：+ 连接(数值相加)，?? 默认值，cond ? a : b 条件，==, !=, &&, ||, ! 以及括号；
+// 表达式只包含单个 ${...} 片段时，返回表达式值的原始类型；否则返回连接后的字符串；
+type ValueExpr struct {
+	text  string
+	parts []exprNode
+}
+
+type exprNode interface {
+	eval(ctx *flux.Context) (interface{}, error)
+}
+
+// CompileValueExpr 编译参数值表达式
+func CompileValueExpr(text string) (*ValueExpr, error) {
+	parts := make([]exprNode, 0, 2)
+	rest := text
+	for len(rest) > 0 {
+		start := strings.Index(rest, "${")
+		if start < 0 {
+			parts = append(parts, literalNode{value: rest})
+			break
+		}
+		if start > 0 {
+			parts = append(parts, literalNode{value: rest[:start]})
+		}
+		end := exprEndOf(rest, start+2)
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed expression: %s", text)
+		}
+		node, err := parseExpr(rest[start+2 : end])
+		if nil != err {
+			return nil, fmt.Errorf("illegal expression: %s, error: %w", text, err)
+		}
+		parts = append(parts, node)
+		rest = rest[end+1:]
+	}
+	return &ValueExpr{text: text, parts: parts}, nil
+}
+
+// Evaluate 在请求上下文中计算表达式的值
+func (e *ValueExpr) Evaluate(ctx *flux.Context) (interface{}, error) {
+	if len(e.parts) == 1 {
+		return e.parts[0].eval(ctx)
+	}
+	sb := new(strings.Builder)
+	for _, part := range e.parts {
+		v, err := part.eval(ctx)
+		if nil != err {
+			return nil, err
+		}
+		sb.WriteString(cast.ToString(v))
+	}
+	return sb.String(), nil
+}
+
+func (e *ValueExpr) String() string {
+	return e.text
+}
+
+// NewExprLookupFunc 构建按表达式计算参数值的查找函数
+func NewExprLookupFunc(expr *ValueExpr) flux.ArgumentLookupFunc {
+	return func(_, _ string, ctx *flux.Context) (flux.MTValue, error) {
+		v, err := expr.Evaluate(ctx)
+		if nil != err {
+			return flux.NewInvalidMTValue(), err
+		}
+		if nil == v {
+			return flux.NewInvalidMTValue(), nil
+		}
+		if str, ok := v.(string); ok {
+			return flux.WrapStringMTValue(str), nil
+		}
+		return flux.WrapObjectMTValue(v), nil
+	}
+}
+
+// exprEndOf 查找表达式片段的结束位置；忽略字符串字面量中的'}'
+func exprEndOf(text string, from int) int {
+	var quote byte
+	for i := from; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '}':
+			return i
+		}
+	}
+	return -1
+}
+
+//// Nodes
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n literalNode) eval(_ *flux.Context) (interface{}, error) {
+	return n.value, nil
+}
+
+type refNode struct {
+	scope string
+	key   string
+}
+
+func (n refNode) eval(ctx *flux.Context) (interface{}, error) {
+	if n.scope == ExprScopeNow {
+		now := time.Now()
+		switch strings.ToLower(n.key) {
+		case "millis":
+			return now.UnixNano() / int64(time.Millisecond), nil
+		case "rfc3339":
+			return now.Format(time.RFC3339), nil
+		case "date":
+			return now.Format("2006-01-02"), nil
+		default:
+			return now.Unix(), nil
+		}
+	}
+	mtv, err := LookupMTValue(n.scope, n.key, ctx)
+	if nil != err {
+		return nil, err
+	}
+	if !mtv.Valid {
+		return nil, nil
+	}
+	return mtv.Value, nil
+}
+
+type unaryNode struct {
+	op   string
+	expr exprNode
+}
+
+func (n unaryNode) eval(ctx *flux.Context) (interface{}, error) {
+	v, err := n.expr.eval(ctx)
+	if nil != err {
+		return nil, err
+	}
+	return !exprTruthy(v), nil
+}
+
+type binaryNode struct {
+	op    string
+	left  exprNode
+	right exprNode
+}
+
+func (n binaryNode) eval(ctx *flux.Context) (interface{}, error) {
+	lv, err := n.left.eval(ctx)
+	if nil != err {
+		return nil, err
+	}
+	// 短路运算
+	switch n.op {
+	case "??":
+		if !isEmptyOrNil(lv) {
+			return lv, nil
+		}
+		return n.right.eval(ctx)
+	case "&&":
+		if !exprTruthy(lv) {
+			return false, nil
+		}
+		rv, err := n.right.eval(ctx)
+		return exprTruthy(rv), err
+	case "||":
+		if exprTruthy(lv) {
+			return true, nil
+		}
+		rv, err := n.right.eval(ctx)
+		return exprTruthy(rv), err
+	}
+	rv, err := n.right.eval(ctx)
+	if nil != err {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return cast.ToString(lv) == cast.ToString(rv), nil
+	case "!=":
+		return cast.ToString(lv) != cast.ToString(rv), nil
+	default: // +
+		if ln, lok := exprNumber(lv); lok {
+			if rn, rok := exprNumber(rv); rok {
+				li, lerr := cast.ToInt64E(lv)
+				ri, rerr := cast.ToInt64E(rv)
+				if nil == lerr && nil == rerr && float64(li) == ln && float64(ri) == rn {
+					return li + ri, nil
+				}
+				return ln + rn, nil
+			}
+		}
+		return cast.ToString(lv) + cast.ToString(rv), nil
+	}
+}
+
+type condNode struct {
+	cond exprNode
+	yes  exprNode
+	no   exprNode
+}
+
+func (n condNode) eval(ctx *flux.Context) (interface{}, error) {
+	cv, err := n.cond.eval(ctx)
+	if nil != err {
+		return nil, err
+	}
+	if exprTruthy(cv) {
+		return n.yes.eval(ctx)
+	}
+	return n.no.eval(ctx)
+}
+
+func exprTruthy(v interface{}) bool {
+	switch b := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return b
+	case string:
+		return "" != b && "false" != strings.ToLower(b) && "0" != b
+	default:
+		if n, ok := exprNumber(v); ok {
+			return n != 0
+		}
+		return true
+	}
+}
+
+func exprNumber(v interface{}) (float64, bool) {
+	switch v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return cast.ToFloat64(v), true
+	default:
+		return 0, false
+	}
+}
+
+//// Parser
+
+type exprToken struct {
+	kind  byte // s: 字符串, n: 数值, i: 标识符, o: 运算符
+	value string
+}
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+func parseExpr(src string) (exprNode, error) {
+	tokens, err := tokenizeExpr(src)
+	if nil != err {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("empty expression")
+	}
+	p := &exprParser{tokens: tokens}
+	node, err := p.parseCond()
+	if nil != err {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected token: %s", p.tokens[p.pos].value)
+	}
+	return node, nil
+}
+
+func (p *exprParser) peekOp(ops ...string) (string, bool) {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != 'o' {
+		return "", false
+	}
+	for _, op := range ops {
+		if p.tokens[p.pos].value == op {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) parseCond() (exprNode, error) {
+	cond, err := p.parseBinary(0)
+	if nil != err {
+		return nil, err
+	}
+	if _, ok := p.peekOp("?"); !ok {
+		return cond, nil
+	}
+	p.pos++
+	yes, err := p.parseCond()
+	if nil != err {
+		return nil, err
+	}
+	if _, ok := p.peekOp(":"); !ok {
+		return nil, errors.New("missing ':' in conditional expression")
+	}
+	p.pos++
+	no, err := p.parseCond()
+	if nil != err {
+		return nil, err
+	}
+	return condNode{cond: cond, yes: yes, no: no}, nil
+}
+
+// 二元运算符，按优先级从低到高
+var exprBinaryLevels = [][]string{{"??"}, {"||"}, {"&&"}, {"==", "!="}, {"+"}}
+
+func (p *exprParser) parseBinary(level int) (exprNode, error) {
+	if level >= len(exprBinaryLevels) {
+		return p.parseUnary()
+	}
+	left, err := p.parseBinary(level + 1)
+	if nil != err {
+		return nil, err
+	}
+	for {
+		op, ok := p.peekOp(exprBinaryLevels[level]...)
+		if !ok {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseBinary(level + 1)
+		if nil != err {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if _, ok := p.peekOp("!"); ok {
+		p.pos++
+		expr, err := p.parseUnary()
+		if nil != err {
+			return nil, err
+		}
+		return unaryNode{op: "!", expr: expr}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, errExprUnexpectedEnd
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+	switch token.kind {
+	case 's':
+		return literalNode{value: token.value}, nil
+	case 'n':
+		if n, err := strconv.ParseInt(token.value, 10, 64); nil == err {
+			return literalNode{value: n}, nil
+		}
+		f, err := strconv.ParseFloat(token.value, 64)
+		if nil != err {
+			return nil, fmt.Errorf("illegal number: %s", token.value)
+		}
+		return literalNode{value: f}, nil
+	case 'i':
+		switch token.value {
+		case "true":
+			return literalNode{value: true}, nil
+		case "false":
+			return literalNode{value: false}, nil
+		case "null", "nil":
+			return literalNode{value: nil}, nil
+		}
+		idx := strings.IndexByte(token.value, '.')
+		if idx <= 0 || idx == len(token.value)-1 {
+			if strings.ToUpper(token.value) == ExprScopeNow {
+				return refNode{scope: ExprScopeNow, key: "unix"}, nil
+			}
+			return nil, fmt.Errorf("illegal reference, require scope.key: %s", token.value)
+		}
+		return refNode{scope: strings.ToUpper(token.value[:idx]), key: token.value[idx+1:]}, nil
+	default:
+		if token.value == "(" {
+			node, err := p.parseCond()
+			if nil != err {
+				return nil, err
+			}
+			if _, ok := p.peekOp(")"); !ok {
+				return nil, errors.New("missing ')'")
+			}
+			p.pos++
+			return node, nil
+		}
+		return nil, fmt.Errorf("unexpected token: %s", token.value)
+	}
+}
+
+func tokenizeExpr(src string) ([]exprToken, error) {
+	tokens := make([]exprToken, 0, 8)
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(src[i+1:], c)
+			if end < 0 {
+				return nil, errors.New("unclosed string literal")
+			}
+			tokens = append(tokens, exprToken{kind: 's', value: src[i+1 : i+1+end]})
+			i += end + 2
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, exprToken{kind: 'n', value: src[i:j]})
+			i = j
+		case isExprIdentStart(c):
+			j := i
+			for j < len(src) && isExprIdentPart(src[j]) {
+				j++
+			}
+			tokens = append(tokens, exprToken{kind: 'i', value: src[i:j]})
+			i = j
+		default:
+			op := ""
+			for _, candidate := range []string{"??", "==", "!=", "&&", "||", "+", "?", ":", "!", "(", ")"} {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if "" == op {
+				return nil, fmt.Errorf("illegal character: %c", c)
+			}
+			tokens = append(tokens, exprToken{kind: 'o', value: op})
+			i += len(op)
+		}
+	}
+	return tokens, nil
+}
+
+func isExprIdentStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+// 值域引用的Key允许包含 '.', '-', '$', '[', ']', '*'，用于Header名称和JSONPath
+func isExprIdentPart(c byte) bool {
+	return isExprIdentStart(c) || c >= '0' && c <= '9' || c == '.' || c == '-' || c == '$' || c == '[' || c == ']' || c == '*'
+}
@@ -0,0 +1,71 @@
+package common
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/internal/fluxtest"
+	"net/http/httptest"
+	"testing"
+
+	assert2 "github.com/stretchr/testify/assert"
+)
+
+func newExprContext() *flux.Context {
+	mr := httptest.NewRequest("GET", "http://mocking/expr?id=1001&vip=true", nil)
+	mr.Header.Set("X-Uid", "u01")
+	ctx := fluxtest.NewContext(mr)
+	ctx.SetAttribute("jwt.sub", "s01")
+	return ctx
+}
+
+func TestValueExpr(t *testing.T) {
+	assert := assert2.New(t)
+	ctx := newExprContext()
+	cases := []struct {
+		expr     string
+		expected interface{}
+	}{
+		{expr: "static", expected: "static"},
+		{expr: "${header.X-Uid}", expected: "u01"},
+		{expr: "${attr.jwt.sub}", expected: "s01"},
+		{expr: "uid:${header.X-Uid}/${query.id}", expected: "uid:u01/1001"},
+		{expr: "${header.X-None ?? 'anonymous'}", expected: "anonymous"},
+		{expr: "${header.X-None ?? query.none ?? 'x'}", expected: "x"},
+		{expr: "${query.vip == 'true' ? 'V:' + query.id : 'N'}", expected: "V:1001"},
+		{expr: "${!query.vip ? 'N' : 'V'}", expected: "V"},
+		{expr: "${query.id != '' && header.X-Uid == 'u01'}", expected: true},
+		{expr: "${1 + 2}", expected: int64(3)},
+		{expr: "${(query.none || query.vip) ? 'a' : 'b'}", expected: "a"},
+		{expr: "${'{}' + query.id}", expected: "{}1001"},
+		{expr: "${query.none}", expected: nil},
+	}
+	for _, tcase := range cases {
+		expr, err := CompileValueExpr(tcase.expr)
+		assert.NoError(err, tcase.expr)
+		v, err := expr.Evaluate(ctx)
+		assert.NoError(err, tcase.expr)
+		assert.Equal(tcase.expected, v, tcase.expr)
+	}
+	now, err := CompileValueExpr("${now.unix}")
+	assert.NoError(err)
+	v, err := now.Evaluate(ctx)
+	assert.NoError(err)
+	_, ok := v.(int64)
+	assert.True(ok)
+}
+
+func TestValueExpr_Illegal(t *testing.T) {
+	assert := assert2.New(t)
+	for _, text := range []string{"${header.X-Uid", "${'abc}", "${a ? b}", "${(query.id}", "${query.id +}", "${uid}", "${query.id # 1}"} {
+		_, err := CompileValueExpr(text)
+		assert.Error(err, text)
+	}
+}
+
+func TestNewExprLookupFunc(t *testing.T) {
+	assert := assert2.New(t)
+	expr, err := CompileValueExpr("${query.id}")
+	assert.NoError(err)
+	mtv, err := NewExprLookupFunc(expr)("", "", newExprContext())
+	assert.NoError(err)
+	assert.Equal(flux.WrapStringMTValue("1001"), mtv)
+}
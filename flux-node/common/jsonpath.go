@@ -0,0 +1,166 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/ext"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// 请求体解析后的JSON对象，缓存在请求的Variable中
+const variableKeyJSONBody = "@flux.body.json"
+
+var errJSONPathIllegal = errors.New("illegal json path")
+
+// JSONPath 表达式的节点类型
+const (
+	jsonPathField = iota
+	jsonPathIndex
+	jsonPathWildcard
+)
+
+type jsonPathToken struct {
+	kind  int
+	name  string
+	index int
+}
+
+// LookupJSONBody 使用JSONPath表达式，查找请求体JSON对象的值；请求体在同一请求中只解析一次；
+// 返回值：查找的值，值是否存在，错误；请求体为空时，值不存在；
+func LookupJSONBody(webex flux.ServerWebContext, expr string) (interface{}, bool, error) {
+	value, err := loadVariableOnce(webex, variableKeyJSONBody, func() (interface{}, error) {
+		return decodeJSONBody(webex)
+	})
+	if nil != err {
+		return nil, false, err
+	}
+	if nil == value {
+		return nil, false, nil
+	}
+	return EvalJSONPath(value, expr)
+}
+
+func decodeJSONBody(webex flux.ServerWebContext) (interface{}, error) {
+	reader, err := webex.BodyReader()
+	if nil != err {
+		return nil, flux.NewRequestBodyError(err)
+	}
+	data, err := ioutil.ReadAll(reader)
+	_ = reader.Close()
+	if nil != err {
+		return nil, flux.NewRequestBodyError(err)
+	}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return nil, nil
+	}
+	var value interface{}
+	if err := ext.JSONUnmarshal(data, &value); nil != err {
+		return nil, flux.NewRequestBodyError(fmt.Errorf("cannot decode body as json, error: %w", err))
+	}
+	return value, nil
+}
+
+// EvalJSONPath 在JSON对象上执行JSONPath表达式；支持的语法：
+// 1. 根节点 $，可省略；
+// 2. 字段 .name 以及 ['name']；
+// 3. 数组下标 [0]，负数下标从末尾计数；
+// 4. 通配符 [*] 以及 .*，返回匹配值的列表；
+// 返回值：查找的值，值是否存在，表达式错误；
+func EvalJSONPath(root interface{}, expr string) (interface{}, bool, error) {
+	tokens, err := parseJSONPath(expr)
+	if nil != err {
+		return nil, false, fmt.Errorf("%w: %s", err, expr)
+	}
+	nodes := []interface{}{root}
+	wildcard := false
+	for _, token := range tokens {
+		next := make([]interface{}, 0, len(nodes))
+		for _, node := range nodes {
+			switch token.kind {
+			case jsonPathWildcard:
+				switch v := node.(type) {
+				case []interface{}:
+					next = append(next, v...)
+				case map[string]interface{}:
+					for _, item := range v {
+						next = append(next, item)
+					}
+				}
+			case jsonPathIndex:
+				arr, ok := node.([]interface{})
+				if !ok {
+					continue
+				}
+				idx := token.index
+				if idx < 0 {
+					idx += len(arr)
+				}
+				if idx >= 0 && idx < len(arr) {
+					next = append(next, arr[idx])
+				}
+			default:
+				if obj, ok := node.(map[string]interface{}); ok {
+					if v, has := obj[token.name]; has {
+						next = append(next, v)
+					}
+				}
+			}
+		}
+		wildcard = wildcard || token.kind == jsonPathWildcard
+		nodes = next
+	}
+	if wildcard {
+		return nodes, true, nil
+	}
+	if len(nodes) == 0 {
+		return nil, false, nil
+	}
+	return nodes[0], true, nil
+}
+
+// parseJSONPath 解析JSONPath表达式为节点列表
+func parseJSONPath(expr string) ([]jsonPathToken, error) {
+	expr = strings.TrimSpace(expr)
+	expr = strings.TrimPrefix(expr, "$")
+	tokens := make([]jsonPathToken, 0, 4)
+	for i := 0; i < len(expr); {
+		switch expr[i] {
+		case '.':
+			i++
+		case '[':
+			end := strings.IndexByte(expr[i:], ']')
+			if end < 0 {
+				return nil, errJSONPathIllegal
+			}
+			inner := strings.TrimSpace(expr[i+1 : i+end])
+			i += end + 1
+			switch {
+			case inner == "*":
+				tokens = append(tokens, jsonPathToken{kind: jsonPathWildcard})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				tokens = append(tokens, jsonPathToken{kind: jsonPathField, name: inner[1 : len(inner)-1]})
+			default:
+				idx, err := strconv.Atoi(inner)
+				if nil != err {
+					return nil, errJSONPathIllegal
+				}
+				tokens = append(tokens, jsonPathToken{kind: jsonPathIndex, index: idx})
+			}
+		default:
+			end := strings.IndexAny(expr[i:], ".[")
+			if end < 0 {
+				end = len(expr) - i
+			}
+			if name := expr[i : i+end]; name == "*" {
+				tokens = append(tokens, jsonPathToken{kind: jsonPathWildcard})
+			} else {
+				tokens = append(tokens, jsonPathToken{kind: jsonPathField, name: name})
+			}
+			i += end
+		}
+	}
+	return tokens, nil
+}
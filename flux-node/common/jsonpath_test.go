@@ -0,0 +1,68 @@
+package common
+
+import (
+	"bytes"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/ext"
+	"github.com/bytepowered/flux/flux-node/internal/fluxtest"
+	"net/http/httptest"
+	"testing"
+
+	assert2 "github.com/stretchr/testify/assert"
+)
+
+func TestEvalJSONPath(t *testing.T) {
+	assert := assert2.New(t)
+	root := map[string]interface{}{
+		"order": map[string]interface{}{
+			"no": "N001",
+			"items": []interface{}{
+				map[string]interface{}{"sku": "A"},
+				map[string]interface{}{"sku": "B"},
+			},
+		},
+	}
+	cases := []struct {
+		expr  string
+		value interface{}
+		ok    bool
+	}{
+		{expr: "$.order.no", value: "N001", ok: true},
+		{expr: "order.no", value: "N001", ok: true},
+		{expr: "$['order']['no']", value: "N001", ok: true},
+		{expr: "$.order.items[0].sku", value: "A", ok: true},
+		{expr: "$.order.items[-1].sku", value: "B", ok: true},
+		{expr: "$.order.items[*].sku", value: []interface{}{"A", "B"}, ok: true},
+		{expr: "$.order.items[5].sku", value: nil, ok: false},
+		{expr: "$.order.none", value: nil, ok: false},
+	}
+	for _, tcase := range cases {
+		v, ok, err := EvalJSONPath(root, tcase.expr)
+		assert.NoError(err, tcase.expr)
+		assert.Equal(tcase.ok, ok, tcase.expr)
+		assert.Equal(tcase.value, v, tcase.expr)
+	}
+	_, _, err := EvalJSONPath(root, "$.order.items[x]")
+	assert.Error(err)
+	_, _, err = EvalJSONPath(root, "$.order.items[0")
+	assert.Error(err)
+}
+
+func TestLookupMTValue_BodyPath(t *testing.T) {
+	ext.RegisterSerializer(ext.TypeNameSerializerJson, flux.NewJsonSerializer())
+	assert := assert2.New(t)
+	body := []byte(`{"order":{"items":[{"sku":"A","count":2}]}}`)
+	mr := httptest.NewRequest("POST", "http://mocking/body", bytes.NewReader(body))
+	ctx := fluxtest.NewContext(mr)
+	mtv, err := LookupMTValue(flux.ScopeBodyPath, "$.order.items[0].sku", ctx)
+	assert.NoError(err)
+	assert.True(mtv.Valid)
+	assert.Equal("A", mtv.Value)
+	mtv, err = LookupMTValue(flux.ScopeJSON, "$.order.items[0].count", ctx)
+	assert.NoError(err)
+	assert.Equal(float64(2), mtv.Value)
+	mtv, err = LookupMTValue(flux.ScopeBodyPath, "$.order.none", ctx)
+	assert.NoError(err)
+	assert.False(mtv.Valid)
+	assert.Equal("A", LookupWebValue(ctx, flux.ScopeBodyPath, "order.items[0].sku"))
+}
@@ -8,8 +8,26 @@ import (
 	"net/textproto"
 	"net/url"
 	"strings"
+	"sync/atomic"
 )
 
+// JWT Claims 在Context Attribute中的Key前缀；Filter初始化时设置，请求处理时并发读取
+var claimKeyPrefix atomic.Value
+
+func init() {
+	claimKeyPrefix.Store("jwt")
+}
+
+// SetClaimAttributeKeyPrefix 设置JWT Claims在Context Attribute中的Key前缀；与JWT验证Filter的配置保持一致；
+func SetClaimAttributeKeyPrefix(prefix string) {
+	claimKeyPrefix.Store(fluxpkg.MustNotEmpty(prefix, "claim key prefix is empty"))
+}
+
+// ClaimAttributeKey 返回JWT Claim在Context Attribute中的Key
+func ClaimAttributeKey(name string) string {
+	return claimKeyPrefix.Load().(string) + "." + name
+}
+
 // LookupExpr 搜索LookupExpr表达式指定域的值。
 func LookupMTValueByExpr(expr string, ctx *flux.Context) (interface{}, error) {
 	if expr == "" || nil == ctx {
@@ -55,6 +73,27 @@ func LookupMTValue(scope, key string, ctx *flux.Context) (value flux.MTValue, er
 		return lookupValues(ctx.HeaderVars(), key), nil
 	case flux.ScopeHeaderMap:
 		return flux.WrapStrValuesMapMTValue(ctx.HeaderVars()), nil
+	case flux.ScopeCookie:
+		if cookie, err := ctx.CookieVar(key); nil == err && nil != cookie {
+			return flux.WrapStringMTValue(cookie.Value), nil
+		}
+		return flux.NewInvalidMTValue(), nil
+	case flux.ScopeCookieMap:
+		cookies := ctx.CookieVars()
+		values := make(map[string][]string, len(cookies))
+		for _, cookie := range cookies {
+			values[cookie.Name] = append(values[cookie.Name], cookie.Value)
+		}
+		return flux.WrapStrValuesMapMTValue(values), nil
+	case flux.ScopeFile:
+		return lookupFileValue(ctx, key, false)
+	case flux.ScopeFileMulti:
+		return lookupFileValue(ctx, key, true)
+	case flux.ScopeClaim:
+		if v, ok := ctx.GetAttribute(ClaimAttributeKey(key)); ok {
+			return flux.WrapObjectMTValue(v), nil
+		}
+		return flux.NewInvalidMTValue(), nil
 	case flux.ScopeAttr:
 		v, _ := ctx.GetAttribute(key)
 		return flux.WrapObjectMTValue(v), nil
@@ -63,6 +102,12 @@ func LookupMTValue(scope, key string, ctx *flux.Context) (value flux.MTValue, er
 	case flux.ScopeBody:
 		reader, err := ctx.BodyReader()
 		return flux.MTValue{Valid: err == nil, Value: reader, MediaType: ctx.HeaderVar(flux.HeaderContentType)}, err
+	case flux.ScopeBodyPath, flux.ScopeJSON:
+		v, ok, err := LookupJSONBody(ctx, key)
+		if nil != err || !ok {
+			return flux.NewInvalidMTValue(), err
+		}
+		return flux.WrapObjectMTValue(v), nil
 	case flux.ScopeParam:
 		v, _ := fluxpkg.LookupByProviders(key, ctx.QueryVars, ctx.FormVars)
 		return flux.WrapStringMTValue(v), nil
@@ -84,6 +129,9 @@ func LookupMTValue(scope, key string, ctx *flux.Context) (value flux.MTValue, er
 		if mtv := lookupValues(ctx.HeaderVars(), key); mtv.Valid {
 			return mtv, nil
 		}
+		if cookie, err := ctx.CookieVar(key); nil == err && nil != cookie {
+			return flux.WrapStringMTValue(cookie.Value), nil
+		}
 		if v, ok := ctx.GetAttribute(key); ok {
 			return flux.WrapObjectMTValue(v), nil
 		}
@@ -0,0 +1,59 @@
+package common
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/internal/fluxtest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	assert2 "github.com/stretchr/testify/assert"
+)
+
+func TestLookupMTValue_CookieAndClaim(t *testing.T) {
+	assert := assert2.New(t)
+	mr := httptest.NewRequest("GET", "http://mocking/cookie", nil)
+	mr.AddCookie(&http.Cookie{Name: "token", Value: "abc"})
+	mr.AddCookie(&http.Cookie{Name: "env", Value: "gray"})
+	ctx := fluxtest.NewContext(mr)
+	ctx.SetAttribute(ClaimAttributeKey("uid"), "u1001")
+
+	mtv, err := LookupMTValue(flux.ScopeCookie, "token", ctx)
+	assert.NoError(err)
+	assert.Equal(flux.WrapStringMTValue("abc"), mtv)
+	mtv, err = LookupMTValue(flux.ScopeCookie, "none", ctx)
+	assert.NoError(err)
+	assert.False(mtv.Valid)
+	mtv, err = LookupMTValue(flux.ScopeCookieMap, "-", ctx)
+	assert.NoError(err)
+	assert.Equal(map[string][]string{"token": {"abc"}, "env": {"gray"}}, mtv.Value)
+	mtv, err = LookupMTValue(flux.ScopeClaim, "uid", ctx)
+	assert.NoError(err)
+	assert.Equal("u1001", mtv.Value)
+	mtv, err = LookupMTValue(flux.ScopeAuto, "env", ctx)
+	assert.NoError(err)
+	assert.Equal("gray", mtv.Value)
+
+	assert.Equal("abc", LookupWebValueByExpr(ctx, "cookie:token"))
+	assert.Equal("u1001", LookupWebValueByExpr(ctx, "claim:uid"))
+}
+
+func TestSetClaimAttributeKeyPrefix(t *testing.T) {
+	assert := assert2.New(t)
+	defer SetClaimAttributeKeyPrefix("jwt")
+	ctx := fluxtest.NewContext(httptest.NewRequest("GET", "http://mocking/claim", nil))
+	ctx.SetAttribute("auth.uid", "u2002")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			_ = ClaimAttributeKey("uid")
+		}
+	}()
+	SetClaimAttributeKeyPrefix("auth")
+	<-done
+	assert.Equal("auth.uid", ClaimAttributeKey("uid"))
+	mtv, err := LookupMTValue(flux.ScopeClaim, "uid", ctx)
+	assert.NoError(err)
+	assert.Equal("u2002", mtv.Value)
+}
@@ -29,6 +29,17 @@ func LookupWebValue(webex flux.ServerWebContext, scope, key string) string {
 		return webex.FormVar(key)
 	case flux.ScopeHeader:
 		return webex.HeaderVar(key)
+	case flux.ScopeCookie:
+		if cookie, err := webex.CookieVar(key); nil == err && nil != cookie {
+			return cookie.Value
+		}
+		return ""
+	case flux.ScopeClaim:
+		if ctx, ok := webex.(*flux.Context); ok {
+			v, _ := ctx.GetAttribute(ClaimAttributeKey(key))
+			return cast.ToString(v)
+		}
+		return ""
 	case flux.ScopeRequest:
 		switch strings.ToLower(key) {
 		case "method":
@@ -37,6 +48,9 @@ func LookupWebValue(webex flux.ServerWebContext, scope, key string) string {
 			return webex.URI()
 		}
 		return webex.Method()
+	case flux.ScopeBodyPath, flux.ScopeJSON:
+		v, _, _ := LookupJSONBody(webex, key)
+		return cast.ToString(v)
 	case flux.ScopeParam:
 		v, _ := fluxpkg.LookupByProviders(key, webex.QueryVars, webex.FormVars)
 		return v
@@ -49,6 +63,10 @@ func LookupWebValue(webex flux.ServerWebContext, scope, key string) string {
 		if v := webex.HeaderVar(key); v != "" {
 			return v
 		}
+		// Cookie
+		if cookie, err := webex.CookieVar(key); nil == err && nil != cookie {
+			return cookie.Value
+		}
 		// Variables
 		return cast.ToString(webex.Variable(key))
 	default:
@@ -0,0 +1,212 @@
+package common
+
+import (
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/labstack/gommon/bytes"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"path"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	// ConfigKeyMultipartMaxMemory 解析multipart请求时，文件内容在内存中缓存的最大字节数；超出部分写入临时文件；
+	ConfigKeyMultipartMaxMemory = "multipart.max_memory"
+)
+
+// FILE值域Key的元数据后缀：name#filename, name#content_type, name#size
+const (
+	FileMetaFilename    = "filename"
+	FileMetaContentType = "content_type"
+	FileMetaSize        = "size"
+)
+
+// 请求解析后的multipart表单，缓存在请求的Variable中
+const variableKeyMultipartForm = "@flux.body.multipart"
+
+const defaultMultipartMaxMemory = 8 << 20
+
+var multipartMaxMemory int64 = defaultMultipartMaxMemory
+
+// ConfigureMultipart 配置multipart请求的解析参数
+func ConfigureMultipart(config *flux.Configuration) error {
+	if size := config.GetString(ConfigKeyMultipartMaxMemory); "" != size {
+		n, err := bytes.Parse(size)
+		if nil != err {
+			return fmt.Errorf("invalid multipart max memory: %s, error: %w", size, err)
+		}
+		atomic.StoreInt64(&multipartMaxMemory, n)
+	}
+	return nil
+}
+
+// LookupFileParts 查找multipart请求中指定表单名称的文件；请求在同一请求中只解析一次；
+// 文件受Endpoint的 upload_max_size, upload_allowed_types 属性限制，超出限制时返回 *flux.ServeError 错误；
+func LookupFileParts(ctx *flux.Context, name string) ([]*multipart.FileHeader, error) {
+	form, err := multipartFormOf(ctx)
+	if nil != err || nil == form {
+		return nil, err
+	}
+	files := form.File[name]
+	if len(files) == 0 {
+		return nil, nil
+	}
+	return files, nil
+}
+
+// ReleaseMultipartForm 删除请求解析multipart时创建的临时文件
+func ReleaseMultipartForm(webex flux.ServerWebContext) {
+	if cached, ok := webex.Variable(variableKeyMultipartForm).(*onceVariable); ok {
+		if form, ok := cached.value.(*multipart.Form); ok && nil != form {
+			_ = form.RemoveAll()
+		}
+	}
+}
+
+// lookupFileValue 按FILE值域的Key查找文件或者文件的元数据
+func lookupFileValue(ctx *flux.Context, key string, multi bool) (flux.MTValue, error) {
+	name, meta := key, ""
+	if idx := strings.LastIndexByte(key, '#'); idx > 0 {
+		name, meta = key[:idx], strings.ToLower(key[idx+1:])
+	}
+	files, err := LookupFileParts(ctx, name)
+	if nil != err || len(files) == 0 {
+		return flux.NewInvalidMTValue(), err
+	}
+	if "" == meta {
+		if multi {
+			return flux.WrapObjectMTValue(files), nil
+		}
+		return flux.WrapObjectMTValue(files[0]), nil
+	}
+	values := make([]string, 0, len(files))
+	for _, file := range files {
+		switch meta {
+		case FileMetaFilename:
+			values = append(values, file.Filename)
+		case FileMetaContentType:
+			values = append(values, file.Header.Get(flux.HeaderContentType))
+		case FileMetaSize:
+			values = append(values, fmt.Sprintf("%d", file.Size))
+		default:
+			return flux.NewInvalidMTValue(), fmt.Errorf("unsupported file meta: %s", meta)
+		}
+	}
+	if multi {
+		return flux.WrapStrListMTValue(values), nil
+	}
+	return flux.WrapStringMTValue(values[0]), nil
+}
+
+func multipartFormOf(ctx *flux.Context) (*multipart.Form, error) {
+	value, err := loadVariableOnce(ctx.ServerWebContext, variableKeyMultipartForm, func() (interface{}, error) {
+		return parseMultipartForm(ctx.ServerWebContext, ctx.Endpoint())
+	})
+	form, _ := value.(*multipart.Form)
+	return form, err
+}
+
+func parseMultipartForm(webex flux.ServerWebContext, endpoint *flux.Endpoint) (*multipart.Form, error) {
+	mediaType, params, err := mime.ParseMediaType(webex.HeaderVar(flux.HeaderContentType))
+	if nil != err || !strings.HasPrefix(mediaType, "multipart/") || "" == params["boundary"] {
+		return nil, nil
+	}
+	// 解析文件到磁盘之前，先按上传限制扫描文件分段；文件超出大小限制时，不再读取剩余内容
+	if serr := scanUploadParts(webex, params["boundary"], endpoint); nil != serr {
+		return nil, serr
+	}
+	reader, err := webex.BodyReader()
+	if nil != err {
+		return nil, flux.NewRequestBodyError(err)
+	}
+	defer reader.Close()
+	form, err := multipart.NewReader(reader, params["boundary"]).ReadForm(atomic.LoadInt64(&multipartMaxMemory))
+	if nil != err {
+		return nil, flux.NewRequestBodyError(fmt.Errorf("cannot parse multipart body, error: %w", err))
+	}
+	return form, nil
+}
+
+// scanUploadParts 按Endpoint的 upload_max_size, upload_allowed_types 属性校验全部文件分段；
+// 文件超出大小限制时返回413错误，文件类型不允许或者请求格式错误时返回400错误；
+func scanUploadParts(webex flux.ServerWebContext, boundary string, endpoint *flux.Endpoint) *flux.ServeError {
+	if nil == endpoint {
+		return nil
+	}
+	var maxSize int64 = 0
+	if attr, ok := endpoint.GetAttrEx(flux.EndpointAttrTagUploadMaxSize); ok {
+		size, err := bytes.Parse(attr.GetString())
+		if nil != err {
+			return &flux.ServeError{
+				StatusCode: flux.StatusServerError,
+				ErrorCode:  flux.ErrorCodeGatewayEndpoint,
+				Message:    flux.ErrorMessageRequestPrepare,
+				CauseError: fmt.Errorf("invalid endpoint attr %s: %s", flux.EndpointAttrTagUploadMaxSize, attr.GetString()),
+			}
+		}
+		maxSize = size
+	}
+	allowed := endpoint.GetAttr(flux.EndpointAttrTagUploadAllowedTypes).GetStringSlice()
+	if maxSize <= 0 && len(allowed) == 0 {
+		return nil
+	}
+	reader, err := webex.BodyReader()
+	if nil != err {
+		return flux.NewRequestBodyError(err)
+	}
+	defer reader.Close()
+	parts := multipart.NewReader(reader, boundary)
+	for {
+		part, err := parts.NextPart()
+		if io.EOF == err {
+			return nil
+		}
+		if nil != err {
+			return flux.NewRequestBodyError(fmt.Errorf("cannot parse multipart body, error: %w", err))
+		}
+		if "" == part.FileName() {
+			continue
+		}
+		if len(allowed) > 0 {
+			ctype := part.Header.Get(flux.HeaderContentType)
+			if mt, _, err := mime.ParseMediaType(ctype); nil == err {
+				ctype = mt
+			}
+			if !isContentTypeAllowed(ctype, allowed) {
+				return &flux.ServeError{
+					StatusCode: flux.StatusBadRequest,
+					ErrorCode:  flux.ErrorCodeRequestInvalid,
+					Message:    flux.ErrorMessageRequestUploadType,
+					CauseError: fmt.Errorf("file type not allowed, filename: %s, content-type: %s", part.FileName(), ctype),
+				}
+			}
+		}
+		if maxSize > 0 {
+			n, err := io.Copy(ioutil.Discard, io.LimitReader(part, maxSize+1))
+			if nil != err {
+				return flux.NewRequestBodyError(fmt.Errorf("cannot read multipart file, error: %w", err))
+			}
+			if n > maxSize {
+				return flux.NewRequestBodyError(fmt.Errorf("file too large, filename: %s, limit: %d, error: %w",
+					part.FileName(), maxSize, flux.ErrRequestBodyTooLarge))
+			}
+		}
+	}
+}
+
+func isContentTypeAllowed(ctype string, allowed []string) bool {
+	patterns := make([]string, 0, len(allowed))
+	for _, item := range allowed {
+		patterns = append(patterns, strings.Split(item, ",")...)
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(strings.TrimSpace(pattern)), strings.ToLower(ctype)); ok {
+			return true
+		}
+	}
+	return false
+}
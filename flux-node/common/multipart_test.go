@@ -0,0 +1,101 @@
+package common
+
+import (
+	"bytes"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/internal/fluxtest"
+	"mime/multipart"
+	"net/http/httptest"
+	"net/textproto"
+	"testing"
+
+	assert2 "github.com/stretchr/testify/assert"
+)
+
+func newMultipartContext(endpoint *flux.Endpoint) *flux.Context {
+	buf := new(bytes.Buffer)
+	w := multipart.NewWriter(buf)
+	_ = w.WriteField("name", "foo")
+	for _, f := range []struct{ name, filename, ctype, data string }{
+		{"avatar", "a.png", "image/png", "PNG-DATA"},
+		{"docs", "d1.pdf", "application/pdf", "PDF-1"},
+		{"docs", "d2.txt", "text/plain", "TEXT-22"},
+	} {
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", `form-data; name="`+f.name+`"; filename="`+f.filename+`"`)
+		h.Set(flux.HeaderContentType, f.ctype)
+		part, _ := w.CreatePart(h)
+		_, _ = part.Write([]byte(f.data))
+	}
+	_ = w.Close()
+	mr := httptest.NewRequest("POST", "http://mocking/upload", buf)
+	mr.Header.Set(flux.HeaderContentType, w.FormDataContentType())
+	return fluxtest.NewContextWith(httptest.NewRecorder(), mr, endpoint)
+}
+
+func TestLookupMTValue_File(t *testing.T) {
+	assert := assert2.New(t)
+	ctx := newMultipartContext(&flux.Endpoint{})
+	defer ReleaseMultipartForm(ctx.ServerWebContext)
+	mtv, err := LookupMTValue(flux.ScopeFile, "avatar", ctx)
+	assert.NoError(err)
+	file, ok := mtv.Value.(*multipart.FileHeader)
+	assert.True(ok)
+	assert.Equal("a.png", file.Filename)
+	data, err := toByteArray(mtv.Value)
+	assert.NoError(err)
+	assert.Equal([]byte("PNG-DATA"), data)
+	// meta
+	mtv, err = LookupMTValue(flux.ScopeFile, "avatar#content_type", ctx)
+	assert.NoError(err)
+	assert.Equal(flux.WrapStringMTValue("image/png"), mtv)
+	mtv, err = LookupMTValue(flux.ScopeFileMulti, "docs#filename", ctx)
+	assert.NoError(err)
+	assert.Equal(flux.WrapStrListMTValue([]string{"d1.pdf", "d2.txt"}), mtv)
+	mtv, err = LookupMTValue(flux.ScopeFileMulti, "docs", ctx)
+	assert.NoError(err)
+	assert.Len(mtv.Value, 2)
+	// missing
+	mtv, err = LookupMTValue(flux.ScopeFile, "none", ctx)
+	assert.NoError(err)
+	assert.False(mtv.Valid)
+	_, err = LookupMTValue(flux.ScopeFile, "avatar#unknown", ctx)
+	assert.Error(err)
+}
+
+func TestLookupMTValue_FileLimits(t *testing.T) {
+	assert := assert2.New(t)
+	newEndpoint := func(maxSize, types string) *flux.Endpoint {
+		return &flux.Endpoint{EmbeddedAttributes: flux.EmbeddedAttributes{Attributes: []flux.Attribute{
+			{Name: flux.EndpointAttrTagUploadMaxSize, Value: maxSize},
+			{Name: flux.EndpointAttrTagUploadAllowedTypes, Value: types},
+		}}}
+	}
+	cases := []struct {
+		maxSize, types string
+		status         int
+		message        string
+	}{
+		{"6B", "image/*,application/pdf,text/plain", flux.StatusTooLarge, flux.ErrorMessageRequestTooLarge},
+		{"1KB", "image/*,application/pdf", flux.StatusBadRequest, flux.ErrorMessageRequestUploadType},
+		{"1KB", "image/*,application/pdf,text/*", 0, ""},
+	}
+	for _, tc := range cases {
+		ctx := newMultipartContext(newEndpoint(tc.maxSize, tc.types))
+		mtv, err := LookupMTValue(flux.ScopeFile, "avatar", ctx)
+		if 0 == tc.status {
+			assert.NoError(err)
+			assert.True(mtv.Valid)
+		} else {
+			serr, ok := err.(*flux.ServeError)
+			assert.True(ok)
+			assert.Equal(tc.status, serr.StatusCode)
+			assert.Equal(tc.message, serr.Message)
+			// 参数校验返回相同的错误
+			arg := newLookupArgument(flux.JavaLangStringClassName, "avatar")
+			arg.HttpScope = flux.ScopeFile
+			assert.Equal(serr, ValidateArguments(ctx, []flux.Argument{arg}))
+		}
+		ReleaseMultipartForm(ctx.ServerWebContext)
+	}
+}
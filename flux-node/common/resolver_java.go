@@ -0,0 +1,281 @@
+package common
+
+import (
+	"fmt"
+	"github.com/apache/dubbo-go-hessian2/java8_time"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/ext"
+	gxbig "github.com/dubbogo/gost/math/big"
+	"github.com/spf13/cast"
+	"mime/multipart"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Java日期时间解析配置
+const (
+	ConfigKeyDateLayouts = "date_layouts"
+	ConfigKeyTimeZone    = "time_zone"
+)
+
+var (
+	javaTimeLayouts = []string{
+		time.RFC3339Nano,
+		"2006-01-02 15:04:05.000",
+		"2006-01-02 15:04:05",
+		"2006-01-02T15:04:05.000",
+		"2006-01-02T15:04:05",
+		"2006-01-02",
+		"15:04:05.000",
+		"15:04:05",
+	}
+	javaTimeLocation = time.Local
+	javaTimeMu       sync.RWMutex
+)
+
+var (
+	dateResolver = flux.WrapMTValueResolver(func(value interface{}) (interface{}, error) {
+		if isEmptyOrNil(value) {
+			return nil, nil
+		}
+		return ToTimeE(value)
+	}).ResolveMT
+	localDateResolver = flux.WrapMTValueResolver(func(value interface{}) (interface{}, error) {
+		if isEmptyOrNil(value) {
+			return nil, nil
+		}
+		t, err := ToTimeE(value)
+		if nil != err {
+			return nil, err
+		}
+		return toLocalDate(t), nil
+	}).ResolveMT
+	localTimeResolver = flux.WrapMTValueResolver(func(value interface{}) (interface{}, error) {
+		if isEmptyOrNil(value) {
+			return nil, nil
+		}
+		t, err := ToTimeE(value)
+		if nil != err {
+			return nil, err
+		}
+		return toLocalTime(t), nil
+	}).ResolveMT
+	localDateTimeResolver = flux.WrapMTValueResolver(func(value interface{}) (interface{}, error) {
+		if isEmptyOrNil(value) {
+			return nil, nil
+		}
+		t, err := ToTimeE(value)
+		if nil != err {
+			return nil, err
+		}
+		return &java8_time.LocalDateTime{Date: *toLocalDate(t), Time: *toLocalTime(t)}, nil
+	}).ResolveMT
+	bigDecimalResolver = flux.WrapMTValueResolver(func(value interface{}) (interface{}, error) {
+		if isEmptyOrNil(value) {
+			return nil, nil
+		}
+		str, err := cast.ToStringE(value)
+		if nil != err {
+			return nil, err
+		}
+		decimal := gxbig.Decimal{}
+		if err := decimal.FromString(strings.TrimSpace(str)); nil != err {
+			return nil, fmt.Errorf("cannot convert value to BigDecimal, value: %s, error: %w", str, err)
+		}
+		return decimal, nil
+	}).ResolveMT
+	bigIntegerResolver = flux.WrapMTValueResolver(func(value interface{}) (interface{}, error) {
+		if isEmptyOrNil(value) {
+			return nil, nil
+		}
+		str, err := cast.ToStringE(value)
+		if nil != err {
+			return nil, err
+		}
+		integer := gxbig.Integer{}
+		if err := integer.FromString(strings.TrimSpace(str)); nil != err {
+			return nil, fmt.Errorf("cannot convert value to BigInteger, value: %s, error: %w", str, err)
+		}
+		return integer, nil
+	}).ResolveMT
+	// 枚举值以枚举名称传递，由服务端泛化调用转换为枚举对象
+	enumResolver = flux.WrapMTValueResolver(func(value interface{}) (interface{}, error) {
+		if isEmptyOrNil(value) {
+			return nil, nil
+		}
+		str, err := cast.ToStringE(value)
+		return strings.TrimSpace(str), err
+	}).ResolveMT
+)
+
+func init() {
+	ext.RegisterMTValueResolver("date", dateResolver)
+	ext.RegisterMTValueResolver(flux.JavaUtilDateClassName, dateResolver)
+	ext.RegisterMTValueResolver(flux.JavaTimeLocalDateClassName, localDateResolver)
+	ext.RegisterMTValueResolver(flux.JavaTimeLocalTimeClassName, localTimeResolver)
+	ext.RegisterMTValueResolver(flux.JavaTimeLocalDateTimeClassName, localDateTimeResolver)
+
+	ext.RegisterMTValueResolver(flux.JavaMathBigDecimalClassName, bigDecimalResolver)
+	ext.RegisterMTValueResolver(flux.JavaMathBigIntegerClassName, bigIntegerResolver)
+
+	ext.RegisterMTValueResolver("enum", enumResolver)
+	ext.RegisterMTValueResolver(flux.JavaLangEnumClassName, enumResolver)
+
+	// 基础类型数组：支持 int[] 以及 JVM描述符 [I 两种格式
+	arrays := []struct {
+		names []string
+		elem  reflect.Type
+	}{
+		{names: []string{"int[]", "[I", "java.lang.Integer[]"}, elem: reflect.TypeOf(int32(0))},
+		{names: []string{"long[]", "[J", "java.lang.Long[]"}, elem: reflect.TypeOf(int64(0))},
+		{names: []string{"short[]", "[S", "java.lang.Short[]"}, elem: reflect.TypeOf(int16(0))},
+		{names: []string{"float[]", "[F", "java.lang.Float[]"}, elem: reflect.TypeOf(float32(0))},
+		{names: []string{"double[]", "[D", "java.lang.Double[]"}, elem: reflect.TypeOf(float64(0))},
+		{names: []string{"boolean[]", "[Z", "java.lang.Boolean[]"}, elem: reflect.TypeOf(false)},
+		{names: []string{"java.lang.String[]", "String[]", "[Ljava.lang.String;"}, elem: reflect.TypeOf("")},
+	}
+	for _, arr := range arrays {
+		resolver := NewArrayResolver(arr.elem)
+		for _, name := range arr.names {
+			ext.RegisterMTValueResolver(name, resolver)
+		}
+	}
+	ext.RegisterMTValueResolver("byte[]", bytesResolver)
+	ext.RegisterMTValueResolver("[B", bytesResolver)
+	// 上传文件：InputStream 以字节数组传递；file 类型保留文件对象，用于转发multipart请求
+	ext.RegisterMTValueResolver(flux.JavaIOInputStreamClassName, bytesResolver)
+	ext.RegisterMTValueResolver("file", fileResolver)
+}
+
+// ConfigureJavaTime 配置日期时间的解析格式和时区
+func ConfigureJavaTime(config *flux.Configuration) error {
+	javaTimeMu.Lock()
+	defer javaTimeMu.Unlock()
+	if layouts := config.GetStringSlice(ConfigKeyDateLayouts); len(layouts) > 0 {
+		javaTimeLayouts = layouts
+	}
+	if zone := config.GetString(ConfigKeyTimeZone); "" != zone {
+		loc, err := time.LoadLocation(zone)
+		if nil != err {
+			return fmt.Errorf("invalid time zone: %s, error: %w", zone, err)
+		}
+		javaTimeLocation = loc
+	}
+	return nil
+}
+
+// ToTimeE 最大努力地将值转换成time.Time类型：
+// 1. 数值类型，作为Unix毫秒时间戳；
+// 2. 字符串，按配置的日期格式列表依次解析，未包含时区的格式使用配置的时区；
+func ToTimeE(value interface{}) (time.Time, error) {
+	javaTimeMu.RLock()
+	layouts, loc := javaTimeLayouts, javaTimeLocation
+	javaTimeMu.RUnlock()
+	switch v := value.(type) {
+	case time.Time:
+		return v.In(loc), nil
+	case *time.Time:
+		return v.In(loc), nil
+	case int, int32, int64, uint, uint32, uint64, float32, float64:
+		return time.Unix(0, cast.ToInt64(v)*int64(time.Millisecond)).In(loc), nil
+	}
+	str, err := cast.ToStringE(value)
+	if nil != err {
+		return time.Time{}, fmt.Errorf("cannot convert value to time, value: %+v, value.type: %T", value, value)
+	}
+	str = strings.TrimSpace(str)
+	if ms, err := cast.ToInt64E(str); nil == err {
+		return time.Unix(0, ms*int64(time.Millisecond)).In(loc), nil
+	}
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, str, loc); nil == err {
+			return t.In(loc), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse time, value: %s, layouts: %v", str, layouts)
+}
+
+// NewArrayResolver 构建基础类型数组的解析函数；数组元素转换为指定的Go类型，以生成Hessian类型化数组；
+func NewArrayResolver(elem reflect.Type) flux.MTValueResolver {
+	return func(mtValue flux.MTValue, _ string, _ []string) (interface{}, error) {
+		out := reflect.MakeSlice(reflect.SliceOf(elem), 0, 4)
+		if isEmptyOrNil(mtValue.Value) {
+			return out.Interface(), nil
+		}
+		var values []interface{}
+		switch v := mtValue.Value.(type) {
+		case string:
+			if strings.HasPrefix(strings.TrimSpace(v), "[") {
+				if err := ext.JSONUnmarshal([]byte(v), &values); nil != err {
+					return nil, fmt.Errorf("cannot decode text to array, text: %s, error: %w", v, err)
+				}
+			} else {
+				for _, item := range strings.Split(v, ",") {
+					values = append(values, strings.TrimSpace(item))
+				}
+			}
+		default:
+			rv := reflect.ValueOf(v)
+			if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+				values = []interface{}{v}
+				break
+			}
+			for i := 0; i < rv.Len(); i++ {
+				values = append(values, rv.Index(i).Interface())
+			}
+		}
+		for _, item := range values {
+			ev, err := castElem(item, elem)
+			if nil != err {
+				return nil, err
+			}
+			out = reflect.Append(out, reflect.ValueOf(ev))
+		}
+		return out.Interface(), nil
+	}
+}
+
+var bytesResolver = flux.MTValueResolver(func(mtValue flux.MTValue, _ string, _ []string) (interface{}, error) {
+	if isEmptyOrNil(mtValue.Value) {
+		return []byte{}, nil
+	}
+	return toByteArray(mtValue.Value)
+})
+
+var fileResolver = flux.MTValueResolver(func(mtValue flux.MTValue, _ string, _ []string) (interface{}, error) {
+	switch v := mtValue.Value.(type) {
+	case nil, *multipart.FileHeader, []*multipart.FileHeader:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("cannot convert value to file, value.type: %T", v)
+	}
+})
+
+func castElem(value interface{}, elem reflect.Type) (interface{}, error) {
+	switch elem.Kind() {
+	case reflect.Int16:
+		return cast.ToInt16E(value)
+	case reflect.Int32:
+		return cast.ToInt32E(value)
+	case reflect.Int64:
+		return cast.ToInt64E(value)
+	case reflect.Float32:
+		return cast.ToFloat32E(value)
+	case reflect.Float64:
+		return cast.ToFloat64E(value)
+	case reflect.Bool:
+		return cast.ToBoolE(value)
+	default:
+		return cast.ToStringE(value)
+	}
+}
+
+func toLocalDate(t time.Time) *java8_time.LocalDate {
+	return &java8_time.LocalDate{Year: int32(t.Year()), Month: int32(t.Month()), Day: int32(t.Day())}
+}
+
+func toLocalTime(t time.Time) *java8_time.LocalTime {
+	return &java8_time.LocalTime{Hour: int32(t.Hour()), Minute: int32(t.Minute()), Second: int32(t.Second()), Nano: int32(t.Nanosecond())}
+}
@@ -0,0 +1,80 @@
+package common
+
+import (
+	"github.com/apache/dubbo-go-hessian2/java8_time"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/ext"
+	gxbig "github.com/dubbogo/gost/math/big"
+	"testing"
+	"time"
+
+	assert2 "github.com/stretchr/testify/assert"
+)
+
+func TestJavaDateResolver(t *testing.T) {
+	assert := assert2.New(t)
+	resolver := ext.MTValueResolverByType(flux.JavaUtilDateClassName)
+	v, err := resolver(flux.WrapStringMTValue("2021-03-05 10:20:30"), flux.JavaUtilDateClassName, nil)
+	assert.NoError(err)
+	tm := v.(time.Time)
+	assert.Equal(2021, tm.Year())
+	assert.Equal(time.March, tm.Month())
+	assert.Equal(30, tm.Second())
+	v, err = resolver(flux.WrapObjectMTValue(int64(1614910830000)), flux.JavaUtilDateClassName, nil)
+	assert.NoError(err)
+	assert.Equal(int64(1614910830000), v.(time.Time).UnixNano()/int64(time.Millisecond))
+	_, err = resolver(flux.WrapStringMTValue("not-a-date"), flux.JavaUtilDateClassName, nil)
+	assert.Error(err)
+	v, err = resolver(flux.WrapStringMTValue(""), flux.JavaUtilDateClassName, nil)
+	assert.NoError(err)
+	assert.Nil(v)
+}
+
+func TestJavaTimeResolver(t *testing.T) {
+	assert := assert2.New(t)
+	date, err := ext.MTValueResolverByType(flux.JavaTimeLocalDateClassName)(
+		flux.WrapStringMTValue("2021-03-05"), flux.JavaTimeLocalDateClassName, nil)
+	assert.NoError(err)
+	assert.Equal(&java8_time.LocalDate{Year: 2021, Month: 3, Day: 5}, date)
+	dt, err := ext.MTValueResolverByType(flux.JavaTimeLocalDateTimeClassName)(
+		flux.WrapStringMTValue("2021-03-05T10:20:30"), flux.JavaTimeLocalDateTimeClassName, nil)
+	assert.NoError(err)
+	assert.Equal(&java8_time.LocalDateTime{
+		Date: java8_time.LocalDate{Year: 2021, Month: 3, Day: 5},
+		Time: java8_time.LocalTime{Hour: 10, Minute: 20, Second: 30},
+	}, dt)
+}
+
+func TestJavaBigDecimalResolver(t *testing.T) {
+	assert := assert2.New(t)
+	resolver := ext.MTValueResolverByType(flux.JavaMathBigDecimalClassName)
+	v, err := resolver(flux.WrapStringMTValue("12345.6789"), flux.JavaMathBigDecimalClassName, nil)
+	assert.NoError(err)
+	decimal := v.(gxbig.Decimal)
+	assert.Equal("12345.6789", decimal.String())
+	_, err = resolver(flux.WrapStringMTValue("abc"), flux.JavaMathBigDecimalClassName, nil)
+	assert.Error(err)
+}
+
+func TestJavaArrayResolver(t *testing.T) {
+	ext.RegisterSerializer(ext.TypeNameSerializerJson, flux.NewJsonSerializer())
+	assert := assert2.New(t)
+	v, err := ext.MTValueResolverByType("int[]")(flux.WrapStringMTValue("1, 2,3"), "int[]", nil)
+	assert.NoError(err)
+	assert.Equal([]int32{1, 2, 3}, v)
+	v, err = ext.MTValueResolverByType("[J")(flux.WrapStringMTValue("[10,20]"), "[J", nil)
+	assert.NoError(err)
+	assert.Equal([]int64{10, 20}, v)
+	v, err = ext.MTValueResolverByType("[Ljava.lang.String;")(flux.WrapObjectMTValue([]string{"a", "b"}), "", nil)
+	assert.NoError(err)
+	assert.Equal([]string{"a", "b"}, v)
+	_, err = ext.MTValueResolverByType("int[]")(flux.WrapStringMTValue("1,x"), "int[]", nil)
+	assert.Error(err)
+}
+
+func TestJavaEnumResolver(t *testing.T) {
+	assert := assert2.New(t)
+	v, err := ext.MTValueResolverByType(flux.JavaLangEnumClassName)(flux.WrapStringMTValue(" RED "), "com.foo.Color", nil)
+	assert.NoError(err)
+	assert.Equal("RED", v)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/spf13/cast"
 	"io"
 	"io/ioutil"
+	"mime/multipart"
 	"net/url"
 	"reflect"
 	"strings"
@@ -226,6 +227,12 @@ func toByteArray0(v interface{}) ([]byte, error) {
 		return v.([]byte), nil
 	case string:
 		return []byte(v.(string)), nil
+	case *multipart.FileHeader:
+		file, err := v.(*multipart.FileHeader).Open()
+		if nil != err {
+			return nil, err
+		}
+		return toByteArray0(file)
 	case io.Reader:
 		data, err := ioutil.ReadAll(v.(io.Reader))
 		if closer, ok := v.(io.Closer); ok {
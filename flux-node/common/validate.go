@@ -0,0 +1,350 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-pkg"
+	"github.com/spf13/cast"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// 参数校验的格式
+const (
+	ValidateFormatEmail  = "email"
+	ValidateFormatMobile = "mobile"
+	ValidateFormatURL    = "url"
+	ValidateFormatIPv4   = "ipv4"
+	ValidateFormatUUID   = "uuid"
+	ValidateFormatDigits = "digits"
+)
+
+var (
+	validateFormats = map[string]func(string) bool{
+		ValidateFormatEmail:  regexp.MustCompile(`^[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}$`).MatchString,
+		ValidateFormatMobile: regexp.MustCompile(`^(\+?86)?1[3-9]\d{9}$`).MatchString,
+		ValidateFormatURL:    regexp.MustCompile(`^https?://[^\s/$.?#].[^\s]*$`).MatchString,
+		ValidateFormatIPv4:   regexp.MustCompile(`^((25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\.){3}(25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)$`).MatchString,
+		ValidateFormatUUID:   regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`).MatchString,
+		ValidateFormatDigits: regexp.MustCompile(`^\d+$`).MatchString,
+	}
+	validateFormatsMu sync.RWMutex
+	validatePatterns  sync.Map // pattern -> *regexp.Regexp
+)
+
+var (
+	errValueResolverNotFound = errors.New("argument value resolver not found")
+	errLookupFuncNotFound    = errors.New("argument lookup func not found")
+)
+
+// FieldError 参数字段的校验错误
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+	cause  error  // 非请求参数值的错误：读取请求体失败、参数查找失败、参数定义错误等
+}
+
+// RegisterValidateFormat 注册参数校验的格式函数
+func RegisterValidateFormat(format string, matcher func(string) bool) {
+	format = strings.ToLower(fluxpkg.MustNotEmpty(format, "format is empty"))
+	fluxpkg.AssertNotNil(matcher, "format matcher is nil")
+	validateFormatsMu.Lock()
+	validateFormats[format] = matcher
+	validateFormatsMu.Unlock()
+}
+
+// ValidateArguments 解析并校验参数列表，返回全部校验失败的字段；全部通过时返回nil；
+// 校验规则定义在参数属性中：required, min, max, min_length, max_length, pattern, enum_values, format；
+// 参数值无法转换为参数类型时，同样作为校验失败的字段，返回400错误；参数查找失败、参数定义错误时，返回500错误；
+func ValidateArguments(ctx *flux.Context, args []flux.Argument) *flux.ServeError {
+	_, serr := ResolveArguments(ctx, args)
+	return serr
+}
+
+// ResolveArguments 解析并校验参数列表；校验通过时，返回绑定了已解析参数值的参数列表副本，
+// 后续调用 Argument.Resolve 时直接返回已解析的值，不再重复查找请求参数；校验失败时返回 ValidateArguments 相同的错误；
+func ResolveArguments(ctx *flux.Context, args []flux.Argument) ([]flux.Argument, *flux.ServeError) {
+	errs := make([]FieldError, 0)
+	resolved := make([]flux.Argument, len(args))
+	for i, arg := range args {
+		var value interface{}
+		var ok bool
+		value, ok, errs = validateArgument(ctx, arg, arg.Name, errs)
+		resolved[i] = arg
+		// 请求体Reader只能读取一次，在调用时重新解析
+		if _, isReader := value.(io.Reader); ok && !isReader {
+			resolved[i] = resolvedArgument(arg, value)
+		}
+	}
+	if len(errs) == 0 {
+		return resolved, nil
+	}
+	// 读取请求体失败(超出大小限制、上传文件不符合限制等)，不作为字段校验错误
+	for _, ferr := range errs {
+		var serr *flux.ServeError
+		if errors.As(ferr.cause, &serr) {
+			return nil, serr
+		}
+		if errors.Is(ferr.cause, flux.ErrRequestBodyTooLarge) {
+			return nil, flux.NewRequestBodyError(ferr.cause)
+		}
+	}
+	// 参数查找失败、参数定义错误，是网关内部错误，不向客户端返回错误详情
+	for _, ferr := range errs {
+		if nil != ferr.cause {
+			return nil, &flux.ServeError{
+				StatusCode: flux.StatusServerError,
+				ErrorCode:  flux.ErrorCodeGatewayInternal,
+				Message:    flux.ErrorMessageArgumentsResolve,
+				CauseError: fmt.Errorf("arguments resolve failed, field: %s, error: %w", ferr.Field, ferr.cause),
+			}
+		}
+	}
+	return nil, &flux.ServeError{
+		StatusCode: flux.StatusBadRequest,
+		ErrorCode:  flux.ErrorCodeRequestInvalid,
+		Message:    flux.ErrorMessageRequestValidation,
+		CauseError: fmt.Errorf("arguments validation failed: %+v", errs),
+		Details:    errs,
+	}
+}
+
+// resolvedArgument 返回绑定已解析参数值的参数副本
+func resolvedArgument(arg flux.Argument, value interface{}) flux.Argument {
+	arg.ValueLoader = func() flux.MTValue {
+		return flux.WrapObjectMTValue(value)
+	}
+	arg.ValueResolver = func(_ flux.MTValue, _ string, _ []string) (interface{}, error) {
+		return value, nil
+	}
+	return arg
+}
+
+// validateArgument 解析并校验参数；返回解析的参数值，参数值是否已解析，以及校验失败的字段列表；
+func validateArgument(ctx *flux.Context, arg flux.Argument, path string, errs []FieldError) (interface{}, bool, []FieldError) {
+	// 从请求体绑定的复杂参数，按JSON结构校验字段
+	if IsJSONBindingArgument(arg) {
+		key := arg.HttpName
+		if "" == key {
+			key = arg.Name
+		}
+		if nil == arg.LookupFunc {
+			return nil, false, append(errs, FieldError{Field: path, Reason: errLookupFuncNotFound.Error(), cause: errLookupFuncNotFound})
+		}
+		mtv, err := arg.LookupFunc(arg.HttpScope, key, ctx)
+		if nil != err {
+			return nil, false, append(errs, FieldError{Field: path, Reason: err.Error(), cause: err})
+		}
+		if !mtv.Valid || isEmptyOrNil(mtv.Value) {
+			size := len(errs)
+			errs = checkRequired(arg, path, errs)
+			value, err := bindObject(arg.Fields, arg.Class, map[string]interface{}{}, false)
+			return value, nil == err && len(errs) == size, errs
+		}
+		node, err := bindingNodeOf(arg, arg.GetAttr(flux.ArgumentAttributeTagBindPath).GetString(), mtv)
+		if nil != err {
+			return nil, false, append(errs, FieldError{Field: path, Reason: "invalid json body"})
+		}
+		size := len(errs)
+		if errs = validateNode(arg, node, path, errs); len(errs) > size {
+			return nil, false, errs
+		}
+		value, err := bindJSONNode(arg, arg.Class, arg.Generic, node)
+		return value, nil == err, errs
+	}
+	// 按字段查找的复杂参数
+	if len(arg.Fields) > 0 && nil == arg.ValueLoader {
+		values := make(map[string]interface{}, len(arg.Fields)+1)
+		values["class"] = arg.Class
+		resolved := true
+		for _, field := range arg.Fields {
+			var value interface{}
+			var ok bool
+			value, ok, errs = validateArgument(ctx, field, path+"."+field.Name, errs)
+			values[field.Name] = value
+			resolved = resolved && ok
+		}
+		return values, resolved, errs
+	}
+	var mtv flux.MTValue
+	if nil != arg.ValueLoader {
+		mtv = arg.ValueLoader()
+	} else if nil != arg.LookupFunc {
+		v, err := arg.LookupFunc(arg.HttpScope, arg.HttpName, ctx)
+		if nil != err {
+			return nil, false, append(errs, FieldError{Field: path, Reason: err.Error(), cause: err})
+		}
+		mtv = v
+	} else {
+		return nil, false, append(errs, FieldError{Field: path, Reason: errLookupFuncNotFound.Error(), cause: errLookupFuncNotFound})
+	}
+	if !mtv.Valid || isEmptyOrNil(mtv.Value) {
+		if attr, ok := arg.GetAttrEx(flux.ArgumentAttributeTagDefault); ok {
+			mtv = flux.WrapStringMTValue(attr.GetString())
+		} else {
+			size := len(errs)
+			if errs = checkRequired(arg, path, errs); len(errs) > size {
+				return nil, false, errs
+			}
+			if nil == arg.ValueResolver {
+				return nil, false, append(errs, FieldError{Field: path, Reason: errValueResolverNotFound.Error(), cause: errValueResolverNotFound})
+			}
+			// 非必需的参数，与 Argument.Resolve 相同，按原值解析
+			value, err := arg.ValueResolver(mtv, arg.Class, arg.Generic)
+			return value, nil == err, errs
+		}
+	}
+	return checkValue(arg, mtv, path, errs)
+}
+
+// validateNode 按参数结构校验JSON节点
+func validateNode(arg flux.Argument, node interface{}, path string, errs []FieldError) []FieldError {
+	if nil == node || "" == node {
+		if attr, ok := arg.GetAttrEx(flux.ArgumentAttributeTagDefault); ok && len(arg.Fields) == 0 {
+			_, _, errs = checkValue(arg, flux.WrapStringMTValue(attr.GetString()), path, errs)
+			return errs
+		}
+		return checkRequired(arg, path, errs)
+	}
+	if len(arg.Fields) == 0 {
+		_, _, errs = checkValue(arg, flux.WrapObjectMTValue(node), path, errs)
+		return errs
+	}
+	switch v := node.(type) {
+	case map[string]interface{}:
+		for _, field := range arg.Fields {
+			name := field.HttpName
+			if "" == name {
+				name = field.Name
+			}
+			errs = validateNode(field, v[name], path+"."+field.Name, errs)
+		}
+	case []interface{}:
+		errs = checkRules(arg, v, path, errs)
+		for i, item := range v {
+			if _, ok := item.(map[string]interface{}); !ok {
+				errs = append(errs, FieldError{Field: path + "[" + strconv.Itoa(i) + "]", Reason: "must be an object"})
+				continue
+			}
+			errs = validateNode(arg, item, path+"["+strconv.Itoa(i)+"]", errs)
+		}
+	default:
+		errs = append(errs, FieldError{Field: path, Reason: "must be an object"})
+	}
+	return errs
+}
+
+func checkRequired(arg flux.Argument, path string, errs []FieldError) []FieldError {
+	if arg.GetAttr(flux.ArgumentAttributeTagRequired).GetBool() {
+		return append(errs, FieldError{Field: path, Reason: "is required"})
+	}
+	return errs
+}
+
+// checkValue 转换参数值，并校验规则；返回转换后的参数值，参数值是否通过校验，以及校验失败的字段列表；
+func checkValue(arg flux.Argument, mtv flux.MTValue, path string, errs []FieldError) (interface{}, bool, []FieldError) {
+	if nil == arg.ValueResolver {
+		return nil, false, append(errs, FieldError{Field: path, Reason: errValueResolverNotFound.Error(), cause: errValueResolverNotFound})
+	}
+	value, err := arg.ValueResolver(mtv, arg.Class, arg.Generic)
+	if nil != err {
+		return nil, false, append(errs, FieldError{Field: path, Reason: "invalid value for type " + arg.Class})
+	}
+	size := len(errs)
+	errs = checkRules(arg, value, path, errs)
+	return value, len(errs) == size, errs
+}
+
+func checkRules(arg flux.Argument, value interface{}, path string, errs []FieldError) []FieldError {
+	if attr, ok := arg.GetAttrEx(flux.ArgumentAttributeTagMin); ok {
+		if n, err := cast.ToFloat64E(value); nil != err {
+			errs = append(errs, FieldError{Field: path, Reason: "must be a number"})
+		} else if n < attr.GetFloat64() {
+			errs = append(errs, FieldError{Field: path, Reason: "must be >= " + attr.GetString()})
+		}
+	}
+	if attr, ok := arg.GetAttrEx(flux.ArgumentAttributeTagMax); ok {
+		if n, err := cast.ToFloat64E(value); nil != err {
+			errs = append(errs, FieldError{Field: path, Reason: "must be a number"})
+		} else if n > attr.GetFloat64() {
+			errs = append(errs, FieldError{Field: path, Reason: "must be <= " + attr.GetString()})
+		}
+	}
+	if attr, ok := arg.GetAttrEx(flux.ArgumentAttributeTagMinLength); ok {
+		if lengthOf(value) < attr.GetInt() {
+			errs = append(errs, FieldError{Field: path, Reason: "length must be >= " + attr.GetString()})
+		}
+	}
+	if attr, ok := arg.GetAttrEx(flux.ArgumentAttributeTagMaxLength); ok {
+		if lengthOf(value) > attr.GetInt() {
+			errs = append(errs, FieldError{Field: path, Reason: "length must be <= " + attr.GetString()})
+		}
+	}
+	if attr, ok := arg.GetAttrEx(flux.ArgumentAttributeTagPattern); ok {
+		pattern := attr.GetString()
+		if re, err := patternOf(pattern); nil != err {
+			errs = append(errs, FieldError{Field: path, Reason: "invalid pattern: " + pattern, cause: err})
+		} else if !re.MatchString(cast.ToString(value)) {
+			errs = append(errs, FieldError{Field: path, Reason: "must match pattern " + pattern})
+		}
+	}
+	if attr, ok := arg.GetAttrEx(flux.ArgumentAttributeTagEnumValues); ok {
+		options := attr.GetStringSlice()
+		str := cast.ToString(value)
+		matched := false
+		for _, opt := range options {
+			if opt == str {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			errs = append(errs, FieldError{Field: path, Reason: "must be one of [" + strings.Join(options, ",") + "]"})
+		}
+	}
+	if attr, ok := arg.GetAttrEx(flux.ArgumentAttributeTagFormat); ok {
+		format := strings.ToLower(attr.GetString())
+		validateFormatsMu.RLock()
+		matcher, exists := validateFormats[format]
+		validateFormatsMu.RUnlock()
+		if !exists {
+			errs = append(errs, FieldError{Field: path, Reason: "unknown format " + format, cause: fmt.Errorf("unknown validate format: %s", format)})
+		} else if !matcher(cast.ToString(value)) {
+			errs = append(errs, FieldError{Field: path, Reason: "must be a valid " + format})
+		}
+	}
+	return errs
+}
+
+func lengthOf(value interface{}) int {
+	if str, ok := value.(string); ok {
+		return utf8.RuneCountInString(str)
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return rv.Len()
+	case reflect.Invalid:
+		return 0
+	default:
+		return utf8.RuneCountInString(cast.ToString(value))
+	}
+}
+
+func patternOf(pattern string) (*regexp.Regexp, error) {
+	if re, ok := validatePatterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if nil != err {
+		return nil, err
+	}
+	validatePatterns.Store(pattern, re)
+	return re, nil
+}
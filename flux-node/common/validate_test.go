@@ -0,0 +1,179 @@
+package common
+
+import (
+	"errors"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/ext"
+	"github.com/bytepowered/flux/flux-node/internal/fluxtest"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	assert2 "github.com/stretchr/testify/assert"
+)
+
+func newValidateContext(query string, body string) *flux.Context {
+	mr := httptest.NewRequest("POST", "http://mocking/validate?"+query, strings.NewReader(body))
+	mr.Header.Set(flux.HeaderContentType, flux.MIMEApplicationJSON)
+	return fluxtest.NewContext(mr)
+}
+
+// newLookupArgument 创建从请求中查找参数值的参数
+func newLookupArgument(typeClass, argName string) flux.Argument {
+	arg := ext.NewPrimitiveArgument(typeClass, argName)
+	arg.LookupFunc = LookupMTValue
+	return arg
+}
+
+func withAttrs(arg flux.Argument, attrs ...flux.Attribute) flux.Argument {
+	arg.Attributes = append(arg.Attributes, attrs...)
+	return arg
+}
+
+func TestValidateArguments(t *testing.T) {
+	assert := assert2.New(t)
+	args := []flux.Argument{
+		withAttrs(newLookupArgument(flux.JavaLangIntegerClassName, "age"),
+			flux.Attribute{Name: flux.ArgumentAttributeTagMin, Value: 1},
+			flux.Attribute{Name: flux.ArgumentAttributeTagMax, Value: 150}),
+		withAttrs(newLookupArgument(flux.JavaLangIntegerClassName, "size")),
+		withAttrs(newLookupArgument(flux.JavaLangStringClassName, "name"),
+			flux.Attribute{Name: flux.ArgumentAttributeTagRequired, Value: true}),
+		withAttrs(newLookupArgument(flux.JavaLangStringClassName, "email"),
+			flux.Attribute{Name: flux.ArgumentAttributeTagFormat, Value: "email"}),
+		withAttrs(newLookupArgument(flux.JavaLangStringClassName, "level"),
+			flux.Attribute{Name: flux.ArgumentAttributeTagEnumValues, Value: []interface{}{"LOW", "HIGH"}}),
+		withAttrs(newLookupArgument(flux.JavaLangStringClassName, "code"),
+			flux.Attribute{Name: flux.ArgumentAttributeTagPattern, Value: "^[A-Z]{3}$"},
+			flux.Attribute{Name: flux.ArgumentAttributeTagMaxLength, Value: 3}),
+	}
+	// passed
+	ctx := newValidateContext("age=18&size=10&name=foo&email=foo@bar.com&level=LOW&code=ABC", "")
+	assert.Nil(ValidateArguments(ctx, args))
+	// failed
+	ctx = newValidateContext("age=200&size=abc&email=foo&level=MID&code=abcd", "")
+	serr := ValidateArguments(ctx, args)
+	assert.NotNil(serr)
+	assert.Equal(flux.StatusBadRequest, serr.StatusCode)
+	assert.Equal(flux.ErrorCodeRequestInvalid, serr.ErrorCode)
+	fields := serr.Details.([]FieldError)
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, f.Field)
+	}
+	assert.Equal([]string{"age", "size", "name", "email", "level", "code", "code"}, names)
+}
+
+func TestValidateArguments_JSONBinding(t *testing.T) {
+	ext.RegisterSerializer(ext.TypeNameSerializerJson, flux.NewJsonSerializer())
+	assert := assert2.New(t)
+	arg := newBindingArgument()
+	arg.Fields[0] = withAttrs(arg.Fields[0], flux.Attribute{Name: flux.ArgumentAttributeTagRequired, Value: true})
+	arg.Fields[2] = withAttrs(arg.Fields[2], flux.Attribute{Name: flux.ArgumentAttributeTagMinLength, Value: 1})
+	args := []flux.Argument{arg}
+	ctx := newValidateContext("", `{"orderNo":"N1","items":[{"sku":"A","count":1}]}`)
+	assert.Nil(ValidateArguments(ctx, args))
+	ctx = newValidateContext("", `{"buyer":{"id":"x"},"items":[{"sku":"A","count":"y"}]}`)
+	serr := ValidateArguments(ctx, args)
+	assert.NotNil(serr)
+	assert.Equal([]FieldError{
+		{Field: "order.orderNo", Reason: "is required"},
+		{Field: "order.buyer.id", Reason: "invalid value for type java.lang.Long"},
+		{Field: "order.items[0].count", Reason: "invalid value for type java.lang.Integer"},
+	}, serr.Details)
+	ctx = newValidateContext("", `{"orderNo":"N1","items":[]}`)
+	serr = ValidateArguments(ctx, args)
+	assert.NotNil(serr)
+	assert.Equal([]FieldError{{Field: "order.items", Reason: "length must be >= 1"}}, serr.Details)
+}
+
+func TestValidateArguments_BodyTooLarge(t *testing.T) {
+	assert := assert2.New(t)
+	ctx := newValidateContext("", `{"name":"foo"}`)
+	ctx.Request().GetBody = func() (io.ReadCloser, error) {
+		return nil, flux.ErrRequestBodyTooLarge
+	}
+	arg := newLookupArgument(flux.JavaLangStringClassName, "name")
+	arg.HttpScope = flux.ScopeBodyPath
+	serr := ValidateArguments(ctx, []flux.Argument{arg})
+	assert.NotNil(serr)
+	assert.Equal(flux.StatusTooLarge, serr.StatusCode)
+	assert.Equal(flux.ErrorMessageRequestTooLarge, serr.Message)
+}
+
+func TestResolveArguments_ReuseValues(t *testing.T) {
+	assert := assert2.New(t)
+	lookups := 0
+	countLookup := func(scope, key string, ctx *flux.Context) (flux.MTValue, error) {
+		lookups++
+		return LookupMTValue(scope, key, ctx)
+	}
+	age := withAttrs(newLookupArgument(flux.JavaLangIntegerClassName, "age"),
+		flux.Attribute{Name: flux.ArgumentAttributeTagMin, Value: 1})
+	age.LookupFunc = countLookup
+	name := newLookupArgument(flux.JavaLangStringClassName, "name")
+	name.LookupFunc = countLookup
+	user := ext.NewComplexArgument("com.foo.User", "user")
+	user.Fields = []flux.Argument{name}
+	ctx := newValidateContext("age=18&name=foo", "")
+	args, serr := ResolveArguments(ctx, []flux.Argument{age, user})
+	assert.Nil(serr)
+	assert.Equal(2, lookups)
+	v, err := args[0].Resolve(ctx)
+	assert.NoError(err)
+	assert.Equal(18, v)
+	v, err = args[1].Resolve(ctx)
+	assert.NoError(err)
+	assert.Equal(map[string]interface{}{"class": "com.foo.User", "name": "foo"}, v)
+	assert.Equal(2, lookups)
+	// 校验失败的错误详情
+	_, serr = ResolveArguments(newValidateContext("age=0", ""), []flux.Argument{age})
+	assert.NotNil(serr)
+	assert.Equal([]FieldError{{Field: "age", Reason: "must be >= 1"}}, serr.Details)
+}
+
+func TestResolveArguments_InternalErrors(t *testing.T) {
+	failed := newLookupArgument(flux.JavaLangStringClassName, "name")
+	failed.LookupFunc = func(_, _ string, _ *flux.Context) (flux.MTValue, error) {
+		return flux.NewInvalidMTValue(), errors.New("lookup failed")
+	}
+	noResolver := newLookupArgument(flux.JavaLangStringClassName, "name")
+	noResolver.ValueResolver = nil
+	noLookup := ext.NewPrimitiveArgument(flux.JavaLangStringClassName, "name")
+	noLookup.LookupFunc = nil
+	cases := []struct {
+		name string
+		arg  flux.Argument
+	}{
+		{name: "lookup failed", arg: failed},
+		{name: "value resolver not found", arg: noResolver},
+		{name: "lookup func not found", arg: noLookup},
+		{name: "invalid pattern", arg: withAttrs(newLookupArgument(flux.JavaLangStringClassName, "name"),
+			flux.Attribute{Name: flux.ArgumentAttributeTagPattern, Value: "(foo"})},
+		{name: "unknown format", arg: withAttrs(newLookupArgument(flux.JavaLangStringClassName, "name"),
+			flux.Attribute{Name: flux.ArgumentAttributeTagFormat, Value: "none"})},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert := assert2.New(t)
+			_, serr := ResolveArguments(newValidateContext("name=foo", ""), []flux.Argument{c.arg})
+			assert.NotNil(serr)
+			assert.Equal(flux.StatusServerError, serr.StatusCode)
+			assert.Equal(flux.ErrorCodeGatewayInternal, serr.ErrorCode)
+			assert.Equal(flux.ErrorMessageArgumentsResolve, serr.Message)
+			assert.Nil(serr.Details)
+		})
+	}
+}
+
+func TestResolveArguments_InvalidJSONBody(t *testing.T) {
+	ext.RegisterSerializer(ext.TypeNameSerializerJson, flux.NewJsonSerializer())
+	assert := assert2.New(t)
+	arg := newLookupArgument(flux.JavaLangStringClassName, "name")
+	arg.HttpScope, arg.HttpName = flux.ScopeBodyPath, "$.name"
+	_, serr := ResolveArguments(newValidateContext("", `{"name":`), []flux.Argument{arg})
+	assert.NotNil(serr)
+	assert.Equal(flux.StatusBadRequest, serr.StatusCode)
+	assert.Equal(flux.ErrorMessageRequestPrepare, serr.Message)
+}
@@ -0,0 +1,27 @@
+package common
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	"sync"
+)
+
+// onceVariable 缓存在请求Variable中，只加载一次的值
+type onceVariable struct {
+	once  sync.Once
+	value interface{}
+	err   error
+}
+
+// loadVariableOnce 加载缓存在请求Variable中的值；值不存在时，调用load函数加载并缓存；并发调用时只加载一次；
+func loadVariableOnce(webex flux.ServerWebContext, key string, load func() (interface{}, error)) (interface{}, error) {
+	// 同一请求的多个子调用(例如聚合调用)可能并发加载同一个缓存值
+	actual, _ := webex.LoadOrStoreVariable(key, new(onceVariable))
+	cached, ok := actual.(*onceVariable)
+	if !ok {
+		return load()
+	}
+	cached.once.Do(func() {
+		cached.value, cached.err = load()
+	})
+	return cached.value, cached.err
+}
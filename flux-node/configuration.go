@@ -13,6 +13,7 @@ const (
 	NamespaceWebListeners              = "web_listeners"
 	NamespaceTransporters              = "transporters"
 	NamespaceEndpointDiscoveryServices = "endpoint_discovery_services"
+	NamespaceValueResolvers            = "value_resolvers"
 )
 
 // NewGlobalConfiguration 创建全局Viper实例的配置对象
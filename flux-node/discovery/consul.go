@@ -0,0 +1,367 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/ext"
+	"github.com/bytepowered/flux/flux-node/logger"
+	"github.com/bytepowered/flux/flux-node/remoting"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// 在Consul KV注册的根路径。需要与客户端的注册保持一致。
+	consulDiscoveryEndpointPath = "flux-endpoint"
+	consulDiscoveryServicePath  = "flux-service"
+)
+
+const (
+	ConsulId = "consul"
+)
+
+const (
+	consulConfigRootpathEndpoint = "rootpath_endpoint"
+	consulConfigRootpathService  = "rootpath_service"
+	consulConfigRegistrySelector = "registry_selector"
+	consulConfigWaitTime         = "wait_time"
+	consulConfigRetryInterval    = "retry_interval"
+)
+
+const (
+	consulHeaderIndex = "X-Consul-Index"
+	consulHeaderToken = "X-Consul-Token"
+)
+
+var _ flux.EndpointDiscovery = new(ConsulDiscoveryService)
+
+type (
+	// ConsulOption 配置函数
+	ConsulOption func(discovery *ConsulDiscoveryService)
+)
+
+// WithConsulHttpClient 配置请求Consul的HttpClient
+func WithConsulHttpClient(client *http.Client) ConsulOption {
+	return func(discovery *ConsulDiscoveryService) {
+		discovery.client = client
+	}
+}
+
+// NewConsulServiceWith returns new a consul discovery service
+func NewConsulServiceWith(id string, opts ...ConsulOption) *ConsulDiscoveryService {
+	r := &ConsulDiscoveryService{
+		id:     id,
+		client: &http.Client{},
+	}
+	r.watchCtx, r.watchCancel = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// ConsulDiscoveryService 基于Consul KV实现的Endpoint元数据注册中心；
+// 通过阻塞查询(Blocking Query)监听根路径下的全部Key，对比新旧数据发送Added/Updated/Removed事件；
+type ConsulDiscoveryService struct {
+	id            string
+	client        *http.Client
+	endpointPath  string
+	servicePath   string
+	waitTime      time.Duration
+	retryInterval time.Duration
+	registries    []consulRegistry
+	// 监听协程的生命周期
+	watchCtx    context.Context
+	watchCancel context.CancelFunc
+	watchGroup  sync.WaitGroup
+}
+
+// consulRegistry Consul注册中心（数据中心）的连接配置
+type consulRegistry struct {
+	id         string
+	address    string
+	datacenter string
+	token      string
+}
+
+// consulKVPair Consul KV接口返回的数据
+type consulKVPair struct {
+	Key         string `json:"Key"`
+	Value       []byte `json:"Value"`
+	ModifyIndex uint64 `json:"ModifyIndex"`
+}
+
+func (r *ConsulDiscoveryService) Id() string {
+	return r.id
+}
+
+// Init init discovery
+func (r *ConsulDiscoveryService) Init(config *flux.Configuration) error {
+	config.SetDefaults(map[string]interface{}{
+		consulConfigRootpathEndpoint: consulDiscoveryEndpointPath,
+		consulConfigRootpathService:  consulDiscoveryServicePath,
+		consulConfigWaitTime:         55 * time.Second,
+		consulConfigRetryInterval:    5 * time.Second,
+	})
+	// 未配置注册中心时，不启用Consul注册中心
+	if !config.IsSet("registry_centers") {
+		logger.Infow("ConsulEndpointDiscovery registry centers not configured, skip")
+		return nil
+	}
+	selected := config.GetStringSlice(consulConfigRegistrySelector)
+	if len(selected) == 0 {
+		selected = []string{"default"}
+	}
+	logger.Infow("ConsulEndpointDiscovery selected discovery", "selected-ids", selected)
+	r.endpointPath = toConsulPrefix(config.GetString(consulConfigRootpathEndpoint))
+	r.servicePath = toConsulPrefix(config.GetString(consulConfigRootpathService))
+	if r.endpointPath == "" || r.servicePath == "" {
+		return errors.New("config(rootpath_endpoint, rootpath_service) is empty")
+	}
+	r.waitTime = config.GetDuration(consulConfigWaitTime)
+	r.retryInterval = config.GetDuration(consulConfigRetryInterval)
+	r.registries = make([]consulRegistry, len(selected))
+	registries := config.Sub("registry_centers")
+	for i := range selected {
+		id := selected[i]
+		conf := registries.Sub(id)
+		conf.SetGlobalAlias(map[string]string{
+			"address":    "consul.address",
+			"datacenter": "consul.datacenter",
+			"token":      "consul.token",
+		})
+		address := conf.GetString("address")
+		if "" == address {
+			return fmt.Errorf("consul registry config(address) is empty, registry-id: %s", id)
+		}
+		if !strings.Contains(address, "://") {
+			address = "http://" + address
+		}
+		if _, err := url.Parse(address); nil != err {
+			return fmt.Errorf("invalid consul address: %s, registry-id: %s, error: %w", address, id, err)
+		}
+		r.registries[i] = consulRegistry{
+			id:         id,
+			address:    strings.TrimSuffix(address, "/"),
+			datacenter: conf.GetString("datacenter"),
+			token:      conf.GetString("token"),
+		}
+		logger.Infow("ConsulEndpointDiscovery start consul discovery", "discovery-id", id, "address", address)
+	}
+	return nil
+}
+
+// WatchEndpoints Listen http endpoints events
+func (r *ConsulDiscoveryService) WatchEndpoints(ctx context.Context, events chan<- flux.EndpointEvent) error {
+	const msg = "DISCOVERY:CONSUL:ENDPOINT:LISTEN_KEY"
+	callback := func(event remoting.NodeEvent) {
+		if evt, err := NewEndpointEvent(event.Data, event.EventType); nil == err {
+			sendEndpointEvent(ctx, events, evt)
+		} else {
+			logger.Errorw(msg, "endpoint-event", event, "error", err)
+		}
+	}
+	logger.Infow(msg, "endpoint-path", r.endpointPath)
+	r.onRegistries(ctx, r.endpointPath, callback)
+	return nil
+}
+
+// WatchServices Listen gateway services events
+func (r *ConsulDiscoveryService) WatchServices(ctx context.Context, events chan<- flux.ServiceEvent) error {
+	const msg = "DISCOVERY:CONSUL:SERVICE:LISTEN_KEY"
+	callback := func(event remoting.NodeEvent) {
+		if evt, ok := NewServiceEvent(event.Data, event.EventType, event.Path); ok {
+			sendServiceEvent(ctx, events, evt)
+		}
+	}
+	logger.Infow(msg, "service-path", r.servicePath)
+	r.onRegistries(ctx, r.servicePath, callback)
+	return nil
+}
+
+// onRegistries 在每个注册中心同步加载一次全部数据，之后在独立协程中通过阻塞查询监听变更；
+func (r *ConsulDiscoveryService) onRegistries(ctx context.Context, prefix string, callback func(remoting.NodeEvent)) {
+	for _, registry := range r.registries {
+		watcher := &consulWatcher{registry: registry, prefix: prefix, known: make(map[string]consulKVPair)}
+		if err := r.sync(ctx, watcher, false, callback); nil != err {
+			logger.Errorw("DISCOVERY:CONSUL:REGISTRIES:WATCH/Error", "registry-id", registry.id, "watch-path", prefix, "error", err)
+		} else {
+			logger.Infow("DISCOVERY:CONSUL:REGISTRIES:WATCH/Success", "registry-id", registry.id, "watch-path", prefix)
+		}
+		r.watchGroup.Add(1)
+		go func(watcher *consulWatcher) {
+			defer r.watchGroup.Done()
+			wctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			go func() {
+				select {
+				case <-r.watchCtx.Done():
+					cancel()
+				case <-wctx.Done():
+				}
+			}()
+			r.watch(wctx, watcher, callback)
+		}(watcher)
+	}
+}
+
+// Shutdown shutdown discovery service
+func (r *ConsulDiscoveryService) Shutdown(ctx context.Context) error {
+	logger.Info("ConsulEndpointDiscovery shutdown")
+	r.watchCancel()
+	done := make(chan struct{})
+	go func() {
+		r.watchGroup.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// consulWatcher 单个注册中心、单个根路径的监听状态
+type consulWatcher struct {
+	registry consulRegistry
+	prefix   string
+	index    uint64
+	known    map[string]consulKVPair
+}
+
+func (r *ConsulDiscoveryService) watch(ctx context.Context, watcher *consulWatcher, callback func(remoting.NodeEvent)) {
+	for {
+		err := r.sync(ctx, watcher, true, callback)
+		if nil != ctx.Err() {
+			logger.Infow("DISCOVERY:CONSUL:REGISTRIES:WATCH/CANCELED", "registry-id", watcher.registry.id, "watch-path", watcher.prefix)
+			return
+		}
+		if nil == err && watcher.index > 0 {
+			continue
+		}
+		// 查询失败，或者未返回索引无法阻塞查询时，按重试间隔等待
+		if nil != err {
+			logger.Warnw("DISCOVERY:CONSUL:REGISTRIES:WATCH/Error", "registry-id", watcher.registry.id, "watch-path", watcher.prefix, "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.retryInterval):
+		}
+	}
+}
+
+// sync 查询根路径下的全部Key，与已知数据对比后回调变更事件；
+func (r *ConsulDiscoveryService) sync(ctx context.Context, watcher *consulWatcher, blocking bool, callback func(remoting.NodeEvent)) error {
+	index := uint64(0)
+	if blocking {
+		index = watcher.index
+	}
+	pairs, newIndex, err := r.list(ctx, watcher.registry, watcher.prefix, index)
+	if nil != err {
+		return err
+	}
+	// Consul索引可能被重置，此时需要从头开始查询
+	if newIndex < watcher.index {
+		watcher.index = 0
+	} else {
+		watcher.index = newIndex
+	}
+	latest := make(map[string]consulKVPair, len(pairs))
+	for _, pair := range pairs {
+		if strings.HasSuffix(pair.Key, "/") || len(pair.Value) == 0 {
+			continue
+		}
+		latest[pair.Key] = pair
+	}
+	for _, key := range sortedKeys(watcher.known) {
+		if _, ok := latest[key]; !ok {
+			callback(remoting.NodeEvent{Path: key, EventType: remoting.EventTypeNodeDelete, Data: watcher.known[key].Value})
+		}
+	}
+	for _, key := range sortedKeys(latest) {
+		pair := latest[key]
+		if old, ok := watcher.known[key]; !ok {
+			callback(remoting.NodeEvent{Path: key, EventType: remoting.EventTypeNodeAdd, Data: pair.Value})
+		} else if old.ModifyIndex != pair.ModifyIndex {
+			callback(remoting.NodeEvent{Path: key, EventType: remoting.EventTypeNodeUpdate, Data: pair.Value})
+		}
+	}
+	watcher.known = latest
+	return nil
+}
+
+// consulWait 返回阻塞查询的等待时间参数；使用毫秒精度，避免小于1秒的等待时间被截断为0(Consul按默认的5分钟等待)
+func consulWait(wait time.Duration) string {
+	ms := int64(wait / time.Millisecond)
+	if ms < 1 {
+		ms = 1
+	}
+	return strconv.FormatInt(ms, 10) + "ms"
+}
+
+// list 查询Consul KV根路径下的全部Key；index大于0时使用阻塞查询，直到数据变更或者等待超时；
+func (r *ConsulDiscoveryService) list(ctx context.Context, registry consulRegistry, prefix string, index uint64) ([]consulKVPair, uint64, error) {
+	query := url.Values{}
+	query.Set("recurse", "true")
+	if "" != registry.datacenter {
+		query.Set("dc", registry.datacenter)
+	}
+	timeout := 10 * time.Second
+	if index > 0 {
+		query.Set("index", strconv.FormatUint(index, 10))
+		query.Set("wait", consulWait(r.waitTime))
+		// Consul会在等待时间上增加最多 wait/16 的随机时间
+		timeout += r.waitTime + r.waitTime/16
+	}
+	reqctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	addr := registry.address + "/v1/kv/" + prefix + "?" + query.Encode()
+	request, err := http.NewRequestWithContext(reqctx, http.MethodGet, addr, nil)
+	if nil != err {
+		return nil, 0, err
+	}
+	if "" != registry.token {
+		request.Header.Set(consulHeaderToken, registry.token)
+	}
+	resp, err := r.client.Do(request)
+	if nil != err {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	newIndex, _ := strconv.ParseUint(resp.Header.Get(consulHeaderIndex), 10, 64)
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		// 根路径下没有任何Key
+		return nil, newIndex, nil
+	case http.StatusOK:
+		data, err := ioutil.ReadAll(resp.Body)
+		if nil != err {
+			return nil, 0, fmt.Errorf("read consul response, error: %w", err)
+		}
+		pairs := make([]consulKVPair, 0)
+		if err := ext.JSONUnmarshal(data, &pairs); nil != err {
+			return nil, 0, fmt.Errorf("decode consul response, error: %w", err)
+		}
+		return pairs, newIndex, nil
+	default:
+		return nil, 0, fmt.Errorf("unexpected consul response status: %d", resp.StatusCode)
+	}
+}
+
+// toConsulPrefix 将根路径转换为Consul KV的前缀：/flux-endpoint -> flux-endpoint/
+func toConsulPrefix(path string) string {
+	path = strings.Trim(path, "/")
+	if "" == path {
+		return ""
+	}
+	return path + "/"
+}
@@ -0,0 +1,154 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/ext"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeConsul 模拟Consul KV的阻塞查询接口，按数据中心保存数据
+type fakeConsul struct {
+	mu    sync.Mutex
+	index uint64
+	data  map[string]map[string]consulKVPair
+}
+
+func newFakeConsul() *fakeConsul {
+	return &fakeConsul{index: 1, data: make(map[string]map[string]consulKVPair)}
+}
+
+func (f *fakeConsul) put(dc, key, value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.index++
+	if nil == f.data[dc] {
+		f.data[dc] = make(map[string]consulKVPair)
+	}
+	f.data[dc][key] = consulKVPair{Key: key, Value: []byte(value), ModifyIndex: f.index}
+}
+
+func (f *fakeConsul) delete(dc, key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.index++
+	delete(f.data[dc], key)
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	prefix := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+	index, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64)
+	wait, _ := time.ParseDuration(r.URL.Query().Get("wait"))
+	deadline := time.Now().Add(wait)
+	for {
+		f.mu.Lock()
+		if index < f.index || time.Now().After(deadline) {
+			break
+		}
+		f.mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+	}
+	defer f.mu.Unlock()
+	pairs := make([]consulKVPair, 0)
+	for key, pair := range f.data[r.URL.Query().Get("dc")] {
+		if strings.HasPrefix(key, prefix) {
+			pairs = append(pairs, pair)
+		}
+	}
+	w.Header().Set(consulHeaderIndex, strconv.FormatUint(f.index, 10))
+	if len(pairs) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	data, _ := json.Marshal(pairs)
+	_, _ = w.Write(data)
+}
+
+func consulEndpoint(pattern, method string) string {
+	return `{"version":"1.0","httpMethod":"GET","httpPattern":"` + pattern +
+		`","service":{"interface":"foo","method":"` + method + `"}}`
+}
+
+func TestConsulDiscovery_WatchEndpoints(t *testing.T) {
+	ext.RegisterSerializer(ext.TypeNameSerializerJson, flux.NewJsonSerializer())
+	assert := assert2.New(t)
+	consul := newFakeConsul()
+	consul.put("dc1", "flux-endpoint/a", consulEndpoint("/api/a", "a"))
+	consul.put("dc2", "flux-endpoint/b", consulEndpoint("/api/b", "b"))
+	consul.put("dc1", "flux-service/s", `{"interface":"foo","method":"s"}`)
+	server := httptest.NewServer(consul)
+	defer server.Close()
+	r := NewConsulServiceWith("test")
+	assert.NoError(r.Init(flux.NewConfigurationOfMap(map[string]interface{}{
+		"wait_time":         "300ms",
+		"retry_interval":    "20ms",
+		"registry_selector": []string{"dc1", "dc2"},
+		"registry_centers": map[string]interface{}{
+			"dc1": map[string]interface{}{"address": server.URL, "datacenter": "dc1"},
+			"dc2": map[string]interface{}{"address": server.URL, "datacenter": "dc2"},
+		},
+	})))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer r.Shutdown(context.Background())
+	endpoints := make(chan flux.EndpointEvent, 16)
+	services := make(chan flux.ServiceEvent, 16)
+	assert.NoError(r.WatchEndpoints(ctx, endpoints))
+	assert.NoError(r.WatchServices(ctx, services))
+	assert.Equal(2, len(endpoints))
+	assert.Equal(1, len(services))
+	<-endpoints
+	<-endpoints
+	<-services
+	next := func() flux.EndpointEvent {
+		select {
+		case evt := <-endpoints:
+			return evt
+		case <-time.After(3 * time.Second):
+			t.Fatal("watch event timeout")
+			return flux.EndpointEvent{}
+		}
+	}
+	consul.put("dc1", "flux-endpoint/c", consulEndpoint("/api/c", "c"))
+	evt := next()
+	assert.Equal(flux.EventType(flux.EventTypeAdded), evt.EventType)
+	assert.Equal("/api/c", evt.Endpoint.HttpPattern)
+	consul.put("dc2", "flux-endpoint/b", consulEndpoint("/api/b", "b2"))
+	evt = next()
+	assert.Equal(flux.EventType(flux.EventTypeUpdated), evt.EventType)
+	assert.Equal("b2", evt.Endpoint.Service.Method)
+	consul.delete("dc1", "flux-endpoint/a")
+	evt = next()
+	assert.Equal(flux.EventType(flux.EventTypeRemoved), evt.EventType)
+	assert.Equal("/api/a", evt.Endpoint.HttpPattern)
+}
+
+func TestConsulDiscovery_NotConfigured(t *testing.T) {
+	r := NewConsulServiceWith("test")
+	assert2.NoError(t, r.Init(flux.NewConfigurationOfMap(map[string]interface{}{})))
+	assert2.NoError(t, r.WatchEndpoints(context.Background(), make(chan flux.EndpointEvent)))
+}
+
+func TestConsulWait(t *testing.T) {
+	cases := []struct {
+		wait     time.Duration
+		expected string
+	}{
+		{wait: time.Minute, expected: "60000ms"},
+		{wait: 1500 * time.Millisecond, expected: "1500ms"},
+		{wait: 500 * time.Millisecond, expected: "500ms"},
+		{wait: time.Microsecond, expected: "1ms"},
+		{wait: 0, expected: "1ms"},
+	}
+	for _, tc := range cases {
+		assert2.Equal(t, tc.expected, consulWait(tc.wait))
+	}
+}
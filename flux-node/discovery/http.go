@@ -0,0 +1,299 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/ext"
+	"github.com/bytepowered/flux/flux-node/logger"
+	"github.com/bytepowered/flux/flux-node/remoting"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	HttpId = "http"
+)
+
+const (
+	httpConfigEndpointUrls    = "endpoint_urls"
+	httpConfigServiceUrls     = "service_urls"
+	httpConfigHeaders         = "headers"
+	httpConfigPollInterval    = "poll_interval"
+	httpConfigRequestTimeout  = "request_timeout"
+	httpConfigLongPollEnable  = "long_poll_enable"
+	httpConfigLongPollTimeout = "long_poll_timeout"
+	httpConfigLongPollParam   = "long_poll_param"
+)
+
+var _ flux.EndpointDiscovery = new(HttpDiscoveryService)
+
+type (
+	// HttpOption 配置函数
+	HttpOption func(discovery *HttpDiscoveryService)
+)
+
+// WithHttpClient 配置拉取元数据的HttpClient
+func WithHttpClient(client *http.Client) HttpOption {
+	return func(discovery *HttpDiscoveryService) {
+		discovery.client = client
+	}
+}
+
+// NewHttpServiceWith returns new a http polling discovery service
+func NewHttpServiceWith(id string, opts ...HttpOption) *HttpDiscoveryService {
+	r := &HttpDiscoveryService{
+		id:     id,
+		client: &http.Client{},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// HttpDiscoveryService 通过Http轮询配置服务的Endpoint元数据注册中心；
+// 配置服务返回Endpoint/Service的JSON数组，支持ETag/If-None-Match和长轮询；
+// 每次拉取的数据与当前快照对比，发送Added/Updated/Removed事件；配置服务不可用时保留最后一次有效的数据；
+type HttpDiscoveryService struct {
+	id              string
+	client          *http.Client
+	endpointUrls    []string
+	serviceUrls     []string
+	headers         map[string]string
+	pollInterval    time.Duration
+	requestTimeout  time.Duration
+	longPollEnable  bool
+	longPollTimeout time.Duration
+	longPollParam   string
+}
+
+// httpSource 单个配置地址的拉取状态
+type httpSource struct {
+	url   string
+	etag  string
+	value interface{}
+}
+
+func (r *HttpDiscoveryService) Id() string {
+	return r.id
+}
+
+func (r *HttpDiscoveryService) Init(config *flux.Configuration) error {
+	config.SetDefaults(map[string]interface{}{
+		httpConfigPollInterval:    10 * time.Second,
+		httpConfigRequestTimeout:  5 * time.Second,
+		httpConfigLongPollEnable:  false,
+		httpConfigLongPollTimeout: 30 * time.Second,
+		httpConfigLongPollParam:   "wait",
+	})
+	r.endpointUrls = config.GetStringSlice(httpConfigEndpointUrls)
+	r.serviceUrls = config.GetStringSlice(httpConfigServiceUrls)
+	r.headers = config.GetStringMapString(httpConfigHeaders)
+	r.pollInterval = config.GetDuration(httpConfigPollInterval)
+	r.requestTimeout = config.GetDuration(httpConfigRequestTimeout)
+	r.longPollEnable = config.GetBool(httpConfigLongPollEnable)
+	r.longPollTimeout = config.GetDuration(httpConfigLongPollTimeout)
+	r.longPollParam = config.GetString(httpConfigLongPollParam)
+	if r.pollInterval <= 0 {
+		return fmt.Errorf("invalid config(poll_interval): %s", r.pollInterval)
+	}
+	for _, urls := range [][]string{r.endpointUrls, r.serviceUrls} {
+		for _, addr := range urls {
+			if _, err := url.Parse(addr); nil != err {
+				return fmt.Errorf("invalid discovery url: %s, error: %w", addr, err)
+			}
+		}
+	}
+	logger.Infow("Http discovery, poll urls", "endpoint-urls", r.endpointUrls, "service-urls", r.serviceUrls,
+		"long-poll", r.longPollEnable)
+	return nil
+}
+
+func (r *HttpDiscoveryService) WatchEndpoints(ctx context.Context, events chan<- flux.EndpointEvent) error {
+	current := make(map[string]flux.Endpoint)
+	r.watch(ctx, r.endpointUrls, decodeHttpEndpoints, func(values []interface{}) {
+		merged := make(map[string]flux.Endpoint)
+		for _, v := range values {
+			if nil != v {
+				for key, ep := range v.(map[string]flux.Endpoint) {
+					merged[key] = ep
+				}
+			}
+		}
+		for _, evt := range DiffEndpoints(current, merged) {
+			if !sendEndpointEvent(ctx, events, evt) {
+				return
+			}
+		}
+		current = merged
+	})
+	return nil
+}
+
+func (r *HttpDiscoveryService) WatchServices(ctx context.Context, events chan<- flux.ServiceEvent) error {
+	current := make(map[string]flux.TransporterService)
+	r.watch(ctx, r.serviceUrls, decodeHttpServices, func(values []interface{}) {
+		merged := make(map[string]flux.TransporterService)
+		for _, v := range values {
+			if nil != v {
+				for key, srv := range v.(map[string]flux.TransporterService) {
+					merged[key] = srv
+				}
+			}
+		}
+		for _, evt := range DiffServices(current, merged) {
+			if !sendServiceEvent(ctx, events, evt) {
+				return
+			}
+		}
+		current = merged
+	})
+	return nil
+}
+
+// watch 首次同步拉取全部地址，之后每个地址在独立协程中轮询；任一地址的数据变更时，合并全部地址的数据并回调；
+func (r *HttpDiscoveryService) watch(ctx context.Context, urls []string, decoder func([]byte) (interface{}, error), onChanged func([]interface{})) {
+	if len(urls) == 0 {
+		return
+	}
+	mu := new(sync.Mutex)
+	sources := make([]*httpSource, len(urls))
+	for i, addr := range urls {
+		sources[i] = &httpSource{url: addr}
+	}
+	notify := func() {
+		values := make([]interface{}, len(sources))
+		for i, src := range sources {
+			values[i] = src.value
+		}
+		onChanged(values)
+	}
+	for _, src := range sources {
+		if value, changed, err := r.poll(ctx, src, false, decoder); nil != err {
+			logger.Warnw("DISCOVERY:HTTP:POLL/ERROR", "url", src.url, "error", err)
+		} else if changed {
+			src.value = value
+		}
+	}
+	notify()
+	for _, src := range sources {
+		go r.loop(ctx, src, decoder, func(src *httpSource, value interface{}) {
+			mu.Lock()
+			defer mu.Unlock()
+			src.value = value
+			notify()
+		})
+	}
+}
+
+func (r *HttpDiscoveryService) loop(ctx context.Context, src *httpSource, decoder func([]byte) (interface{}, error), update func(*httpSource, interface{})) {
+	logger.Infow("DISCOVERY:HTTP:POLL/START", "url", src.url)
+	defer logger.Infow("DISCOVERY:HTTP:POLL/STOP", "url", src.url)
+	for {
+		start := time.Now()
+		value, changed, err := r.poll(ctx, src, r.longPollEnable, decoder)
+		if nil != ctx.Err() {
+			return
+		}
+		if nil != err {
+			logger.Warnw("DISCOVERY:HTTP:POLL/ERROR", "url", src.url, "error", err)
+		} else if changed {
+			update(src, value)
+		}
+		// 长轮询模式下，配置服务挂起请求直到数据变更；请求失败或者立即返回未变更时，按轮询间隔等待
+		if r.longPollEnable && nil == err && (changed || time.Since(start) >= time.Second) {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.pollInterval):
+		}
+	}
+}
+
+// poll 拉取并解析地址的数据；返回解析后的数据和数据是否变更；请求或者解析失败时不更新ETag；
+func (r *HttpDiscoveryService) poll(ctx context.Context, src *httpSource, wait bool, decoder func([]byte) (interface{}, error)) (interface{}, bool, error) {
+	addr, timeout := src.url, r.requestTimeout
+	if wait && "" != src.etag {
+		u, _ := url.Parse(src.url)
+		query := u.Query()
+		query.Set(r.longPollParam, strconv.Itoa(int(r.longPollTimeout/time.Second)))
+		u.RawQuery = query.Encode()
+		addr, timeout = u.String(), timeout+r.longPollTimeout
+	}
+	reqctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(reqctx, http.MethodGet, addr, nil)
+	if nil != err {
+		return nil, false, err
+	}
+	for key, value := range r.headers {
+		request.Header.Set(key, value)
+	}
+	request.Header.Set(flux.HeaderAccept, flux.MIMEApplicationJSON)
+	if "" != src.etag {
+		request.Header.Set("If-None-Match", src.etag)
+	}
+	resp, err := r.client.Do(request)
+	if nil != err {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return nil, false, nil
+	case http.StatusOK:
+		data, err := ioutil.ReadAll(resp.Body)
+		if nil != err {
+			return nil, false, fmt.Errorf("read response, error: %w", err)
+		}
+		value, err := decoder(data)
+		if nil != err {
+			return nil, false, err
+		}
+		src.etag = resp.Header.Get("ETag")
+		return value, true, nil
+	default:
+		return nil, false, fmt.Errorf("unexpected response status: %d", resp.StatusCode)
+	}
+}
+
+// decodeHttpEndpoints 解析Endpoint的JSON数组；无效的Endpoint被忽略；
+func decodeHttpEndpoints(data []byte) (interface{}, error) {
+	items := make([]json.RawMessage, 0)
+	if err := ext.JSONUnmarshal(data, &items); nil != err {
+		return nil, fmt.Errorf("ILLEGAL_JSONFORMAT: err: %w", err)
+	}
+	endpoints := make(map[string]flux.Endpoint, len(items))
+	for _, item := range items {
+		evt, err := NewEndpointEvent(item, remoting.EventTypeNodeAdd)
+		if nil != err {
+			logger.Warnw("DISCOVERY:HTTP:ENDPOINT:INVALID", "data", string(item), "error", err)
+			continue
+		}
+		endpoints[EndpointKey(evt.Endpoint)] = evt.Endpoint
+	}
+	return endpoints, nil
+}
+
+// decodeHttpServices 解析Service的JSON数组；无效的Service被忽略；
+func decodeHttpServices(data []byte) (interface{}, error) {
+	items := make([]json.RawMessage, 0)
+	if err := ext.JSONUnmarshal(data, &items); nil != err {
+		return nil, fmt.Errorf("ILLEGAL_JSONFORMAT: err: %w", err)
+	}
+	services := make(map[string]flux.TransporterService, len(items))
+	for _, item := range items {
+		if evt, ok := NewServiceEvent(item, remoting.EventTypeNodeAdd, "http"); ok {
+			services[evt.Service.ServiceID()] = evt.Service
+		}
+	}
+	return services, nil
+}
@@ -0,0 +1,87 @@
+package discovery
+
+import (
+	"context"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/ext"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type mockConfigServer struct {
+	mu     sync.Mutex
+	etag   string
+	body   string
+	status int
+}
+
+func (m *mockConfigServer) set(etag, body string, status int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.etag, m.body, m.status = etag, body, status
+}
+
+func (m *mockConfigServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.status != http.StatusOK {
+		w.WriteHeader(m.status)
+		return
+	}
+	if r.Header.Get("If-None-Match") == m.etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", m.etag)
+	_, _ = w.Write([]byte(m.body))
+}
+
+func TestHttpDiscovery_WatchEndpoints(t *testing.T) {
+	ext.RegisterSerializer(ext.TypeNameSerializerJson, flux.NewJsonSerializer())
+	assert := assert2.New(t)
+	mock := &mockConfigServer{}
+	mock.set("v1", `[
+		{"version":"1.0","httpMethod":"GET","httpPattern":"/api/a","service":{"interface":"foo","method":"a"}},
+		{"version":"1.0","httpMethod":"GET","httpPattern":"/api/b","service":{"interface":"foo","method":"b"}},
+		{"httpMethod":"GET"}
+	]`, http.StatusOK)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+	r := NewHttpServiceWith("test")
+	assert.NoError(r.Init(flux.NewConfigurationOfMap(map[string]interface{}{
+		"endpoint_urls": []string{server.URL},
+		"poll_interval": "20ms",
+	})))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan flux.EndpointEvent, 16)
+	assert.NoError(r.WatchEndpoints(ctx, events))
+	assert.Equal(2, len(events))
+	<-events
+	<-events
+	// unreachable: keep last known
+	mock.set("v1", "", http.StatusServiceUnavailable)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(0, len(events))
+	// changed
+	mock.set("v2", `[
+		{"version":"1.0","httpMethod":"GET","httpPattern":"/api/a","service":{"interface":"foo","method":"a2"}}
+	]`, http.StatusOK)
+	received := make(map[string]flux.EventType)
+	for len(received) < 2 {
+		select {
+		case evt := <-events:
+			received[evt.Endpoint.HttpPattern] = evt.EventType
+		case <-time.After(3 * time.Second):
+			t.Fatal("poll event timeout")
+		}
+	}
+	assert.Equal(map[string]flux.EventType{
+		"/api/a": flux.EventTypeUpdated,
+		"/api/b": flux.EventTypeRemoved,
+	}, received)
+}
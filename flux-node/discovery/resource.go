@@ -6,14 +6,33 @@ import (
 	"github.com/bytepowered/flux/flux-node"
 	"github.com/bytepowered/flux/flux-node/ext"
 	"github.com/bytepowered/flux/flux-node/logger"
+	"github.com/fsnotify/fsnotify"
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
 const (
 	ResourceId = "resource"
 )
 
+const (
+	resourceConfigIncludes    = "includes"
+	resourceConfigWatchEnable = "watch_enable"
+	resourceConfigWatchDelay  = "watch_delay"
+)
+
+const (
+	// 文件变更后延迟重新加载，合并编辑器保存文件时产生的多个事件
+	defaultResourceWatchDelay = 500 * time.Millisecond
+)
+
 var _ flux.EndpointDiscovery = new(ResourceDiscoveryService)
 
 type (
@@ -30,7 +49,8 @@ type Resources struct {
 func NewResourceServiceWith(id string, opts ...ResourceOption) *ResourceDiscoveryService {
 	r := &ResourceDiscoveryService{
 		id:        id,
-		resources: make([]Resources, 0, 8),
+		endpoints: make(map[string]flux.Endpoint),
+		services:  make(map[string]flux.TransporterService),
 	}
 	for _, opt := range opts {
 		opt(r)
@@ -38,9 +58,21 @@ func NewResourceServiceWith(id string, opts ...ResourceOption) *ResourceDiscover
 	return r
 }
 
+// ResourceDiscoveryService 基于本地YAML资源文件的Endpoint元数据注册中心；
+// 开启监听时，资源文件变更后重新加载，并对比新旧资源，发送Added/Updated/Removed事件；
 type ResourceDiscoveryService struct {
-	id        string
-	resources []Resources
+	id          string
+	includes    []string
+	local       Resources
+	watchEnable bool
+	watchDelay  time.Duration
+	// 当前生效的资源
+	mu             sync.Mutex
+	endpoints      map[string]flux.Endpoint
+	services       map[string]flux.TransporterService
+	endpointEvents chan<- flux.EndpointEvent
+	serviceEvents  chan<- flux.ServiceEvent
+	watchOnce      sync.Once
 }
 
 func (r *ResourceDiscoveryService) Id() string {
@@ -48,66 +80,308 @@ func (r *ResourceDiscoveryService) Id() string {
 }
 
 func (r *ResourceDiscoveryService) Init(config *flux.Configuration) error {
+	config.SetDefaults(map[string]interface{}{
+		resourceConfigWatchEnable: true,
+		resourceConfigWatchDelay:  defaultResourceWatchDelay,
+	})
+	r.watchEnable = config.GetBool(resourceConfigWatchEnable)
+	r.watchDelay = config.GetDuration(resourceConfigWatchDelay)
 	// 加载指定路径的配置
-	files := config.GetStringSlice("includes")
-	logger.Infow("Resource discovery, load resources", "includes", files)
-	if err := r.includes(files); nil != err {
-		return err
-	}
+	r.includes = config.GetStringSlice(resourceConfigIncludes)
+	logger.Infow("Resource discovery, load resources", "includes", r.includes, "watch", r.watchEnable)
 	// 本地指定
-	define := map[string]interface{}{
-		"endpoints": config.GetOrDefault("endpoints", make([]interface{}, 0)),
-		"services":  config.GetOrDefault("services", make([]interface{}, 0)),
-	}
-	if bytes, err := ext.JSONMarshal(define); nil != err {
-		return fmt.Errorf("response discovery, redecode config, error: %w", err)
-	} else {
-		var out Resources
-		if err := yaml.Unmarshal(bytes, &out); nil != err {
+	if config.IsSet("endpoints") || config.IsSet("services") {
+		define := map[string]interface{}{
+			"endpoints": config.GetOrDefault("endpoints", make([]interface{}, 0)),
+			"services":  config.GetOrDefault("services", make([]interface{}, 0)),
+		}
+		if bytes, err := ext.JSONMarshal(define); nil != err {
+			return fmt.Errorf("response discovery, redecode config, error: %w", err)
+		} else if err := yaml.Unmarshal(bytes, &r.local); nil != err {
 			return fmt.Errorf("discovery service decode config, err: %w", err)
-		} else if len(out.Endpoints) > 0 || len(out.Services) > 0 {
-			r.resources = append(r.resources, out)
 		}
 	}
+	resources, err := r.load()
+	if nil != err {
+		return err
+	}
+	r.endpoints, r.services = indexResources(resources)
 	return nil
 }
 
 func (r *ResourceDiscoveryService) WatchEndpoints(ctx context.Context, events chan<- flux.EndpointEvent) error {
-	for _, res := range r.resources {
-		for _, ep := range res.Endpoints {
-			if ep.IsValid() {
-				EnsureServiceAttrs(&ep.Service)
-				events <- flux.EndpointEvent{EventType: flux.EventTypeAdded, Endpoint: ep}
-			}
+	r.mu.Lock()
+	r.endpointEvents = events
+	for _, key := range sortedKeys(r.endpoints) {
+		if !sendEndpointEvent(ctx, events, flux.EndpointEvent{EventType: flux.EventTypeAdded, Endpoint: r.endpoints[key]}) {
+			break
 		}
 	}
+	r.mu.Unlock()
+	r.startWatch(ctx)
 	return nil
 }
 
 func (r *ResourceDiscoveryService) WatchServices(ctx context.Context, events chan<- flux.ServiceEvent) error {
-	for _, res := range r.resources {
-		for _, srv := range res.Services {
-			if srv.IsValid() {
-				EnsureServiceAttrs(&srv)
-				events <- flux.ServiceEvent{EventType: flux.EventTypeAdded, Service: srv}
+	r.mu.Lock()
+	r.serviceEvents = events
+	for _, key := range sortedKeys(r.services) {
+		if !sendServiceEvent(ctx, events, flux.ServiceEvent{EventType: flux.EventTypeAdded, Service: r.services[key]}) {
+			break
+		}
+	}
+	r.mu.Unlock()
+	r.startWatch(ctx)
+	return nil
+}
+
+// Reload 重新加载资源文件，并发送变更事件；加载失败时保留当前生效的资源；
+func (r *ResourceDiscoveryService) Reload(ctx context.Context) error {
+	resources, err := r.load()
+	if nil != err {
+		return err
+	}
+	endpoints, services := indexResources(resources)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	srvEvents := DiffServices(r.services, services)
+	epEvents := DiffEndpoints(r.endpoints, endpoints)
+	r.endpoints, r.services = endpoints, services
+	logger.Infow("DISCOVERY:RESOURCE:RELOAD", "service-events", len(srvEvents), "endpoint-events", len(epEvents))
+	// 先发送Service事件，Endpoint可能引用变更的Service
+	if nil != r.serviceEvents {
+		for _, evt := range srvEvents {
+			if !sendServiceEvent(ctx, r.serviceEvents, evt) {
+				return nil
+			}
+		}
+	}
+	if nil != r.endpointEvents {
+		for _, evt := range epEvents {
+			if !sendEndpointEvent(ctx, r.endpointEvents, evt) {
+				return nil
 			}
 		}
 	}
 	return nil
 }
 
-func (r *ResourceDiscoveryService) includes(files []string) error {
-	for _, file := range files {
+func (r *ResourceDiscoveryService) startWatch(ctx context.Context) {
+	if !r.watchEnable || len(r.includes) == 0 {
+		return
+	}
+	r.watchOnce.Do(func() {
+		watcher, err := fsnotify.NewWatcher()
+		if nil != err {
+			logger.Errorw("DISCOVERY:RESOURCE:WATCH/ERROR", "error", err)
+			return
+		}
+		for _, dir := range r.watchDirs() {
+			if err := watcher.Add(dir); nil != err {
+				logger.Errorw("DISCOVERY:RESOURCE:WATCH/ERROR", "path", dir, "error", err)
+			} else {
+				logger.Infow("DISCOVERY:RESOURCE:WATCH", "path", dir)
+			}
+		}
+		go r.watch(ctx, watcher)
+	})
+}
+
+func (r *ResourceDiscoveryService) watch(ctx context.Context, watcher *fsnotify.Watcher) {
+	defer watcher.Close()
+	var reload <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Infow("DISCOVERY:RESOURCE:WATCH/CANCELED")
+			return
+		case evt, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if evt.Op != fsnotify.Chmod && r.isIncluded(evt.Name) {
+				reload = time.After(r.watchDelay)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			logger.Warnw("DISCOVERY:RESOURCE:WATCH/ERROR", "error", err)
+		case <-reload:
+			reload = nil
+			if err := r.Reload(ctx); nil != err {
+				logger.Errorw("DISCOVERY:RESOURCE:RELOAD/ERROR", "error", err)
+			}
+		}
+	}
+}
+
+// watchDirs 返回需要监听的目录；监听文件所在目录，以支持编辑器通过重命名替换文件的保存方式；
+func (r *ResourceDiscoveryService) watchDirs() []string {
+	dirs := make([]string, 0, len(r.includes))
+	seen := make(map[string]bool, len(r.includes))
+	for _, path := range r.includes {
+		dir := filepath.Clean(path)
+		if info, err := os.Stat(path); nil != err || !info.IsDir() {
+			dir = filepath.Dir(dir)
+		}
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+func (r *ResourceDiscoveryService) isIncluded(name string) bool {
+	name = filepath.Clean(name)
+	for _, path := range r.includes {
+		path = filepath.Clean(path)
+		if name == path {
+			return true
+		}
+		if filepath.Dir(name) == path && isResourceFile(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// load 加载全部资源文件和本地配置的资源；任一文件加载失败时返回错误；
+func (r *ResourceDiscoveryService) load() (Resources, error) {
+	out := Resources{
+		Endpoints: make([]flux.Endpoint, 0, 16),
+		Services:  make([]flux.TransporterService, 0, 16),
+	}
+	for _, file := range r.files() {
 		bytes, err := ioutil.ReadFile(file)
 		if nil != err {
-			return fmt.Errorf("discovery service read config, path: %s, err: %w", file, err)
+			return out, fmt.Errorf("discovery service read config, path: %s, err: %w", file, err)
 		}
-		var out Resources
-		if err := yaml.Unmarshal(bytes, &out); nil != err {
-			return fmt.Errorf("discovery service decode config, path: %s, err: %w", file, err)
-		} else {
-			r.resources = append(r.resources, out)
+		var res Resources
+		if err := yaml.Unmarshal(bytes, &res); nil != err {
+			return out, fmt.Errorf("discovery service decode config, path: %s, err: %w", file, err)
 		}
+		out.Endpoints = append(out.Endpoints, res.Endpoints...)
+		out.Services = append(out.Services, res.Services...)
 	}
-	return nil
+	out.Endpoints = append(out.Endpoints, r.local.Endpoints...)
+	out.Services = append(out.Services, r.local.Services...)
+	return out, nil
+}
+
+// files 返回资源文件列表；目录展开为目录下的YAML文件；
+func (r *ResourceDiscoveryService) files() []string {
+	files := make([]string, 0, len(r.includes))
+	for _, path := range r.includes {
+		info, err := os.Stat(path)
+		if nil != err || !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		entries, err := ioutil.ReadDir(path)
+		if nil != err {
+			files = append(files, path)
+			continue
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && isResourceFile(entry.Name()) {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+	return files
+}
+
+// DiffEndpoints 对比新旧Endpoint，返回变更事件
+func DiffEndpoints(olds, news map[string]flux.Endpoint) []flux.EndpointEvent {
+	events := make([]flux.EndpointEvent, 0)
+	for _, key := range sortedKeys(olds) {
+		if _, ok := news[key]; !ok {
+			events = append(events, flux.EndpointEvent{EventType: flux.EventTypeRemoved, Endpoint: olds[key]})
+		}
+	}
+	for _, key := range sortedKeys(news) {
+		if old, ok := olds[key]; !ok {
+			events = append(events, flux.EndpointEvent{EventType: flux.EventTypeAdded, Endpoint: news[key]})
+		} else if !reflect.DeepEqual(old, news[key]) {
+			events = append(events, flux.EndpointEvent{EventType: flux.EventTypeUpdated, Endpoint: news[key]})
+		}
+	}
+	return events
+}
+
+// DiffServices 对比新旧Service，返回变更事件
+func DiffServices(olds, news map[string]flux.TransporterService) []flux.ServiceEvent {
+	events := make([]flux.ServiceEvent, 0)
+	for _, key := range sortedKeys(olds) {
+		if _, ok := news[key]; !ok {
+			events = append(events, flux.ServiceEvent{EventType: flux.EventTypeRemoved, Service: olds[key]})
+		}
+	}
+	for _, key := range sortedKeys(news) {
+		if old, ok := olds[key]; !ok {
+			events = append(events, flux.ServiceEvent{EventType: flux.EventTypeAdded, Service: news[key]})
+		} else if !reflect.DeepEqual(old, news[key]) {
+			events = append(events, flux.ServiceEvent{EventType: flux.EventTypeUpdated, Service: news[key]})
+		}
+	}
+	return events
+}
+
+// indexResources 按Endpoint的 Method#Pattern#Version 和Service的ID索引有效资源
+func indexResources(res Resources) (map[string]flux.Endpoint, map[string]flux.TransporterService) {
+	endpoints := make(map[string]flux.Endpoint, len(res.Endpoints))
+	for _, ep := range res.Endpoints {
+		if ep.IsValid() {
+			EnsureServiceAttrs(&ep.Service)
+			endpoints[EndpointKey(ep)] = ep
+		}
+	}
+	services := make(map[string]flux.TransporterService, len(res.Services))
+	for _, srv := range res.Services {
+		if srv.IsValid() {
+			EnsureServiceAttrs(&srv)
+			services[srv.ServiceID()] = srv
+		}
+	}
+	return endpoints, services
+}
+
+// EndpointKey 返回标识Endpoint的Key：Method#Pattern#Version
+func EndpointKey(ep flux.Endpoint) string {
+	return strings.ToUpper(ep.HttpMethod) + "#" + ep.HttpPattern + "#" + ep.Version
+}
+
+func sendEndpointEvent(ctx context.Context, events chan<- flux.EndpointEvent, evt flux.EndpointEvent) bool {
+	select {
+	case events <- evt:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func sendServiceEvent(ctx context.Context, events chan<- flux.ServiceEvent, evt flux.ServiceEvent) bool {
+	select {
+	case events <- evt:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func sortedKeys(m interface{}) []string {
+	keys := reflect.ValueOf(m).MapKeys()
+	out := make([]string, len(keys))
+	for i, key := range keys {
+		out[i] = key.String()
+	}
+	sort.Strings(out)
+	return out
+}
+
+func isResourceFile(name string) bool {
+	suffix := strings.ToLower(filepath.Ext(name))
+	return suffix == ".yml" || suffix == ".yaml"
 }
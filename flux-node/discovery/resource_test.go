@@ -0,0 +1,122 @@
+package discovery
+
+import (
+	"context"
+	"github.com/bytepowered/flux/flux-node"
+	assert2 "github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const resourceV1 = `
+endpoints:
+    -   version: "1.0"
+        httpPattern: "/api/a"
+        httpMethod: "GET"
+        service: { interface: "foo", method: "a" }
+    -   version: "1.0"
+        httpPattern: "/api/b"
+        httpMethod: "GET"
+        service: { interface: "foo", method: "b" }
+services:
+    -   interface: "foo"
+        method: "a"
+`
+
+const resourceV2 = `
+endpoints:
+    -   version: "1.0"
+        httpPattern: "/api/a"
+        httpMethod: "GET"
+        service: { interface: "foo", method: "a2" }
+    -   version: "1.0"
+        httpPattern: "/api/c"
+        httpMethod: "POST"
+        service: { interface: "foo", method: "c" }
+services:
+    -   interface: "foo"
+        method: "a"
+`
+
+func newResourceDiscovery(t *testing.T, dir string) *ResourceDiscoveryService {
+	r := NewResourceServiceWith("test")
+	config := flux.NewConfigurationOfMap(map[string]interface{}{
+		"includes":    []string{dir},
+		"watch_delay": "20ms",
+	})
+	assert2.NoError(t, r.Init(config))
+	return r
+}
+
+func TestResourceDiscovery_Reload(t *testing.T) {
+	assert := assert2.New(t)
+	dir, err := ioutil.TempDir("", "flux-resource")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "api.yml")
+	assert.NoError(ioutil.WriteFile(file, []byte(resourceV1), 0644))
+	r := newResourceDiscovery(t, dir)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	endpoints := make(chan flux.EndpointEvent, 16)
+	services := make(chan flux.ServiceEvent, 16)
+	r.watchEnable = false
+	assert.NoError(r.WatchEndpoints(ctx, endpoints))
+	assert.NoError(r.WatchServices(ctx, services))
+	assert.Equal(2, len(endpoints))
+	assert.Equal(1, len(services))
+	<-endpoints
+	<-endpoints
+	<-services
+	// changed
+	assert.NoError(ioutil.WriteFile(file, []byte(resourceV2), 0644))
+	assert.NoError(r.Reload(ctx))
+	assert.Equal(0, len(services))
+	events := make(map[string]flux.EventType)
+	for len(endpoints) > 0 {
+		evt := <-endpoints
+		events[evt.Endpoint.HttpPattern] = evt.EventType
+	}
+	assert.Equal(map[string]flux.EventType{
+		"/api/a": flux.EventTypeUpdated,
+		"/api/b": flux.EventTypeRemoved,
+		"/api/c": flux.EventTypeAdded,
+	}, events)
+	// invalid: keep previous state
+	assert.NoError(ioutil.WriteFile(file, []byte("endpoints: [ {"), 0644))
+	assert.Error(r.Reload(ctx))
+	assert.Equal(0, len(endpoints))
+	assert.Equal(2, len(r.endpoints))
+}
+
+func TestResourceDiscovery_Watch(t *testing.T) {
+	assert := assert2.New(t)
+	dir, err := ioutil.TempDir("", "flux-resource")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "api.yml")
+	assert.NoError(ioutil.WriteFile(file, []byte(resourceV1), 0644))
+	r := newResourceDiscovery(t, dir)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	endpoints := make(chan flux.EndpointEvent, 16)
+	assert.NoError(r.WatchEndpoints(ctx, endpoints))
+	<-endpoints
+	<-endpoints
+	assert.NoError(ioutil.WriteFile(filepath.Join(dir, "more.yaml"), []byte(`
+endpoints:
+    -   httpPattern: "/api/d"
+        httpMethod: "GET"
+        service: { interface: "foo", method: "d" }
+`), 0644))
+	select {
+	case evt := <-endpoints:
+		assert.Equal(flux.EventType(flux.EventTypeAdded), evt.EventType)
+		assert.Equal("/api/d", evt.Endpoint.HttpPattern)
+	case <-time.After(3 * time.Second):
+		t.Fatal("watch event timeout")
+	}
+}
@@ -1,6 +1,7 @@
 package flux
 
 import (
+	"errors"
 	"fmt"
 	"github.com/spf13/cast"
 	"net/http"
@@ -24,7 +25,8 @@ const (
 )
 
 const (
-	ErrorMessageProtocolUnknown = "GATEWAY:PROTOCOL:UNKNOWN"
+	ErrorMessageProtocolUnknown  = "GATEWAY:PROTOCOL:UNKNOWN"
+	ErrorMessageArgumentsResolve = "GATEWAY:ARGUMENTS:RESOLVE"
 
 	ErrorMessageTransportDecodeResponse = "TRANSPORT:DECODE_RESPONSE"
 	ErrorMessageTransportWriteResponse  = "TRANSPORT:WRITE_RESPONSE"
@@ -37,13 +39,39 @@ const (
 	ErrorMessageHttpInvokeFailed   = "TRANSPORT:HT:INVOKE"
 	ErrorMessageHttpAssembleFailed = "TRANSPORT:HT:ASSEMBLE"
 
+	ErrorMessageGrpcInvokeFailed      = "TRANSPORT:GR:INVOKE"
+	ErrorMessageGrpcAssembleFailed    = "TRANSPORT:GR:ASSEMBLE"
+	ErrorMessageGrpcDescriptorMissing = "TRANSPORT:GR:DESCRIPTOR_MISSING"
+
+	ErrorMessageInProcFuncNotFound   = "TRANSPORT:IN:FUNC_NOT_FOUND"
+	ErrorMessageInProcInvokeFailed   = "TRANSPORT:IN:INVOKE"
+	ErrorMessageInProcAssembleFailed = "TRANSPORT:IN:ASSEMBLE"
+
+	ErrorMessageScriptLoadFailed     = "TRANSPORT:SC:LOAD"
+	ErrorMessageScriptInvokeFailed   = "TRANSPORT:SC:INVOKE"
+	ErrorMessageScriptInvokeTimeout  = "TRANSPORT:SC:TIMEOUT"
+	ErrorMessageScriptAssembleFailed = "TRANSPORT:SC:ASSEMBLE"
+
+	ErrorMessageAggregateInvalid          = "TRANSPORT:AG:INVALID"
+	ErrorMessageAggregateServiceNotFound  = "TRANSPORT:AG:SERVICE_NOT_FOUND"
+	ErrorMessageAggregateCallFailed       = "TRANSPORT:AG:CALL_FAILED"
+	ErrorMessageAggregateDependencyFailed = "TRANSPORT:AG:DEPENDENCY_FAILED"
+
 	ErrorMessagePermissionAccessDenied    = "PERMISSION:ACCESS_DENIED"
 	ErrorMessagePermissionServiceNotFound = "PERMISSION:SERVICE:NOT_FOUND"
 	ErrorMessagePermissionVerifyError     = "PERMISSION:VERIFY:ERROR"
 
 	ErrorMessageWebServerRequestNotFound = "SERVER:REQUEST:NOT_FOUND"
 
-	ErrorMessageRequestPrepare = "REQUEST:BODY:PREPARE"
+	ErrorMessageRequestPrepare    = "REQUEST:BODY:PREPARE"
+	ErrorMessageRequestTooLarge   = "REQUEST:BODY:TOO_LARGE"
+	ErrorMessageRequestUploadType = "REQUEST:UPLOAD:TYPE_NOT_ALLOWED"
+	ErrorMessageRequestValidation = "REQUEST:ARGUMENTS:INVALID"
+)
+
+var (
+	// ErrRequestBodyTooLarge 请求体超出大小限制
+	ErrRequestBodyTooLarge = errors.New("request body too large")
 )
 
 // ServeError 定义网关处理请求的服务错误；
@@ -55,6 +83,7 @@ type ServeError struct {
 	CauseError error                  // 内部错误对象；错误对象不会被输出到请求端；
 	Header     http.Header            // 响应Header
 	Extras     map[string]interface{} // 用于定义和跟踪的额外信息；额外信息不会被输出到请求端；
+	Details    interface{}            `json:",omitempty"` // 错误详情，例如参数校验失败的字段列表；详情会被输出到请求端；
 }
 
 func (e *ServeError) Error() string {
@@ -92,3 +121,21 @@ func (e *ServeError) Merge(header http.Header) *ServeError {
 	}
 	return e
 }
+
+// NewRequestBodyError 返回读取请求体失败的ServeError；请求体超出大小限制时返回413状态码，否则返回400状态码；
+func NewRequestBodyError(err error) *ServeError {
+	if errors.Is(err, ErrRequestBodyTooLarge) {
+		return &ServeError{
+			StatusCode: StatusTooLarge,
+			ErrorCode:  ErrorCodeRequestInvalid,
+			Message:    ErrorMessageRequestTooLarge,
+			CauseError: err,
+		}
+	}
+	return &ServeError{
+		StatusCode: StatusBadRequest,
+		ErrorCode:  ErrorCodeRequestInvalid,
+		Message:    ErrorMessageRequestPrepare,
+		CauseError: err,
+	}
+}
@@ -0,0 +1,32 @@
+package ext
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-pkg"
+	"sync"
+)
+
+var (
+	inprocFuncs = new(sync.Map)
+)
+
+// RegisterInProcFunc 注册进程内服务函数，函数按TransporterService的Interface和Method绑定
+func RegisterInProcFunc(iface, method string, fun flux.InProcFunc) {
+	iface = fluxpkg.MustNotEmpty(iface, "InProcFunc interface is empty")
+	method = fluxpkg.MustNotEmpty(method, "InProcFunc method is empty")
+	inprocFuncs.Store(iface+":"+method, fluxpkg.MustNotNil(fun, "InProcFunc is nil").(flux.InProcFunc))
+}
+
+// RemoveInProcFunc 删除进程内服务函数
+func RemoveInProcFunc(iface, method string) {
+	inprocFuncs.Delete(iface + ":" + method)
+}
+
+// InProcFuncBy 查找Interface和Method绑定的进程内服务函数
+func InProcFuncBy(iface, method string) (flux.InProcFunc, bool) {
+	v, ok := inprocFuncs.Load(iface + ":" + method)
+	if !ok {
+		return nil, false
+	}
+	return v.(flux.InProcFunc), true
+}
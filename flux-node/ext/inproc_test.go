@@ -0,0 +1,24 @@
+package ext
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	assert2 "github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestInProcFuncRegistry(t *testing.T) {
+	assert := assert2.New(t)
+	RegisterInProcFunc("health", "ping", func(ctx *flux.Context, arguments map[string]interface{}) (*flux.ResponseBody, error) {
+		return &flux.ResponseBody{StatusCode: flux.StatusOK, Body: "pong"}, nil
+	})
+	fun, ok := InProcFuncBy("health", "ping")
+	assert.True(ok)
+	resp, err := fun(nil, nil)
+	assert.NoError(err)
+	assert.Equal("pong", resp.Body)
+	_, ok = InProcFuncBy("health", "pong")
+	assert.False(ok)
+	RemoveInProcFunc("health", "ping")
+	_, ok = InProcFuncBy("health", "ping")
+	assert.False(ok)
+}
@@ -9,6 +9,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"sync"
 )
 
 var _ flux.ServerWebContext = new(EchoWebContext)
@@ -27,6 +28,8 @@ type EchoWebContext struct {
 	context   context.Context
 	echoc     echo.Context
 	variables map[interface{}]interface{}
+	// 聚合调用等场景下，同一请求的Variable会被多个协程并发访问
+	varmu sync.RWMutex
 }
 
 func (w *EchoWebContext) WebListener() flux.WebListener {
@@ -157,12 +160,29 @@ func (w *EchoWebContext) Variable(key string) interface{} {
 }
 
 func (w *EchoWebContext) SetVariable(key string, value interface{}) {
+	w.varmu.Lock()
 	w.variables[key] = value
+	w.varmu.Unlock()
+}
+
+func (w *EchoWebContext) LoadOrStoreVariable(key string, value interface{}) (interface{}, bool) {
+	w.varmu.Lock()
+	defer w.varmu.Unlock()
+	if v, ok := w.variables[key]; ok {
+		return v, true
+	}
+	if v := w.echoc.Get(key); nil != v {
+		return v, true
+	}
+	w.variables[key] = value
+	return value, false
 }
 
 func (w *EchoWebContext) GetVariable(key string) (interface{}, bool) {
 	// 本地Variable
+	w.varmu.RLock()
 	v, ok := w.variables[key]
+	w.varmu.RUnlock()
 	if ok {
 		return v, true
 	}
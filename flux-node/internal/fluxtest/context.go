@@ -0,0 +1,38 @@
+package fluxtest
+
+import (
+	"bytes"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/internal"
+	"github.com/labstack/echo/v4"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+)
+
+var mock = echo.New()
+
+// NewContext 使用Http请求构建测试用的Context，响应写入到Recorder中
+func NewContext(request *http.Request) *flux.Context {
+	return NewContextWith(httptest.NewRecorder(), request, &flux.Endpoint{})
+}
+
+// NewContextWith 使用指定的ResponseWriter和Endpoint构建测试用的Context；
+// 与网关Server一致，请求Body被缓存为可重复读取的GetBody函数；
+func NewContextWith(writer http.ResponseWriter, request *http.Request, endpoint *flux.Endpoint) *flux.Context {
+	if nil == request.GetBody {
+		body := make([]byte, 0)
+		if nil != request.Body {
+			body, _ = ioutil.ReadAll(request.Body)
+		}
+		request.Body = ioutil.NopCloser(bytes.NewReader(body))
+		request.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+	webex := internal.NewServeWebContext(mock.NewContext(request, writer), "flux-test", nil)
+	ctx := flux.NewContext()
+	ctx.Reset(webex, endpoint)
+	return ctx
+}
@@ -79,8 +79,18 @@ const (
 	StatusServerError  = http.StatusInternalServerError
 	StatusBadGateway   = http.StatusBadGateway
 	StatusNoContent    = http.StatusNoContent
+	StatusTooLarge     = http.StatusRequestEntityTooLarge
 )
 
+// WebBodyLimiter 可限制读取大小的请求体；WebListener缓存的请求体实现此接口，用于按Endpoint设置请求体大小限制；
+// 读取超出限制的请求体时，返回 ErrRequestBodyTooLarge 错误；
+type WebBodyLimiter interface {
+	// SetBodyLimit 设置请求体的最大字节数，覆盖WebListener配置的默认限制；小于等于0表示不限制；
+	SetBodyLimit(limit int64)
+	// Exceeded 判断请求声明的Content-Length是否超出当前限制
+	Exceeded() bool
+}
+
 // Web interfaces defines
 type (
 	// WebHandler 定义处理Web请求的处理函数
@@ -188,6 +198,9 @@ type ServerWebContext interface {
 	// SetVariable 设置Context域键值；作用域与请求生命周期相同；
 	GetVariable(key string) (interface{}, bool)
 
+	// LoadOrStoreVariable 原子地加载或设置Context域键值：键值已存在时返回已有值和true；否则设置为value并返回value和false；
+	LoadOrStoreVariable(key string, value interface{}) (actual interface{}, loaded bool)
+
 	// WebListener 返回当前请求所属的WebListener
 	WebListener() WebListener
 }
@@ -3,9 +3,13 @@ package main
 import (
 	"github.com/bytepowered/flux/flux-node"
 	"github.com/bytepowered/flux/flux-node/server"
+	_ "github.com/bytepowered/flux/flux-node/transporter/aggregate"
 	_ "github.com/bytepowered/flux/flux-node/transporter/dubbo"
 	_ "github.com/bytepowered/flux/flux-node/transporter/echo"
+	_ "github.com/bytepowered/flux/flux-node/transporter/grpc"
 	_ "github.com/bytepowered/flux/flux-node/transporter/http"
+	_ "github.com/bytepowered/flux/flux-node/transporter/inproc"
+	_ "github.com/bytepowered/flux/flux-node/transporter/script"
 	_ "github.com/bytepowered/flux/flux-node/webecho"
 )
 
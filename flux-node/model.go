@@ -39,12 +39,24 @@ const (
 	ScopeHeader = "HEADER"
 	// 获取Header全部参数
 	ScopeHeaderMap = "HEADER_MAP"
+	// 从Cookie中读取
+	ScopeCookie = "COOKIE"
+	// 获取全部Cookie参数
+	ScopeCookieMap = "COOKIE_MAP"
+	// 获取multipart请求的上传文件；FILE_MUL 获取同名的全部文件
+	ScopeFile      = "FILE"
+	ScopeFileMulti = "FILE_MUL"
+	// 获取JWT Claims的单个参数
+	ScopeClaim = "CLAIM"
 	// 获取Http Attributes的单个参数
 	ScopeAttr = "ATTR"
 	// 获取Http Attributes的Map结果
 	ScopeAttrs = "ATTRS"
 	// 获取Body数据
 	ScopeBody = "BODY"
+	// 使用JSONPath表达式，从JSON格式的Body中获取数据
+	ScopeBodyPath = "BODY_PATH"
+	ScopeJSON     = "JSON"
 	// 获取Request元数据
 	ScopeRequest = "REQUEST"
 	// 自动查找数据源
@@ -60,10 +72,13 @@ const (
 
 // Support protocols
 const (
-	ProtoDubbo = "DUBBO"
-	ProtoGRPC  = "GRPC"
-	ProtoHttp  = "HTTP"
-	ProtoEcho  = "ECHO"
+	ProtoDubbo     = "DUBBO"
+	ProtoGRPC      = "GRPC"
+	ProtoHttp      = "HTTP"
+	ProtoEcho      = "ECHO"
+	ProtoInProc    = "INPROC"
+	ProtoScript    = "SCRIPT"
+	ProtoAggregate = "AGGREGATE"
 )
 
 // ServiceAttributes
@@ -82,11 +97,36 @@ const (
 	EndpointAttrTagAuthorize  = "authorize"  // 标识Endpoint访问是否需要授权
 	EndpointAttrTagListenerId = "listenerid" // 标识Endpoint绑定到哪个ListenServer服务
 	EndpointAttrTagBizId      = "bizid"      // 标识Endpoint绑定到业务标识
+	// 上传文件的限制：单个文件的最大大小，例如 10MB；允许的文件类型，支持 image/* 通配
+	EndpointAttrTagUploadMaxSize      = "upload_max_size"
+	EndpointAttrTagUploadAllowedTypes = "upload_allowed_types"
+	// 请求体的最大大小，例如 20MB；覆盖WebListener的全局 body_limit 设置
+	EndpointAttrTagBodyMaxSize = "body_max_size"
 )
 
 // ArgumentAttributes
 const (
 	ArgumentAttributeTagDefault = "default" // 参数的默认值属性
+	ArgumentAttributeTagEnum    = "enum"    // 标识参数类型为Java枚举，参数值以枚举名称传递
+	ArgumentAttributeTagExpr    = "expr"    // 参数值表达式，例如：${header.X-Uid ?? 'anonymous'}
+	// 复杂参数是否按字段结构从JSON请求体绑定；需要值域为BODY，默认不开启
+	ArgumentAttributeTagJSONBinding = "json_binding"
+	// 从请求体绑定复杂参数时，绑定子树的JSONPath路径
+	ArgumentAttributeTagBindPath = "bind_path"
+	// 从请求体绑定复杂参数时，是否保留未声明的字段；未定义时使用全局配置
+	ArgumentAttributeTagKeepUnknown = "keep_unknown"
+)
+
+// ArgumentAttributes: 参数校验规则
+const (
+	ArgumentAttributeTagRequired   = "required"    // 参数值必须存在并且非空
+	ArgumentAttributeTagMin        = "min"         // 数值的最小值
+	ArgumentAttributeTagMax        = "max"         // 数值的最大值
+	ArgumentAttributeTagMinLength  = "min_length"  // 字符串或者集合的最小长度
+	ArgumentAttributeTagMaxLength  = "max_length"  // 字符串或者集合的最大长度
+	ArgumentAttributeTagPattern    = "pattern"     // 字符串必须匹配的正则表达式
+	ArgumentAttributeTagEnumValues = "enum_values" // 参数值必须为列表中的值之一
+	ArgumentAttributeTagFormat     = "format"      // 字符串格式：email, mobile, url, ipv4, uuid, digits
 )
 
 type (
@@ -140,6 +180,10 @@ func (a Attribute) GetInt() int {
 	return cast.ToInt(a.Value)
 }
 
+func (a Attribute) GetFloat64() float64 {
+	return cast.ToFloat64(a.Value)
+}
+
 func (a Attribute) GetBool() bool {
 	return cast.ToBool(a.Value)
 }
@@ -11,6 +11,17 @@ const (
 	JavaUtilListClassName    = "java.util.List"
 )
 
+const (
+	JavaUtilDateClassName          = "java.util.Date"
+	JavaMathBigDecimalClassName    = "java.math.BigDecimal"
+	JavaMathBigIntegerClassName    = "java.math.BigInteger"
+	JavaTimeLocalDateClassName     = "java.time.LocalDate"
+	JavaTimeLocalTimeClassName     = "java.time.LocalTime"
+	JavaTimeLocalDateTimeClassName = "java.time.LocalDateTime"
+	JavaLangEnumClassName          = "java.lang.Enum"
+	JavaIOInputStreamClassName     = "java.io.InputStream"
+)
+
 const (
 	ValueMediaTypeGoObject          = "go:object"
 	ValueMediaTypeGoString          = "go:string"
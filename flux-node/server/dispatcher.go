@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/common"
 	"github.com/bytepowered/flux/flux-node/ext"
 	"github.com/bytepowered/flux/flux-node/logger"
 	"github.com/prometheus/client_golang/prometheus"
@@ -157,6 +158,13 @@ func (r *Dispatcher) Route(ctx *flux.Context) *flux.ServeError {
 				Message:    fmt.Sprintf("ROUTE:UNKNOWN_PROTOCOL:%s", proto),
 			}
 		}
+		// Arguments validation: 校验通过的参数值绑定到请求的服务定义，传输时不再重复解析
+		arguments, serr := common.ResolveArguments(ctx, ctx.Transporter().Arguments)
+		if nil != serr {
+			logger.TraceContext(ctx).Infow("SERVER:ROUTE:ARGUMENTS_INVALID", "fields", serr.Details)
+			return serr
+		}
+		ctx.Endpoint().Service.Arguments = arguments
 		// Transporter exchange
 		timer := prometheus.NewTimer(r.metrics.RouteDuration.WithLabelValues("Transporter", proto))
 		transporter.Transport(ctx)
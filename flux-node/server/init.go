@@ -21,4 +21,6 @@ func init() {
 	// Endpoint discovery
 	ext.RegisterEndpointDiscovery(discovery.NewZookeeperServiceWith(discovery.ZookeeperId))
 	ext.RegisterEndpointDiscovery(discovery.NewResourceServiceWith(discovery.ResourceId))
+	ext.RegisterEndpointDiscovery(discovery.NewHttpServiceWith(discovery.HttpId))
+	ext.RegisterEndpointDiscovery(discovery.NewConsulServiceWith(discovery.ConsulId))
 }
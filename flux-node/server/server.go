@@ -6,10 +6,12 @@ import (
 	dubgo "github.com/apache/dubbo-go/config"
 	"github.com/bytepowered/flux/flux-inspect"
 	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/common"
 	"github.com/bytepowered/flux/flux-node/ext"
 	"github.com/bytepowered/flux/flux-node/listener"
 	"github.com/bytepowered/flux/flux-node/logger"
 	"github.com/bytepowered/flux/flux-pkg"
+	"github.com/labstack/gommon/bytes"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"golang.org/x/net/context"
 	"net/http"
@@ -103,6 +105,7 @@ func NewDefaultBootstrapServer(options ...Option) *BootstrapServer {
 				// Http Inspect
 				{Method: "GET", Pattern: "/inspect/endpoints", Handler: fluxinspect.EndpointsHandler},
 				{Method: "GET", Pattern: "/inspect/services", Handler: fluxinspect.ServicesHandler},
+				{Method: "GET", Pattern: "/inspect/upstreams", Handler: fluxinspect.UpstreamsHandler},
 				// Metrics
 				{Method: "GET", Pattern: "/inspect/metrics", Handler: flux.WrapHttpHandler(promhttp.Handler())},
 			}),
@@ -133,6 +136,15 @@ func (s *BootstrapServer) Prepare() error {
 
 // Initial
 func (s *BootstrapServer) Initial() error {
+	// Value resolvers
+	resolvers := flux.NewConfigurationOfNS(flux.NamespaceValueResolvers)
+	if err := common.ConfigureJavaTime(resolvers); nil != err {
+		return err
+	}
+	common.ConfigureJSONBinding(resolvers)
+	if err := common.ConfigureMultipart(resolvers); nil != err {
+		return err
+	}
 	// Listen Server
 	for id, webListener := range s.listener {
 		if err := webListener.Init(LoadWebListenerConfig(id)); nil != err {
@@ -266,8 +278,14 @@ func (s *BootstrapServer) route(webex flux.ServerWebContext, server flux.WebList
 		hook(webex, ctxw)
 	}
 	defer func(start time.Time) {
+		common.ReleaseMultipartForm(webex)
 		trace.Infow("SERVER:ROUTE:END", "metric", ctxw.Metrics(), "elapses", time.Since(start).String())
 	}(ctxw.StartAt())
+	// 按Endpoint限制请求体大小
+	if serr := applyBodyLimit(webex, &endpoint); nil != serr {
+		server.HandleError(webex, serr)
+		return nil
+	}
 	// route
 	if serr := s.dispatcher.Route(ctxw); nil != serr {
 		server.HandleError(webex, serr)
@@ -277,7 +295,13 @@ func (s *BootstrapServer) route(webex flux.ServerWebContext, server flux.WebList
 
 func (s *BootstrapServer) onServiceEvent(event flux.ServiceEvent) {
 	service := event.Service
-	initArguments(service.Arguments)
+	if flux.EventTypeRemoved != event.EventType {
+		if err := initService(service); nil != err {
+			logger.Errorw("SERVER:EVENT:SERVICE:INVALID",
+				"service-id", service.ServiceId, "alias-id", service.AliasId, "error", err)
+			return
+		}
+	}
 	switch event.EventType {
 	case flux.EventTypeAdded:
 		logger.Infow("SERVER:EVENT:SERVICE:ADD",
@@ -301,6 +325,11 @@ func (s *BootstrapServer) onServiceEvent(event flux.ServiceEvent) {
 			ext.RemoveTransporterService(service.AliasId)
 		}
 	}
+	for _, transporter := range ext.Transporters() {
+		if listener, ok := transporter.(flux.ServiceEventListener); ok {
+			listener.OnServiceEvent(event)
+		}
+	}
 }
 
 func (s *BootstrapServer) onEndpointEvent(event flux.EndpointEvent) {
@@ -313,8 +342,12 @@ func (s *BootstrapServer) onEndpointEvent(event flux.EndpointEvent) {
 	pattern := event.Endpoint.HttpPattern
 	routeKey := fmt.Sprintf("%s#%s", method, pattern)
 	endpoint := event.Endpoint
-	initArguments(endpoint.Service.Arguments)
-	initArguments(endpoint.Permission.Arguments)
+	if flux.EventTypeRemoved != event.EventType {
+		if err := initEndpoint(&endpoint); nil != err {
+			logger.Errorw("SERVER:EVENT:ENDPOINT:INVALID", "method", method, "pattern", pattern, "error", err)
+			return
+		}
+	}
 	bind, isreg := s.selectMultiEndpoint(routeKey, &endpoint)
 	switch event.EventType {
 	case flux.EventTypeAdded:
@@ -465,10 +498,79 @@ func isAllowedHttpMethod(method string) bool {
 	}
 }
 
-func initArguments(args []flux.Argument) {
+// initEndpoint 初始化Endpoint的服务和权限服务参数，并校验服务定义
+func initEndpoint(endpoint *flux.Endpoint) error {
+	if err := initService(endpoint.Service); nil != err {
+		return err
+	}
+	return initArguments(endpoint.Permission.Arguments)
+}
+
+// initService 初始化服务参数，并校验服务定义；参数表达式错误或者校验失败的服务定义被拒绝加载
+func initService(service flux.TransporterService) error {
+	if err := initArguments(service.Arguments); nil != err {
+		return err
+	}
+	return validateService(service)
+}
+
+// validateService 使用服务协议对应的Transporter校验服务定义
+func validateService(service flux.TransporterService) error {
+	proto := service.RpcProto()
+	if "" == proto {
+		return nil
+	}
+	if transporter, ok := ext.TransporterBy(proto); ok {
+		if validator, ok := transporter.(flux.ServiceValidator); ok {
+			return validator.ValidateService(service)
+		}
+	}
+	return nil
+}
+
+func initArguments(args []flux.Argument) error {
 	for i := range args {
-		args[i].ValueResolver = ext.MTValueResolverByType(args[i].Class)
+		if err := initArguments(args[i].Fields); nil != err {
+			return err
+		}
+		if common.IsJSONBindingArgument(args[i]) {
+			args[i].ValueResolver = common.NewJSONBindingResolver(args[i])
+		} else if args[i].GetAttr(flux.ArgumentAttributeTagEnum).GetBool() {
+			args[i].ValueResolver = ext.MTValueResolverByType(flux.JavaLangEnumClassName)
+		} else {
+			args[i].ValueResolver = ext.MTValueResolverByType(args[i].Class)
+		}
 		args[i].LookupFunc = ext.ArgumentLookupFunc()
-		initArguments(args[i].Fields)
+		// 参数值表达式：编译一次，在每个请求中计算
+		if attr, ok := args[i].GetAttrEx(flux.ArgumentAttributeTagExpr); ok && len(args[i].Fields) == 0 {
+			expr, err := common.CompileValueExpr(attr.GetString())
+			if nil != err {
+				return fmt.Errorf("invalid argument expr, argument: %s, error: %w", args[i].Name, err)
+			}
+			args[i].LookupFunc = common.NewExprLookupFunc(expr)
+		}
+	}
+	return nil
+}
+
+// applyBodyLimit 按Endpoint的 body_max_size 属性设置请求体大小限制，覆盖WebListener配置的默认限制；
+// 请求声明的Content-Length超出生效的限制时，返回413错误；
+func applyBodyLimit(webex flux.ServerWebContext, endpoint *flux.Endpoint) *flux.ServeError {
+	request := webex.Request()
+	limiter, isLimiter := request.Body.(flux.WebBodyLimiter)
+	var limit int64 = 0
+	if attr, ok := endpoint.GetAttrEx(flux.EndpointAttrTagBodyMaxSize); ok {
+		if size, err := bytes.Parse(attr.GetString()); nil != err {
+			logger.Trace(webex.RequestId()).Warnw("SERVER:ROUTE:BODY_LIMIT_INVALID", "body-max-size", attr.GetString(), "error", err)
+		} else if isLimiter {
+			limiter.SetBodyLimit(size)
+		} else {
+			limit = size
+		}
 	}
+	if (isLimiter && limiter.Exceeded()) || (limit > 0 && request.ContentLength > limit) {
+		return flux.NewRequestBodyError(fmt.Errorf("request body too large, size: %d, error: %w",
+			request.ContentLength, flux.ErrRequestBodyTooLarge))
+	}
+	return nil
 }
@@ -17,6 +17,14 @@ type (
 		// Writer
 		Writer() TransportWriter
 	}
+	// ServiceEventListener 可选实现接口：Transporter实现此接口时，接收后端服务定义的变更事件
+	ServiceEventListener interface {
+		OnServiceEvent(event ServiceEvent)
+	}
+	// ServiceValidator 可选实现接口：Transporter实现此接口时，在加载服务定义时校验服务配置，校验失败的服务定义被拒绝加载
+	ServiceValidator interface {
+		ValidateService(service TransporterService) error
+	}
 	// TransportCodec 解析 Transporter 返回的原始数据，生成响应对象
 	TransportCodec func(ctx *Context, packet interface{}) (*ResponseBody, error)
 	// TransportWriter
@@ -24,6 +32,8 @@ type (
 		Write(ctx *Context, response *ResponseBody)
 		WriteError(ctx *Context, err *ServeError)
 	}
+	// InProcFunc 在网关进程内执行的服务函数：接收已解析的参数值(以参数名为Key)和请求Context，返回响应数据
+	InProcFunc func(ctx *Context, arguments map[string]interface{}) (*ResponseBody, error)
 	// ResponseBody 后端服务返回统一响应数据结构
 	ResponseBody struct {
 		StatusCode  int                    // Http状态码
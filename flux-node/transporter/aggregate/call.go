@@ -0,0 +1,207 @@
+package aggregate
+
+import (
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/spf13/cast"
+	"strings"
+	"time"
+)
+
+// 聚合服务的属性
+const (
+	ServiceAttrTagAggregate        = "aggregate"         // 聚合调用的子服务定义，可重复定义
+	ServiceAttrTagAggregatePolicy  = "aggregate_policy"  // 子服务调用失败的处理策略
+	ServiceAttrTagAggregateTimeout = "aggregate_timeout" // 子服务调用的默认超时时间
+)
+
+// 子服务调用失败的处理策略
+const (
+	PolicyFailFast = "fail_fast" // 任意子服务调用失败，取消其它调用并返回错误
+	PolicyPartial  = "partial"   // 返回成功的部分结果，以及失败子服务的错误信息
+)
+
+// Call 定义聚合服务中的一个子服务调用
+type Call struct {
+	Key       string            // 结果Key，同时用于依赖和参数绑定的引用
+	ServiceId string            // 子服务的ServiceId
+	Depends   []string          // 依赖的其它子服务调用
+	Binds     map[string]string // 参数绑定：参数名 -> 结果路径(key.field.field)
+	Timeout   time.Duration     // 调用超时时间
+	Required  bool              // 在partial策略下，调用失败时整个请求失败
+}
+
+// Plan 聚合服务的调用计划
+type Plan struct {
+	Policy string
+	Calls  []Call
+}
+
+// ParsePlan 从服务属性中解析聚合调用计划，并校验子服务依赖关系；
+// aggregate 属性值为子服务ServiceId，或者包含 key/service/depends/bind/timeout/required 的配置对象；
+func ParsePlan(service flux.TransporterService) (Plan, error) {
+	plan := Plan{Policy: PolicyFailFast}
+	if attr, ok := service.GetAttrEx(ServiceAttrTagAggregatePolicy); ok {
+		plan.Policy = strings.ToLower(attr.GetString())
+	}
+	if PolicyFailFast != plan.Policy && PolicyPartial != plan.Policy {
+		return plan, fmt.Errorf("unknown aggregate policy: %s", plan.Policy)
+	}
+	var timeout time.Duration
+	if attr, ok := service.GetAttrEx(ServiceAttrTagAggregateTimeout); ok {
+		timeout = cast.ToDuration(attr.GetString())
+	}
+	for _, attr := range service.GetAttrs(ServiceAttrTagAggregate) {
+		values, ok := attr.Value.([]interface{})
+		if !ok {
+			values = []interface{}{attr.Value}
+		}
+		for _, v := range values {
+			call, err := parseCall(v, timeout)
+			if nil != err {
+				return plan, err
+			}
+			plan.Calls = append(plan.Calls, call)
+		}
+	}
+	if len(plan.Calls) == 0 {
+		return plan, fmt.Errorf("aggregate calls not defined, service: %s", service.ServiceID())
+	}
+	return plan, plan.validate()
+}
+
+func parseCall(value interface{}, timeout time.Duration) (Call, error) {
+	call := Call{Timeout: timeout, Binds: make(map[string]string, 0)}
+	if id, ok := value.(string); ok {
+		call.Key, call.ServiceId = id, id
+		return call, nil
+	}
+	conf, err := cast.ToStringMapE(value)
+	if nil != err {
+		return call, fmt.Errorf("invalid aggregate call: %+v", value)
+	}
+	call.ServiceId = cast.ToString(conf["service"])
+	if "" == call.ServiceId {
+		return call, fmt.Errorf("aggregate call service not defined: %+v", value)
+	}
+	call.Key = cast.ToString(conf["key"])
+	if "" == call.Key {
+		call.Key = call.ServiceId
+	}
+	switch depends := conf["depends"].(type) {
+	case nil:
+	case string:
+		call.Depends = splitTrim(depends)
+	default:
+		call.Depends = cast.ToStringSlice(depends)
+	}
+	if binds, ok := conf["bind"]; ok {
+		call.Binds = cast.ToStringMapString(binds)
+	}
+	if v, ok := conf["timeout"]; ok {
+		call.Timeout = cast.ToDuration(v)
+	}
+	call.Required = cast.ToBool(conf["required"])
+	// 参数绑定的结果来源，作为隐式依赖
+	for _, path := range call.Binds {
+		if dep := strings.SplitN(path, ".", 2)[0]; !contains(call.Depends, dep) {
+			call.Depends = append(call.Depends, dep)
+		}
+	}
+	return call, nil
+}
+
+// validate 校验子服务调用的Key唯一、依赖存在且无循环依赖
+func (p Plan) validate() error {
+	calls := make(map[string]Call, len(p.Calls))
+	for _, c := range p.Calls {
+		if _, dup := calls[c.Key]; dup {
+			return fmt.Errorf("duplicated aggregate call key: %s", c.Key)
+		}
+		calls[c.Key] = c
+	}
+	for _, c := range p.Calls {
+		for _, dep := range c.Depends {
+			if _, ok := calls[dep]; !ok {
+				return fmt.Errorf("aggregate call: %s, depends on unknown call: %s", c.Key, dep)
+			}
+		}
+	}
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	states := make(map[string]int, len(calls))
+	var visit func(key string) error
+	visit = func(key string) error {
+		switch states[key] {
+		case visiting:
+			return fmt.Errorf("aggregate call has circular dependency: %s", key)
+		case visited:
+			return nil
+		}
+		states[key] = visiting
+		for _, dep := range calls[key].Depends {
+			if err := visit(dep); nil != err {
+				return err
+			}
+		}
+		states[key] = visited
+		return nil
+	}
+	for _, c := range p.Calls {
+		if err := visit(c.Key); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+// LookupPath 按路径(key.field.index)从聚合结果中查找值
+func LookupPath(results map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = results
+	for _, name := range strings.Split(path, ".") {
+		switch v := current.(type) {
+		case map[string]interface{}:
+			next, ok := v[name]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case map[interface{}]interface{}:
+			next, ok := v[name]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []interface{}:
+			idx, err := cast.ToIntE(name)
+			if nil != err || idx < 0 || idx >= len(v) {
+				return nil, false
+			}
+			current = v[idx]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func splitTrim(value string) []string {
+	out := make([]string, 0, 2)
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); "" != v {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+package aggregate
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	assert2 "github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newAggregateService(calls interface{}, attrs ...flux.Attribute) flux.TransporterService {
+	attrs = append(attrs, flux.Attribute{Name: ServiceAttrTagAggregate, Value: calls})
+	return flux.TransporterService{
+		Interface: "aggregate", Method: "test",
+		EmbeddedAttributes: flux.EmbeddedAttributes{Attributes: attrs},
+	}
+}
+
+func TestParsePlan(t *testing.T) {
+	cases := []struct {
+		name    string
+		service flux.TransporterService
+		policy  string
+		calls   []Call
+	}{
+		{
+			name:    "service ids",
+			service: newAggregateService([]interface{}{"user.get", "order.list"}),
+			policy:  PolicyFailFast,
+			calls: []Call{
+				{Key: "user.get", ServiceId: "user.get", Binds: map[string]string{}},
+				{Key: "order.list", ServiceId: "order.list", Binds: map[string]string{}},
+			},
+		},
+		{
+			name: "call options",
+			service: newAggregateService([]interface{}{
+				map[string]interface{}{"key": "user", "service": "user.get"},
+				map[string]interface{}{
+					"key": "orders", "service": "order.list", "depends": "user",
+					"bind": map[string]interface{}{"userId": "user.id"}, "timeout": "200ms", "required": true,
+				},
+			}, flux.Attribute{Name: ServiceAttrTagAggregatePolicy, Value: "PARTIAL"},
+				flux.Attribute{Name: ServiceAttrTagAggregateTimeout, Value: "1s"}),
+			policy: PolicyPartial,
+			calls: []Call{
+				{Key: "user", ServiceId: "user.get", Binds: map[string]string{}, Timeout: time.Second},
+				{Key: "orders", ServiceId: "order.list", Depends: []string{"user"},
+					Binds: map[string]string{"userId": "user.id"}, Timeout: 200 * time.Millisecond, Required: true},
+			},
+		},
+		{
+			name: "implicit bind depends",
+			service: newAggregateService([]interface{}{
+				"user",
+				map[string]interface{}{"service": "order.list", "bind": map[string]interface{}{"userId": "user.id"}},
+			}),
+			policy: PolicyFailFast,
+			calls: []Call{
+				{Key: "user", ServiceId: "user", Binds: map[string]string{}},
+				{Key: "order.list", ServiceId: "order.list", Depends: []string{"user"}, Binds: map[string]string{"userId": "user.id"}},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert := assert2.New(t)
+			plan, err := ParsePlan(c.service)
+			assert.Nil(err)
+			assert.Equal(c.policy, plan.Policy)
+			assert.Equal(c.calls, plan.Calls)
+		})
+	}
+}
+
+func TestParsePlan_Invalid(t *testing.T) {
+	cases := []struct {
+		name    string
+		service flux.TransporterService
+		err     string
+	}{
+		{
+			name:    "unknown policy",
+			service: newAggregateService("a", flux.Attribute{Name: ServiceAttrTagAggregatePolicy, Value: "retry"}),
+			err:     "unknown aggregate policy",
+		},
+		{
+			name:    "no calls",
+			service: flux.TransporterService{Interface: "aggregate", Method: "test"},
+			err:     "aggregate calls not defined",
+		},
+		{
+			name:    "service not defined",
+			service: newAggregateService([]interface{}{map[string]interface{}{"key": "a"}}),
+			err:     "aggregate call service not defined",
+		},
+		{
+			name:    "duplicated key",
+			service: newAggregateService([]interface{}{"a", map[string]interface{}{"key": "a", "service": "b"}}),
+			err:     "duplicated aggregate call key: a",
+		},
+		{
+			name:    "unknown dependency",
+			service: newAggregateService([]interface{}{map[string]interface{}{"service": "a", "depends": "b"}}),
+			err:     "depends on unknown call: b",
+		},
+		{
+			name:    "unknown bind dependency",
+			service: newAggregateService([]interface{}{map[string]interface{}{"service": "a", "bind": map[string]interface{}{"id": "b.id"}}}),
+			err:     "depends on unknown call: b",
+		},
+		{
+			name: "circular dependency",
+			service: newAggregateService([]interface{}{
+				map[string]interface{}{"service": "a", "depends": "c"},
+				map[string]interface{}{"service": "b", "depends": "a"},
+				map[string]interface{}{"service": "c", "depends": "b"},
+			}),
+			err: "circular dependency",
+		},
+		{
+			name:    "self dependency",
+			service: newAggregateService([]interface{}{map[string]interface{}{"service": "a", "depends": "a"}}),
+			err:     "circular dependency",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert := assert2.New(t)
+			_, err := ParsePlan(c.service)
+			assert.NotNil(err)
+			if nil != err {
+				assert.True(strings.Contains(err.Error(), c.err), err.Error())
+			}
+		})
+	}
+}
+
+func TestLookupPath(t *testing.T) {
+	results := map[string]interface{}{
+		"user": map[string]interface{}{
+			"id":   "u1",
+			"tags": []interface{}{"a", map[string]interface{}{"name": "b"}},
+		},
+		"yaml": map[interface{}]interface{}{"id": 1},
+		"text": "plain",
+	}
+	cases := []struct {
+		path  string
+		value interface{}
+		found bool
+	}{
+		{path: "user.id", value: "u1", found: true},
+		{path: "user.tags.0", value: "a", found: true},
+		{path: "user.tags.1.name", value: "b", found: true},
+		{path: "yaml.id", value: 1, found: true},
+		{path: "text", value: "plain", found: true},
+		{path: "user.none", found: false},
+		{path: "user.tags.2", found: false},
+		{path: "user.tags.x", found: false},
+		{path: "text.id", found: false},
+		{path: "none", found: false},
+	}
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			assert := assert2.New(t)
+			value, found := LookupPath(results, c.path)
+			assert.Equal(c.found, found)
+			assert.Equal(c.value, value)
+		})
+	}
+}
@@ -0,0 +1,494 @@
+package aggregate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/common"
+	"github.com/bytepowered/flux/flux-node/ext"
+	"github.com/bytepowered/flux/flux-node/transporter"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 聚合响应的字段
+const (
+	ResponseKeyData   = "data"
+	ResponseKeyErrors = "errors"
+)
+
+func init() {
+	ext.RegisterTransporter(flux.ProtoAggregate, NewTransporter())
+}
+
+var (
+	_ flux.Transporter          = new(RpcTransporter)
+	_ flux.ServiceValidator     = new(RpcTransporter)
+	_ flux.ServiceEventListener = new(RpcTransporter)
+)
+
+var (
+	ErrUnknownAggregateResponse = errors.New("TRANSPORTER:AGGREGATE:UNKNOWN_RESPONSE")
+)
+
+// Option 配置函数
+type Option func(*RpcTransporter)
+
+// RpcTransporter 聚合调用多个子服务：无依赖的子服务并发调用，依赖其它子服务结果的调用在依赖完成后执行；
+// 子服务通过 ext.TransporterServiceById 查找，按其协议对应的Transporter执行；
+type RpcTransporter struct {
+	codec      flux.TransportCodec
+	writer     flux.TransportWriter
+	plans      sync.Map // 按ServiceID缓存已解析的调用计划
+	generation uint64   // 服务定义变更的版本号，变更后重新查找子服务
+}
+
+// planEntry 缓存的聚合调用计划，以及查找到的子服务定义
+type planEntry struct {
+	attrs      []flux.Attribute
+	plan       Plan
+	services   map[string]flux.TransporterService
+	generation uint64
+}
+
+// Result 聚合调用结果
+type Result struct {
+	Policy string
+	Data   map[string]interface{}
+	Errors map[string]*flux.ServeError
+}
+
+// WithTransportCodec 用于配置响应数据解析实现函数
+func WithTransportCodec(fun flux.TransportCodec) Option {
+	return func(service *RpcTransporter) {
+		service.codec = fun
+	}
+}
+
+// WithTransportWriter 用于配置响应数据解析实现函数
+func WithTransportWriter(fun flux.TransportWriter) Option {
+	return func(service *RpcTransporter) {
+		service.writer = fun
+	}
+}
+
+func NewTransporter() flux.Transporter {
+	return NewTransporterWith(
+		WithTransportCodec(NewTransportCodecFunc()),
+		WithTransportWriter(new(transporter.DefaultTransportWriter)),
+	)
+}
+
+func NewTransporterWith(opts ...Option) flux.Transporter {
+	bts := new(RpcTransporter)
+	for _, opt := range opts {
+		opt(bts)
+	}
+	return bts
+}
+
+func (b *RpcTransporter) Writer() flux.TransportWriter {
+	return b.writer
+}
+
+func (b *RpcTransporter) Transport(ctx *flux.Context) {
+	transporter.DoTransport(ctx, b)
+}
+
+func (b *RpcTransporter) InvokeCodec(ctx *flux.Context, service flux.TransporterService) (*flux.ResponseBody, *flux.ServeError) {
+	raw, serr := b.Invoke(ctx, service)
+	if nil != serr {
+		return nil, serr
+	}
+	result, err := b.codec(ctx, raw)
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageTransportDecodeResponse,
+			CauseError: fmt.Errorf("decode aggregate response, err: %w", err),
+		}
+	}
+	return result, nil
+}
+
+func (b *RpcTransporter) Invoke(ctx *flux.Context, service flux.TransporterService) (interface{}, *flux.ServeError) {
+	entry, err := b.loadPlan(service)
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageAggregateInvalid,
+			CauseError: err,
+		}
+	}
+	services, serr := b.loadServices(service, entry)
+	if nil != serr {
+		return nil, serr
+	}
+	// 子服务并发读取请求参数，预先解析Query和Form参数
+	_, _ = ctx.QueryVars(), ctx.FormVars()
+	return newExecution(ctx, entry.plan, services).run()
+}
+
+// ValidateService 加载服务定义时解析并校验聚合调用计划；已注册的子服务同时校验参数绑定；
+func (b *RpcTransporter) ValidateService(service flux.TransporterService) error {
+	plan, err := ParsePlan(service)
+	if nil != err {
+		return err
+	}
+	for _, call := range plan.Calls {
+		// 子服务可能在聚合服务之后加载，未注册的子服务在请求时检查
+		if sub, ok := ext.TransporterServiceById(call.ServiceId); ok {
+			if err := checkBinds(sub, call); nil != err {
+				return err
+			}
+		}
+	}
+	b.plans.Store(service.ServiceID(), &planEntry{attrs: service.Attributes, plan: plan})
+	return nil
+}
+
+// OnServiceEvent 服务定义变更时，重新查找聚合调用的子服务；聚合服务删除时，移除缓存的调用计划；
+func (b *RpcTransporter) OnServiceEvent(event flux.ServiceEvent) {
+	atomic.AddUint64(&b.generation, 1)
+	if flux.EventTypeRemoved == event.EventType {
+		b.plans.Delete(event.Service.ServiceID())
+	}
+}
+
+// loadPlan 返回缓存的调用计划；服务未经校验加载，或者属性已变更时，重新解析调用计划
+func (b *RpcTransporter) loadPlan(service flux.TransporterService) (*planEntry, error) {
+	if v, ok := b.plans.Load(service.ServiceID()); ok {
+		if entry := v.(*planEntry); sameAttrs(entry.attrs, service.Attributes) {
+			return entry, nil
+		}
+	}
+	plan, err := ParsePlan(service)
+	if nil != err {
+		return nil, err
+	}
+	entry := &planEntry{attrs: service.Attributes, plan: plan}
+	b.plans.Store(service.ServiceID(), entry)
+	return entry, nil
+}
+
+// loadServices 返回调用计划的子服务定义；子服务在服务定义变更后重新查找并校验参数绑定
+func (b *RpcTransporter) loadServices(service flux.TransporterService, entry *planEntry) (map[string]flux.TransporterService, *flux.ServeError) {
+	generation := atomic.LoadUint64(&b.generation)
+	if nil != entry.services && generation == entry.generation {
+		return entry.services, nil
+	}
+	services := make(map[string]flux.TransporterService, len(entry.plan.Calls))
+	for _, call := range entry.plan.Calls {
+		sub, ok := ext.TransporterServiceById(call.ServiceId)
+		if !ok {
+			return nil, &flux.ServeError{
+				StatusCode: flux.StatusServerError,
+				ErrorCode:  flux.ErrorCodeGatewayInternal,
+				Message:    flux.ErrorMessageAggregateServiceNotFound,
+				CauseError: fmt.Errorf("aggregate service not found, call: %s, service: %s", call.Key, call.ServiceId),
+			}
+		}
+		if err := checkBinds(sub, call); nil != err {
+			return nil, &flux.ServeError{
+				StatusCode: flux.StatusServerError,
+				ErrorCode:  flux.ErrorCodeGatewayInternal,
+				Message:    flux.ErrorMessageAggregateInvalid,
+				CauseError: err,
+			}
+		}
+		services[call.Key] = sub
+	}
+	// 缓存项被并发请求共享，更新时替换为新的缓存项
+	b.plans.Store(service.ServiceID(), &planEntry{
+		attrs: entry.attrs, plan: entry.plan, services: services, generation: generation,
+	})
+	return services, nil
+}
+
+// sameAttrs 判断两个属性列表是否引用同一份服务定义的属性
+func sameAttrs(a, b []flux.Attribute) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	return len(a) == 0 || &a[0] == &b[0]
+}
+
+// execution 一次聚合调用的执行状态
+type execution struct {
+	parent   *flux.Context
+	plan     Plan
+	services map[string]flux.TransporterService
+	dones    map[string]chan struct{}
+	results  map[string]interface{}
+	errors   map[string]*flux.ServeError
+	metrics  map[string][]flux.Metric
+	failure  *flux.ServeError
+	ctx      context.Context
+	cancel   context.CancelFunc
+	mu       sync.RWMutex
+}
+
+func newExecution(parent *flux.Context, plan Plan, services map[string]flux.TransporterService) *execution {
+	ctx, cancel := context.WithCancel(parent.Context())
+	dones := make(map[string]chan struct{}, len(plan.Calls))
+	for _, call := range plan.Calls {
+		dones[call.Key] = make(chan struct{})
+	}
+	return &execution{
+		parent:   parent,
+		plan:     plan,
+		services: services,
+		dones:    dones,
+		results:  make(map[string]interface{}, len(plan.Calls)),
+		errors:   make(map[string]*flux.ServeError, 0),
+		metrics:  make(map[string][]flux.Metric, len(plan.Calls)),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+func (e *execution) run() (*Result, *flux.ServeError) {
+	defer e.cancel()
+	wg := new(sync.WaitGroup)
+	for _, call := range e.plan.Calls {
+		wg.Add(1)
+		go func(call Call) {
+			defer wg.Done()
+			defer close(e.dones[call.Key])
+			e.execute(call)
+		}(call)
+	}
+	// 等待全部子服务调用结束：子调用使用的WebContext在请求结束后会被回收
+	wg.Wait()
+	for _, call := range e.plan.Calls {
+		for _, m := range e.metrics[call.Key] {
+			e.parent.AddMetric("aggregate."+call.Key+"."+m.Name, m.Elapsed)
+		}
+	}
+	if nil != e.failure {
+		return nil, e.failure
+	}
+	return &Result{Policy: e.plan.Policy, Data: e.results, Errors: e.errors}, nil
+}
+
+func (e *execution) execute(call Call) {
+	for _, dep := range call.Depends {
+		select {
+		case <-e.dones[dep]:
+		case <-e.ctx.Done():
+			e.fail(call, &flux.ServeError{
+				StatusCode: flux.StatusServerError,
+				ErrorCode:  flux.ErrorCodeGatewayCanceled,
+				Message:    flux.ErrorMessageAggregateCallFailed,
+				CauseError: fmt.Errorf("aggregate call canceled, call: %s, err: %w", call.Key, e.ctx.Err()),
+			})
+			return
+		}
+		e.mu.RLock()
+		_, ok := e.results[dep]
+		e.mu.RUnlock()
+		if !ok {
+			e.fail(call, &flux.ServeError{
+				StatusCode: flux.StatusBadGateway,
+				ErrorCode:  flux.ErrorCodeGatewayTransporter,
+				Message:    flux.ErrorMessageAggregateDependencyFailed,
+				CauseError: fmt.Errorf("aggregate call: %s, dependency failed: %s", call.Key, dep),
+			})
+			return
+		}
+	}
+	child, cancel := e.newCallContext(call)
+	defer cancel()
+	// 子服务参数与网关请求参数一致，执行参数解析和校验
+	arguments, serr := common.ResolveArguments(child, child.Transporter().Arguments)
+	if nil != serr {
+		e.fail(call, serr)
+		return
+	}
+	child.Endpoint().Service.Arguments = arguments
+	start := time.Now()
+	response, serr := transporter.DoInvokeCodec(child, child.Transporter())
+	child.AddMetric("elapsed", time.Since(start))
+	e.mu.Lock()
+	e.metrics[call.Key] = child.Metrics()
+	e.mu.Unlock()
+	if nil != serr {
+		e.fail(call, serr)
+		return
+	}
+	body, err := decodeBody(response.Body)
+	if nil != err {
+		e.fail(call, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageTransportDecodeResponse,
+			CauseError: fmt.Errorf("decode aggregate call response, call: %s, err: %w", call.Key, err),
+		})
+		return
+	}
+	if response.StatusCode >= http.StatusBadRequest {
+		e.fail(call, &flux.ServeError{
+			StatusCode: response.StatusCode,
+			ErrorCode:  flux.ErrorCodeGatewayTransporter,
+			Message:    flux.ErrorMessageAggregateCallFailed,
+			CauseError: fmt.Errorf("aggregate call: %s, response status: %d, body: %v", call.Key, response.StatusCode, body),
+		})
+		return
+	}
+	e.mu.Lock()
+	e.results[call.Key] = body
+	e.mu.Unlock()
+}
+
+// fail 记录子服务调用失败；fail_fast策略或者必需的子服务失败时，取消其它调用；
+func (e *execution) fail(call Call, serr *flux.ServeError) {
+	e.parent.Logger().Warnw("TRANSPORTER:AGGREGATE:CALL_FAILED", "call", call.Key, "service", call.ServiceId, "error", serr)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.errors[call.Key] = serr
+	if PolicyPartial == e.plan.Policy && !call.Required {
+		return
+	}
+	if nil == e.failure {
+		e.failure = serr
+		e.cancel()
+	}
+}
+
+// newCallContext 构建子服务调用的Context：复制请求的Attributes和Logger，使用子服务定义替换Endpoint的服务；
+func (e *execution) newCallContext(call Call) (*flux.Context, context.CancelFunc) {
+	var cctx context.Context
+	var cancel context.CancelFunc
+	if call.Timeout > 0 {
+		cctx, cancel = context.WithTimeout(e.ctx, call.Timeout)
+	} else {
+		cctx, cancel = context.WithCancel(e.ctx)
+	}
+	endpoint := *e.parent.Endpoint()
+	endpoint.Service = e.bindArguments(call)
+	child := flux.NewContext()
+	child.Reset(&callWebContext{ServerWebContext: e.parent.ServerWebContext, ctx: cctx}, &endpoint)
+	child.SetLogger(e.parent.Logger())
+	for k, v := range e.parent.Attributes() {
+		child.SetAttribute(k, v)
+	}
+	return child, cancel
+}
+
+// checkBinds 校验参数绑定的参数在子服务中已定义
+func checkBinds(service flux.TransporterService, call Call) error {
+	for name := range call.Binds {
+		found := false
+		for _, arg := range service.Arguments {
+			if arg.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("aggregate call: %s, bind argument not found: %s", call.Key, name)
+		}
+	}
+	return nil
+}
+
+// bindArguments 复制子服务定义，将绑定的参数替换为从依赖子服务结果中加载参数值
+func (e *execution) bindArguments(call Call) flux.TransporterService {
+	service := e.services[call.Key]
+	if len(call.Binds) == 0 {
+		return service
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	arguments := make([]flux.Argument, len(service.Arguments))
+	copy(arguments, service.Arguments)
+	for i := range arguments {
+		path, ok := call.Binds[arguments[i].Name]
+		if !ok {
+			continue
+		}
+		mtv := flux.NewInvalidMTValue()
+		if value, found := LookupPath(e.results, path); found {
+			mtv = flux.WrapObjectMTValue(value)
+		}
+		arguments[i].ValueLoader = func() flux.MTValue {
+			return mtv
+		}
+	}
+	service.Arguments = arguments
+	return service
+}
+
+// callWebContext 子服务调用的WebContext，使用独立的超时控制Context
+type callWebContext struct {
+	flux.ServerWebContext
+	ctx context.Context
+}
+
+func (c *callWebContext) Context() context.Context {
+	return c.ctx
+}
+
+// decodeBody 读取子服务的响应数据；JSON数据解码为对象，否则作为字符串；
+func decodeBody(body interface{}) (interface{}, error) {
+	var data []byte
+	switch v := body.(type) {
+	case []byte:
+		data = v
+	case io.Reader:
+		if closer, ok := v.(io.Closer); ok {
+			defer closer.Close()
+		}
+		bs, err := ioutil.ReadAll(v)
+		if nil != err {
+			return nil, err
+		}
+		data = bs
+	default:
+		return body, nil
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		var out interface{}
+		if err := ext.JSONUnmarshal(trimmed, &out); nil == err {
+			return out, nil
+		}
+	}
+	return string(data), nil
+}
+
+func NewTransportCodecFunc() flux.TransportCodec {
+	return func(ctx *flux.Context, value interface{}) (*flux.ResponseBody, error) {
+		result, ok := value.(*Result)
+		if !ok {
+			return nil, ErrUnknownAggregateResponse
+		}
+		var body interface{} = result.Data
+		if PolicyPartial == result.Policy {
+			out := map[string]interface{}{ResponseKeyData: result.Data}
+			if len(result.Errors) > 0 {
+				errs := make(map[string]interface{}, len(result.Errors))
+				for key, serr := range result.Errors {
+					errs[key] = map[string]interface{}{
+						"code":    serr.GetErrorCode(),
+						"message": serr.Message,
+					}
+				}
+				out[ResponseKeyErrors] = errs
+			}
+			body = out
+		}
+		return &flux.ResponseBody{
+			StatusCode: flux.StatusOK,
+			Headers:    make(http.Header, 0),
+			Body:       body,
+		}, nil
+	}
+}
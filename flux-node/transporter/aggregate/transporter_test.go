@@ -0,0 +1,329 @@
+package aggregate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/common"
+	"github.com/bytepowered/flux/flux-node/ext"
+	"github.com/bytepowered/flux/flux-node/internal/fluxtest"
+	"github.com/bytepowered/flux/flux-node/logger"
+	"github.com/bytepowered/flux/flux-node/transporter/inproc"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func init() {
+	ext.SetLoggerFactory(logger.DefaultFactory)
+	ext.RegisterSerializer(ext.TypeNameSerializerJson, flux.NewJsonSerializer())
+	ext.RegisterTransporter(flux.ProtoInProc, inproc.NewTransporter())
+}
+
+// registerCall 注册进程内函数作为子服务，返回删除子服务的函数
+func registerCall(id string, fun flux.InProcFunc, args ...flux.Argument) func() {
+	ext.RegisterInProcFunc("aggregate", id, fun)
+	ext.RegisterTransporterServiceById(id, flux.TransporterService{
+		Interface: "aggregate", Method: id,
+		EmbeddedAttributes: flux.EmbeddedAttributes{Attributes: []flux.Attribute{
+			{Name: flux.ServiceAttrTagRpcProto, Value: flux.ProtoInProc},
+		}},
+		Arguments: args,
+	})
+	return func() {
+		ext.RemoveInProcFunc("aggregate", id)
+		ext.RemoveTransporterService(id)
+	}
+}
+
+// jsonFunc 返回固定JSON响应的函数
+func jsonFunc(body string) flux.InProcFunc {
+	return func(ctx *flux.Context, arguments map[string]interface{}) (*flux.ResponseBody, error) {
+		return &flux.ResponseBody{StatusCode: flux.StatusOK, Body: []byte(body)}, nil
+	}
+}
+
+// errorFunc 返回调用失败的函数
+func errorFunc(message string) flux.InProcFunc {
+	return func(ctx *flux.Context, arguments map[string]interface{}) (*flux.ResponseBody, error) {
+		return nil, errors.New(message)
+	}
+}
+
+// blockingFunc 等待调用被取消的函数；started 在函数开始执行时关闭
+func blockingFunc(started chan struct{}) flux.InProcFunc {
+	return func(ctx *flux.Context, arguments map[string]interface{}) (*flux.ResponseBody, error) {
+		close(started)
+		select {
+		case <-ctx.Context().Done():
+			return nil, ctx.Context().Err()
+		case <-time.After(3 * time.Second):
+			return &flux.ResponseBody{StatusCode: flux.StatusOK, Body: []byte(`"finished"`)}, nil
+		}
+	}
+}
+
+func newTestContext() *flux.Context {
+	return fluxtest.NewContext(httptest.NewRequest("GET", "http://mocking/aggregate?name=foo", nil))
+}
+
+func TestRpcTransporter_InvokeBind(t *testing.T) {
+	assert := assert2.New(t)
+	name := ext.NewStringArgument("name")
+	name.LookupFunc = common.LookupMTValue
+	defer registerCall("user", jsonFunc(`{"id":"u1","name":"foo"}`))()
+	defer registerCall("orders", func(ctx *flux.Context, arguments map[string]interface{}) (*flux.ResponseBody, error) {
+		return &flux.ResponseBody{StatusCode: flux.StatusOK, Body: []byte(fmt.Sprintf(`{"userId":"%v","name":"%v"}`,
+			arguments["userId"], arguments["name"]))}, nil
+	}, ext.NewStringArgument("userId"), name)()
+	result, serr := NewTransporter().Invoke(newTestContext(), newAggregateService([]interface{}{
+		map[string]interface{}{"service": "orders", "bind": map[string]interface{}{"userId": "user.id"}},
+		"user",
+	}))
+	assert.Nil(serr)
+	assert.Equal(map[string]interface{}{
+		"user":   map[string]interface{}{"id": "u1", "name": "foo"},
+		"orders": map[string]interface{}{"userId": "u1", "name": "foo"},
+	}, result.(*Result).Data)
+}
+
+func TestRpcTransporter_InvokeFailFast(t *testing.T) {
+	assert := assert2.New(t)
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	defer registerCall("slow", func(ctx *flux.Context, arguments map[string]interface{}) (*flux.ResponseBody, error) {
+		resp, err := blockingFunc(started)(ctx, arguments)
+		if nil != err {
+			close(canceled)
+		}
+		return resp, err
+	})()
+	defer registerCall("bad", func(ctx *flux.Context, arguments map[string]interface{}) (*flux.ResponseBody, error) {
+		<-started
+		return nil, errors.New("bad call")
+	})()
+	defer registerCall("next", jsonFunc(`{}`))()
+	start := time.Now()
+	_, serr := NewTransporter().Invoke(newTestContext(), newAggregateService([]interface{}{
+		"slow", "bad", map[string]interface{}{"service": "next", "depends": "bad"},
+	}))
+	assert.NotNil(serr)
+	assert.Equal(flux.ErrorMessageInProcInvokeFailed, serr.Message)
+	assert.True(time.Since(start) < time.Second)
+	select {
+	case <-canceled:
+	default:
+		assert.True(false, "slow call not canceled")
+	}
+}
+
+func TestRpcTransporter_InvokePartial(t *testing.T) {
+	cases := []struct {
+		name   string
+		calls  []interface{}
+		failed bool
+		data   []string
+		errors map[string]string
+	}{
+		{
+			name:   "optional call failed",
+			calls:  []interface{}{"ok", "bad"},
+			data:   []string{"ok"},
+			errors: map[string]string{"bad": flux.ErrorMessageInProcInvokeFailed},
+		},
+		{
+			name: "dependency failed",
+			calls: []interface{}{"ok", "bad",
+				map[string]interface{}{"service": "next", "depends": "bad"}},
+			data: []string{"ok"},
+			errors: map[string]string{
+				"bad":  flux.ErrorMessageInProcInvokeFailed,
+				"next": flux.ErrorMessageAggregateDependencyFailed,
+			},
+		},
+		{
+			name:   "required call failed",
+			calls:  []interface{}{"ok", map[string]interface{}{"service": "bad", "required": true}},
+			failed: true,
+		},
+		{
+			name:   "call timeout",
+			calls:  []interface{}{"ok", map[string]interface{}{"service": "slow", "timeout": "50ms"}},
+			data:   []string{"ok"},
+			errors: map[string]string{"slow": flux.ErrorMessageInProcInvokeFailed},
+		},
+	}
+	defer registerCall("ok", jsonFunc(`{"value":"ok"}`))()
+	defer registerCall("bad", errorFunc("bad call"))()
+	defer registerCall("next", jsonFunc(`{}`))()
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert := assert2.New(t)
+			defer registerCall("slow", blockingFunc(make(chan struct{})))()
+			service := newAggregateService(c.calls, flux.Attribute{Name: ServiceAttrTagAggregatePolicy, Value: PolicyPartial})
+			start := time.Now()
+			result, serr := NewTransporter().Invoke(newTestContext(), service)
+			assert.True(time.Since(start) < time.Second)
+			if c.failed {
+				assert.NotNil(serr)
+				return
+			}
+			assert.Nil(serr)
+			res := result.(*Result)
+			assert.Equal(len(c.data), len(res.Data))
+			for _, key := range c.data {
+				assert.Equal(map[string]interface{}{"value": "ok"}, res.Data[key])
+			}
+			assert.Equal(len(c.errors), len(res.Errors))
+			for key, message := range c.errors {
+				if serr, ok := res.Errors[key]; assert.True(ok) {
+					assert.Equal(message, serr.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestRpcTransporter_InvokeCodecPartial(t *testing.T) {
+	assert := assert2.New(t)
+	defer registerCall("ok", jsonFunc(`{"value":"ok"}`))()
+	defer registerCall("bad", errorFunc("bad call"))()
+	resp, serr := NewTransporter().InvokeCodec(newTestContext(), newAggregateService([]interface{}{"ok", "bad"},
+		flux.Attribute{Name: ServiceAttrTagAggregatePolicy, Value: PolicyPartial}))
+	assert.Nil(serr)
+	assert.Equal(flux.StatusOK, resp.StatusCode)
+	assert.Equal(map[string]interface{}{
+		ResponseKeyData: map[string]interface{}{"ok": map[string]interface{}{"value": "ok"}},
+		ResponseKeyErrors: map[string]interface{}{"bad": map[string]interface{}{
+			"code": flux.ErrorCodeGatewayTransporter, "message": flux.ErrorMessageInProcInvokeFailed,
+		}},
+	}, resp.Body)
+}
+
+func TestRpcTransporter_InvokeArgumentsInvalid(t *testing.T) {
+	assert := assert2.New(t)
+	invoked := false
+	required := ext.NewStringArgument("id")
+	required.LookupFunc = common.LookupMTValue
+	required.Attributes = []flux.Attribute{{Name: flux.ArgumentAttributeTagRequired, Value: true}}
+	defer registerCall("user", func(ctx *flux.Context, arguments map[string]interface{}) (*flux.ResponseBody, error) {
+		invoked = true
+		return nil, nil
+	}, required)()
+	_, serr := NewTransporter().Invoke(newTestContext(), newAggregateService([]interface{}{"user"}))
+	assert.NotNil(serr)
+	assert.Equal(flux.StatusBadRequest, serr.StatusCode)
+	assert.Equal(flux.ErrorMessageRequestValidation, serr.Message)
+	assert.False(invoked)
+}
+
+func TestRpcTransporter_ValidateService(t *testing.T) {
+	defer registerCall("user", jsonFunc(`{}`), ext.NewStringArgument("id"))()
+	cases := []struct {
+		name    string
+		service flux.TransporterService
+		valid   bool
+	}{
+		{
+			name:    "valid",
+			service: newAggregateService([]interface{}{"user", map[string]interface{}{"key": "other", "service": "user", "bind": map[string]interface{}{"id": "user.id"}}}),
+			valid:   true,
+		},
+		{
+			name:    "service not loaded",
+			service: newAggregateService([]interface{}{"none"}),
+			valid:   true,
+		},
+		{
+			name:    "bind argument not found",
+			service: newAggregateService([]interface{}{"user", map[string]interface{}{"key": "other", "service": "user", "bind": map[string]interface{}{"name": "user.name"}}}),
+		},
+		{
+			name:    "circular dependency",
+			service: newAggregateService([]interface{}{map[string]interface{}{"service": "user", "depends": "user"}}),
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert := assert2.New(t)
+			tr := NewTransporter().(*RpcTransporter)
+			err := tr.ValidateService(c.service)
+			assert.Equal(c.valid, nil == err)
+			_, cached := tr.plans.Load(c.service.ServiceID())
+			assert.Equal(c.valid, cached)
+		})
+	}
+}
+
+func TestRpcTransporter_InvokeCachedPlan(t *testing.T) {
+	assert := assert2.New(t)
+	defer registerCall("user", jsonFunc(`{"value":"ok"}`))()
+	tr := NewTransporter().(*RpcTransporter)
+	service := newAggregateService([]interface{}{"user"})
+	assert.Nil(tr.ValidateService(service))
+	v, _ := tr.plans.Load(service.ServiceID())
+	entry := v.(*planEntry)
+	for i := 0; i < 2; i++ {
+		_, serr := tr.Invoke(newTestContext(), service)
+		assert.Nil(serr)
+		v, _ := tr.plans.Load(service.ServiceID())
+		assert.True(v.(*planEntry).plan.Calls[0].Key == entry.plan.Calls[0].Key)
+		assert.NotNil(v.(*planEntry).services)
+	}
+	// 子服务删除后，服务变更事件使缓存的子服务失效
+	ext.RemoveTransporterService("user")
+	tr.OnServiceEvent(flux.ServiceEvent{EventType: flux.EventTypeRemoved, Service: flux.TransporterService{ServiceId: "user"}})
+	_, serr := tr.Invoke(newTestContext(), service)
+	assert.NotNil(serr)
+	assert.Equal(flux.ErrorMessageAggregateServiceNotFound, serr.Message)
+}
+
+// newBodyPathArgument 创建从请求体JSONPath查找值的参数；barrier 使全部子调用同时开始查找参数；
+func newBodyPathArgument(name, path string, barrier *sync.WaitGroup) flux.Argument {
+	arg := ext.NewStringArgument(name)
+	arg.HttpName, arg.HttpScope = path, flux.ScopeBodyPath
+	arg.LookupFunc = func(scope, key string, ctx *flux.Context) (flux.MTValue, error) {
+		barrier.Done()
+		barrier.Wait()
+		return common.LookupMTValue(scope, key, ctx)
+	}
+	return arg
+}
+
+func TestRpcTransporter_ConcurrentBodyPath(t *testing.T) {
+	assert := assert2.New(t)
+	ext.RegisterInProcFunc("aggregate", "echo", func(ctx *flux.Context, arguments map[string]interface{}) (*flux.ResponseBody, error) {
+		return &flux.ResponseBody{StatusCode: flux.StatusOK, Body: []byte(fmt.Sprintf(`{"value":"%v"}`, arguments["value"]))}, nil
+	})
+	defer ext.RemoveInProcFunc("aggregate", "echo")
+	barrier := new(sync.WaitGroup)
+	calls := make([]interface{}, 0, 4)
+	for i := 0; i < 4; i++ {
+		id := fmt.Sprintf("aggregate.child%d", i)
+		ext.RegisterTransporterServiceById(id, flux.TransporterService{
+			Interface: "aggregate", Method: "echo",
+			EmbeddedAttributes: flux.EmbeddedAttributes{Attributes: []flux.Attribute{
+				{Name: flux.ServiceAttrTagRpcProto, Value: flux.ProtoInProc},
+			}},
+			Arguments: []flux.Argument{newBodyPathArgument("value", fmt.Sprintf("$.items[%d]", i), barrier)},
+		})
+		defer ext.RemoveTransporterService(id)
+		calls = append(calls, id)
+	}
+	service := newAggregateService(calls)
+	data := []byte(`{"items":["a","b","c","d"]}`)
+	for n := 0; n < 20; n++ {
+		barrier.Add(len(calls))
+		mr := httptest.NewRequest("POST", "http://mocking/aggregate", bytes.NewReader(data))
+		mr.Header.Set(flux.HeaderContentType, flux.MIMEApplicationJSON)
+		result, serr := NewTransporter().Invoke(fluxtest.NewContext(mr), service)
+		assert.Nil(serr)
+		values := result.(*Result).Data
+		for i, v := range []string{"a", "b", "c", "d"} {
+			assert.Equal(map[string]interface{}{"value": v}, values[fmt.Sprintf("aggregate.child%d", i)])
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package dubbo
+
+import (
+	"github.com/apache/dubbo-go/cluster"
+	"github.com/apache/dubbo-go/common/extension"
+	"github.com/apache/dubbo-go/protocol"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/common"
+	"github.com/bytepowered/flux/flux-pkg"
+	"github.com/spaolacci/murmur3"
+	"github.com/spf13/cast"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 按服务定义集群和负载均衡策略的服务属性；以dubbo_为前缀，与Http协议的同名属性区分；
+const (
+	ServiceAttrTagCluster     = "dubbo_cluster"      // 集群容错策略：failover, failfast, failsafe, failback, available, broadcast, forking
+	ServiceAttrTagLoadBalance = "dubbo_load_balance" // 负载均衡策略：random, roundrobin, leastactive, consistent_hash
+	ServiceAttrTagHashKey     = "dubbo_hash_key"     // 一致性Hash策略的Hash Key表达式(scope:key)，多个表达式以逗号分隔
+)
+
+const (
+	// LoadBalanceConsistentHash 按请求Hash Key选择服务提供者的一致性Hash负载均衡策略
+	LoadBalanceConsistentHash = "consistent_hash"
+	// AttachmentKeyHashKey 一致性Hash策略使用的Attachment键名
+	AttachmentKeyHashKey = "flux.hash-key"
+)
+
+// Hash Key表达式的扩展值域
+const (
+	HashScopeArgument = "ARGUMENT" // 服务参数值，按参数名查找
+)
+
+const hashVirtualNodes = 160
+
+func init() {
+	extension.SetLoadbalance(LoadBalanceConsistentHash, NewConsistentHashLoadBalance)
+}
+
+// ConsistentHashLoadBalance 按Attachment中的Hash Key选择服务提供者，相同Hash Key的请求路由到相同的服务提供者；
+// 未设置Hash Key时，随机选择服务提供者；
+type ConsistentHashLoadBalance struct {
+	rings sync.Map // ServiceKey -> *hashRing
+}
+
+type hashRing struct {
+	signature string
+	hashes    []uint32
+	invokers  []protocol.Invoker
+}
+
+func NewConsistentHashLoadBalance() cluster.LoadBalance {
+	return new(ConsistentHashLoadBalance)
+}
+
+func (lb *ConsistentHashLoadBalance) Select(invokers []protocol.Invoker, invocation protocol.Invocation) protocol.Invoker {
+	if len(invokers) == 0 {
+		return nil
+	}
+	key := invocation.AttachmentsByKey(AttachmentKeyHashKey, "")
+	if "" == key {
+		return invokers[rand.Intn(len(invokers))]
+	}
+	ring := lb.ringOf(invokers)
+	hash := murmur3.Sum32([]byte(key))
+	idx := sort.Search(len(ring.hashes), func(i int) bool {
+		return ring.hashes[i] >= hash
+	})
+	return ring.invokers[idx%len(ring.invokers)]
+}
+
+// ringOf 返回服务提供者列表对应的Hash环；服务提供者列表变更时重建；
+func (lb *ConsistentHashLoadBalance) ringOf(invokers []protocol.Invoker) *hashRing {
+	addresses := make([]string, len(invokers))
+	for i, invoker := range invokers {
+		url := invoker.GetUrl()
+		addresses[i] = url.Ip + ":" + url.Port
+	}
+	sort.Strings(addresses)
+	signature := strings.Join(addresses, ",")
+	serviceKey := invokers[0].GetUrl().ServiceKey()
+	if v, ok := lb.rings.Load(serviceKey); ok && v.(*hashRing).signature == signature {
+		return v.(*hashRing)
+	}
+	type node struct {
+		hash    uint32
+		invoker protocol.Invoker
+	}
+	nodes := make([]node, 0, len(invokers)*hashVirtualNodes)
+	for _, invoker := range invokers {
+		url := invoker.GetUrl()
+		address := url.Ip + ":" + url.Port
+		for i := 0; i < hashVirtualNodes; i++ {
+			nodes = append(nodes, node{hash: murmur3.Sum32([]byte(address + "#" + strconv.Itoa(i))), invoker: invoker})
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].hash < nodes[j].hash
+	})
+	ring := &hashRing{
+		signature: signature,
+		hashes:    make([]uint32, len(nodes)),
+		invokers:  make([]protocol.Invoker, len(nodes)),
+	}
+	for i, n := range nodes {
+		ring.hashes[i], ring.invokers[i] = n.hash, n.invoker
+	}
+	lb.rings.Store(serviceKey, ring)
+	return ring
+}
+
+// LookupHashKey 按服务的 dubbo_hash_key 属性计算请求的Hash Key；
+// 表达式格式为 scope:key，支持Lookup值域(包括 cookie:名称、claim:JWT声明名)，以及 argument:参数名；多个表达式的值以'|'连接；
+func LookupHashKey(ctx *flux.Context, service flux.TransporterService) (string, error) {
+	attr, ok := service.GetAttrEx(ServiceAttrTagHashKey)
+	if !ok {
+		return "", nil
+	}
+	values := make([]string, 0, 2)
+	for _, expr := range strings.Split(attr.GetString(), ",") {
+		scope, key, ok := fluxpkg.LookupParseExpr(strings.TrimSpace(expr))
+		if !ok {
+			continue
+		}
+		switch scope {
+		case HashScopeArgument:
+			for _, arg := range service.Arguments {
+				if arg.Name == key {
+					value, err := arg.Resolve(ctx)
+					if nil != err {
+						return "", err
+					}
+					values = append(values, cast.ToString(value))
+					break
+				}
+			}
+		default:
+			mtv, err := common.LookupMTValue(scope, key, ctx)
+			if nil != err {
+				return "", err
+			}
+			values = append(values, cast.ToString(mtv.Value))
+		}
+	}
+	return strings.Join(values, "|"), nil
+}
@@ -0,0 +1,89 @@
+package dubbo
+
+import (
+	"github.com/apache/dubbo-go/common"
+	"github.com/apache/dubbo-go/protocol"
+	"github.com/apache/dubbo-go/protocol/invocation"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/ext"
+	"github.com/bytepowered/flux/flux-node/internal/fluxtest"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func newHashInvokers(ports ...int) []protocol.Invoker {
+	invokers := make([]protocol.Invoker, 0, len(ports))
+	for _, port := range ports {
+		u := common.NewURLWithOptions(common.WithProtocol(testProtocol), common.WithPath("net.bytepowered.Foo"),
+			common.WithIp("10.0.0.1"), common.WithPort(strconv.Itoa(port)))
+		invokers = append(invokers, protocol.NewBaseInvoker(*u))
+	}
+	return invokers
+}
+
+func hashInvocation(key string) protocol.Invocation {
+	return invocation.NewRPCInvocation("foo", nil, map[string]string{AttachmentKeyHashKey: key})
+}
+
+func TestConsistentHashLoadBalance_Select(t *testing.T) {
+	assert := assert2.New(t)
+	lb := NewConsistentHashLoadBalance()
+	invokers := newHashInvokers(20880, 20881, 20882)
+	selected := make(map[string]protocol.Invoker)
+	counts := make(map[string]int)
+	for i := 0; i < 300; i++ {
+		key := "user-" + strconv.Itoa(i)
+		invoker := lb.Select(invokers, hashInvocation(key))
+		selected[key] = invoker
+		counts[invoker.GetUrl().Port]++
+		// 相同Hash Key选择相同的服务提供者
+		assert.True(invoker == lb.Select(invokers, hashInvocation(key)))
+	}
+	// 分布
+	assert.Equal(3, len(counts))
+	for _, n := range counts {
+		assert.True(n > 50)
+	}
+	// 服务提供者列表顺序不影响选择结果
+	reversed := []protocol.Invoker{invokers[2], invokers[1], invokers[0]}
+	for key, invoker := range selected {
+		assert.True(invoker == lb.Select(reversed, hashInvocation(key)))
+	}
+	// 移除服务提供者时，只有该服务提供者的Hash Key迁移
+	remain := invokers[:2]
+	for key, prev := range selected {
+		invoker := lb.Select(remain, hashInvocation(key))
+		if prev.GetUrl().Port == "20882" {
+			assert.True(invoker != prev)
+		} else {
+			assert.Equal(prev.GetUrl().Port, invoker.GetUrl().Port)
+		}
+	}
+	// 未设置Hash Key时随机选择
+	assert.NotNil(lb.Select(invokers, invocation.NewRPCInvocation("foo", nil, nil)))
+	assert.Nil(lb.Select(nil, hashInvocation("user-1")))
+}
+
+func TestLookupHashKey(t *testing.T) {
+	assert := assert2.New(t)
+	request := httptest.NewRequest("GET", "http://mocking/api", nil)
+	request.Header.Set("X-Tenant", "t1")
+	ctx := fluxtest.NewContext(request)
+	service := flux.TransporterService{
+		Arguments: []flux.Argument{ext.NewStringArgumentWith("userId", "u1")},
+	}
+	key, err := LookupHashKey(ctx, service)
+	assert.NoError(err)
+	assert.Equal("", key)
+	// Http协议同名属性不影响Dubbo的Hash Key
+	service.Attributes = []flux.Attribute{{Name: "hash_key", Value: "userId"}}
+	key, err = LookupHashKey(ctx, service)
+	assert.NoError(err)
+	assert.Equal("", key)
+	service.Attributes = []flux.Attribute{{Name: ServiceAttrTagHashKey, Value: "header:X-Tenant, argument:userId, invalid"}}
+	key, err = LookupHashKey(ctx, service)
+	assert.NoError(err)
+	assert.Equal("t1|u1", key)
+}
@@ -79,6 +79,10 @@ func _addToHeader(headers http.Header, key string, v interface{}) {
 		for _, iv := range sa {
 			headers.Add(key, iv)
 		}
+	} else if ia, ok := v.([]interface{}); ok {
+		for _, iv := range ia {
+			headers.Add(key, cast.ToString(iv))
+		}
 	} else {
 		headers.Add(key, cast.ToString(v))
 	}
@@ -3,16 +3,128 @@ package dubbo
 import (
 	"github.com/apache/dubbo-go/protocol"
 	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/logger"
 	"github.com/spf13/cast"
 	"net/http"
+	"strings"
+	"sync"
 )
 
 const (
 	ResponseKeyStatusCode = "@net.bytepowered.flux.http-status"
 	ResponseKeyHeaders    = "@net.bytepowered.flux.http-headers"
+	ResponseKeyCookies    = "@net.bytepowered.flux.http-cookies"
+	ResponseKeyLocation   = "@net.bytepowered.flux.http-location"
+	ResponseKeyBody       = "@net.bytepowered.flux.http-body"
 )
 
+// 响应头过滤配置
+const (
+	ConfigKeyResponseHeadersAllow = "response_headers.allow"
+	ConfigKeyResponseHeadersDeny  = "response_headers.deny"
+)
+
+// 禁止后端服务设置的逐跳(Hop-by-hop)响应头，以及由网关控制的安全相关响应头
+var defaultDeniedHeaders = []string{
+	"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization", "Proxy-Connection",
+	"Te", "Trailer", "Transfer-Encoding", "Upgrade", "Content-Length",
+	"Strict-Transport-Security", "Content-Security-Policy", "X-Frame-Options",
+	"Access-Control-Allow-Origin", "Access-Control-Allow-Credentials", "Access-Control-Allow-Headers",
+	"Access-Control-Allow-Methods", "Access-Control-Expose-Headers", "Access-Control-Max-Age",
+}
+
+// ResponseKeys 定义Dubbo响应中映射Http响应的Key
+type ResponseKeys struct {
+	Status   string
+	Headers  string
+	Cookies  string
+	Location string
+	Body     string
+}
+
+// DefaultResponseKeys 返回默认的Http响应映射Key
+func DefaultResponseKeys() ResponseKeys {
+	return ResponseKeys{
+		Status:   ResponseKeyStatusCode,
+		Headers:  ResponseKeyHeaders,
+		Cookies:  ResponseKeyCookies,
+		Location: ResponseKeyLocation,
+		Body:     ResponseKeyBody,
+	}
+}
+
+// HeaderFilter 过滤后端服务设置的响应头：
+// 1. 禁止列表中的响应头总是被丢弃；
+// 2. 允许列表非空时，只保留允许列表中的响应头；
+type HeaderFilter struct {
+	allow map[string]bool
+	deny  map[string]bool
+	mu    sync.RWMutex
+}
+
+func NewHeaderFilter(allow, deny []string) *HeaderFilter {
+	f := new(HeaderFilter)
+	f.Configure(allow, deny)
+	return f
+}
+
+// Configure 更新允许列表，以及默认禁止列表之外的禁止列表
+func (f *HeaderFilter) Configure(allow, deny []string) {
+	toSet := func(names []string) map[string]bool {
+		out := make(map[string]bool, len(names))
+		for _, name := range names {
+			if name = strings.TrimSpace(name); "" != name {
+				out[http.CanonicalHeaderKey(name)] = true
+			}
+		}
+		return out
+	}
+	denied := toSet(append(append([]string{}, defaultDeniedHeaders...), deny...))
+	allowed := toSet(allow)
+	f.mu.Lock()
+	f.allow, f.deny = allowed, denied
+	f.mu.Unlock()
+}
+
+// Allowed 判断响应头是否允许输出到客户端
+func (f *HeaderFilter) Allowed(name string) bool {
+	name = http.CanonicalHeaderKey(name)
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if f.deny[name] {
+		return false
+	}
+	return len(f.allow) == 0 || f.allow[name]
+}
+
+// Filter 过滤响应头，返回允许输出的响应头
+func (f *HeaderFilter) Filter(header http.Header) http.Header {
+	out := make(http.Header, len(header))
+	for name, values := range header {
+		if f.Allowed(name) {
+			for _, v := range values {
+				out.Add(name, v)
+			}
+		} else {
+			logger.Warnw("DUBBO:RESPONSE:HEADER_DENIED", "header", name)
+		}
+	}
+	return out
+}
+
+var defaultHeaderFilter = NewHeaderFilter(nil, nil)
+
+// NewTransportCodecFuncWith 使用指定状态码和响应头Key构建响应解析函数
 func NewTransportCodecFuncWith(codeKey, headerKey string) flux.TransportCodec {
+	keys := DefaultResponseKeys()
+	keys.Status, keys.Headers = codeKey, headerKey
+	return NewTransportCodecFuncWithKeys(keys, defaultHeaderFilter)
+}
+
+// NewTransportCodecFuncWithKeys 构建响应解析函数：从Attachments以及响应体中读取Http状态码、响应头、Cookie和重定向地址；
+// 响应体为包含状态码或响应头Key的Map时，读取其Body字段作为响应体；
+// 无效的状态码或者响应头被忽略并记录日志，使用默认值；状态码为0表示使用默认状态码；
+func NewTransportCodecFuncWithKeys(keys ResponseKeys, filter *HeaderFilter) flux.TransportCodec {
 	return func(ctx *flux.Context, raw interface{}) (*flux.ResponseBody, error) {
 		// 支持Dubbo返回Result类型
 		rpcr, ok := raw.(protocol.Result)
@@ -21,27 +133,104 @@ func NewTransportCodecFuncWith(codeKey, headerKey string) flux.TransportCodec {
 				StatusCode: flux.StatusOK, Headers: make(http.Header, 0), Body: raw,
 			}, nil
 		}
-		attrs := make(map[string]interface{}, 8)
 		if err := rpcr.Error(); nil != err {
 			return nil, err
 		}
-		data := rpcr.Result()
+		attrs := make(map[string]interface{}, 8)
 		status := flux.StatusOK
+		headers := make(http.Header, 4)
+		location := ""
 		for k, v := range rpcr.Attachments() {
-			if k == codeKey {
-				status = cast.ToInt(v)
-			} else if k == headerKey {
-				// TODO 需要更新Attachment类型为map[string]interface{}
-			} else {
+			switch k {
+			case keys.Status:
+				code, err := cast.ToIntE(v)
+				if nil != err || !isValidStatus(code) {
+					logger.Warnw("Invalid rpc response status attachment", "value", v, "error", err)
+				} else if 0 != code {
+					status = code
+				}
+			case keys.Headers:
+				var values map[string]interface{}
+				if err := _json.UnmarshalFromString(v, &values); nil != err {
+					logger.Warnw("Invalid rpc response headers attachment", "value", v, "error", err)
+				}
+				for name, hv := range values {
+					_addToHeader(headers, name, hv)
+				}
+			case keys.Cookies:
+				addCookies(headers, v)
+			case keys.Location:
+				location = v
+			default:
 				attrs[k] = v
 			}
 		}
+		data := rpcr.Result()
+		if bv, ok := WrapBodyValues(data); ok && bv.isEnvelope(keys) {
+			if _, has := bv[keys.Status]; has {
+				if code, err := bv.ReadStatusValue(keys.Status); nil == err && !isValidStatus(code) {
+					logger.Warnw("Invalid rpc response status", "status", code)
+				} else if nil == err && 0 != code {
+					status = code
+				}
+			}
+			// 无效的响应头已记录日志，忽略
+			header, _ := bv.ReadHeaderValue(keys.Headers)
+			for name, values := range header {
+				for _, v := range values {
+					headers.Add(name, v)
+				}
+			}
+			if cookies, has := bv[keys.Cookies]; has {
+				addCookies(headers, cookies)
+			}
+			if loc, has := bv[keys.Location]; has {
+				location = cast.ToString(loc)
+			}
+			// 封装结构未包含Body字段时，响应体为空
+			data = bv[keys.Body]
+		}
+		if "" != location {
+			headers.Set("Location", location)
+			// 未指定重定向状态码时，使用302
+			if status < http.StatusMultipleChoices || status >= http.StatusBadRequest {
+				status = http.StatusFound
+			}
+		}
 		return &flux.ResponseBody{
-			StatusCode: status, Headers: make(http.Header, 0), Attachments: attrs, Body: data,
+			StatusCode: status, Headers: filter.Filter(headers), Attachments: attrs, Body: data,
 		}, nil
 	}
 }
 
 func NewTransportCodecFunc() flux.TransportCodec {
-	return NewTransportCodecFuncWith(ResponseKeyStatusCode, ResponseKeyHeaders)
+	return NewTransportCodecFuncWithKeys(DefaultResponseKeys(), defaultHeaderFilter)
+}
+
+// isValidStatus 判断状态码是否有效；0 表示使用默认状态码；
+func isValidStatus(code int) bool {
+	return 0 == code || (code >= 100 && code <= 599)
+}
+
+// isEnvelope 判断响应体是否为包含Http响应信息的封装结构
+func (b BodyValues) isEnvelope(keys ResponseKeys) bool {
+	for _, k := range []string{keys.Status, keys.Headers, keys.Cookies, keys.Location, keys.Body} {
+		if _, ok := b[k]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// addCookies 添加Set-Cookie响应头；支持单个Cookie字符串、JSON数组字符串，或者字符串列表；
+func addCookies(headers http.Header, cookies interface{}) {
+	if str, ok := cookies.(string); ok {
+		if trimmed := strings.TrimSpace(str); strings.HasPrefix(trimmed, "[") {
+			var values []string
+			if err := _json.UnmarshalFromString(trimmed, &values); nil == err {
+				cookies = values
+			}
+		}
+	}
+	_addToHeader(headers, "Set-Cookie", cookies)
 }
@@ -0,0 +1,102 @@
+package dubbo
+
+import (
+	"errors"
+	"github.com/apache/dubbo-go/protocol"
+	"github.com/bytepowered/flux/flux-node"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestTransportCodec_Attachments(t *testing.T) {
+	codec := NewTransportCodecFunc()
+	cases := []struct {
+		name   string
+		attrs  map[string]string
+		status int
+		header string
+	}{
+		{
+			name:   "valid",
+			attrs:  map[string]string{ResponseKeyStatusCode: "201", ResponseKeyHeaders: `{"X-Id":"1"}`},
+			status: http.StatusCreated, header: "1",
+		},
+		{
+			name:   "zero-status",
+			attrs:  map[string]string{ResponseKeyStatusCode: "0", ResponseKeyHeaders: `{"X-Id":"1"}`},
+			status: http.StatusOK, header: "1",
+		},
+		{
+			name:   "malformed-status",
+			attrs:  map[string]string{ResponseKeyStatusCode: "abc", ResponseKeyHeaders: `{"X-Id":"1"}`},
+			status: http.StatusOK, header: "1",
+		},
+		{
+			name:   "out-of-range-status",
+			attrs:  map[string]string{ResponseKeyStatusCode: "1000"},
+			status: http.StatusOK,
+		},
+		{
+			name:   "malformed-headers",
+			attrs:  map[string]string{ResponseKeyStatusCode: "202", ResponseKeyHeaders: `{"X-Id":`},
+			status: http.StatusAccepted,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert2.New(t)
+			tc.attrs["trace-id"] = "t1"
+			resp, err := codec(nil, &protocol.RPCResult{Attrs: tc.attrs, Rest: "hello"})
+			assert.NoError(err)
+			assert.Equal(tc.status, resp.StatusCode)
+			assert.Equal(tc.header, resp.Headers.Get("X-Id"))
+			assert.Equal("hello", resp.Body)
+			assert.Equal("t1", resp.Attachments["trace-id"])
+		})
+	}
+}
+
+func TestTransportCodec_Envelope(t *testing.T) {
+	assert := assert2.New(t)
+	codec := NewTransportCodecFunc()
+	resp, err := codec(nil, &protocol.RPCResult{Rest: map[interface{}]interface{}{
+		ResponseKeyStatusCode: "abc",
+		ResponseKeyHeaders:    "invalid",
+		ResponseKeyBody:       "hello",
+	}})
+	assert.NoError(err)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal("hello", resp.Body)
+	resp, err = codec(nil, &protocol.RPCResult{Rest: map[interface{}]interface{}{
+		ResponseKeyStatusCode: 404,
+		ResponseKeyHeaders:    map[string]interface{}{"X-Id": "1", "Connection": "close"},
+		ResponseKeyBody:       "missing",
+	}})
+	assert.NoError(err)
+	assert.Equal(http.StatusNotFound, resp.StatusCode)
+	assert.Equal("1", resp.Headers.Get("X-Id"))
+	assert.Equal("", resp.Headers.Get("Connection"))
+	assert.Equal("missing", resp.Body)
+}
+
+func TestTransportCodec_Location(t *testing.T) {
+	assert := assert2.New(t)
+	resp, err := NewTransportCodecFunc()(nil, &protocol.RPCResult{Attrs: map[string]string{
+		ResponseKeyLocation: "/login",
+	}})
+	assert.NoError(err)
+	assert.Equal(http.StatusFound, resp.StatusCode)
+	assert.Equal("/login", resp.Headers.Get("Location"))
+}
+
+func TestTransportCodec_Error(t *testing.T) {
+	assert := assert2.New(t)
+	codec := NewTransportCodecFunc()
+	_, err := codec(nil, &protocol.RPCResult{Err: errors.New("remote error")})
+	assert.Error(err)
+	resp, err := codec(nil, "raw")
+	assert.NoError(err)
+	assert.Equal(flux.StatusOK, resp.StatusCode)
+	assert.Equal("raw", resp.Body)
+}
@@ -0,0 +1,127 @@
+package dubbo
+
+import (
+	"github.com/apache/dubbo-go/common/constant"
+	dubgo "github.com/apache/dubbo-go/config"
+	"github.com/bytepowered/flux/flux-node"
+	"strings"
+	"time"
+)
+
+import (
+	_ "github.com/apache/dubbo-go/cluster/router/tag"
+)
+
+// 泳道路由配置
+const (
+	ConfigKeyLaneEnable       = "lane.enable"
+	ConfigKeyLaneHeader       = "lane.header"
+	ConfigKeyLaneCookie       = "lane.cookie"
+	ConfigKeyLaneMode         = "lane.mode"
+	ConfigKeyLaneForce        = "lane.force"
+	ConfigKeyLaneAllow        = "lane.allow"
+	ConfigKeyLaneReadyTimeout = "lane.ready_timeout"
+)
+
+// 泳道路由模式
+const (
+	LaneModeTag     = "tag"     // 按Dubbo标签路由(dubbo.tag)，由TagRouter选择打标的服务提供者
+	LaneModeGroup   = "group"   // 泳道标识作为服务的rpcgroup
+	LaneModeVersion = "version" // 泳道标识作为服务的rpcversion
+)
+
+// ServiceAttrTagLane 泳道服务的内部属性，标识泳道Reference
+const ServiceAttrTagLane = "lane"
+
+// LaneConfig 泳道路由配置：从请求Header或者Cookie读取泳道标识，将Dubbo调用路由到对应泳道的服务提供者；
+// 泳道不存在可用的服务提供者时，回退到基础泳道；
+type LaneConfig struct {
+	Enable       bool
+	Header       string
+	Cookie       string
+	Mode         string
+	Force        bool            // 强制使用泳道，不回退到基础泳道
+	ReadyTimeout time.Duration   // 泳道Reference创建后等待服务提供者可用的最长时间
+	Allow        map[string]bool // 允许的泳道标识；非空时，不在列表中的泳道标识被忽略
+}
+
+// NewLaneConfig 从配置中读取泳道路由配置
+func NewLaneConfig(config *flux.Configuration) LaneConfig {
+	allow := make(map[string]bool, 4)
+	for _, lane := range config.GetStringSlice(ConfigKeyLaneAllow) {
+		if lane = strings.TrimSpace(lane); "" != lane {
+			allow[lane] = true
+		}
+	}
+	return LaneConfig{
+		Enable:       config.GetBool(ConfigKeyLaneEnable),
+		Header:       config.GetString(ConfigKeyLaneHeader),
+		Cookie:       config.GetString(ConfigKeyLaneCookie),
+		Mode:         strings.ToLower(config.GetString(ConfigKeyLaneMode)),
+		Force:        config.GetBool(ConfigKeyLaneForce),
+		ReadyTimeout: config.GetDuration(ConfigKeyLaneReadyTimeout),
+		Allow:        allow,
+	}
+}
+
+// LaneOf 读取请求的泳道标识：Header优先，其次为Cookie；不在允许列表中的泳道标识被忽略；
+func (c LaneConfig) LaneOf(ctx *flux.Context) string {
+	if !c.Enable {
+		return ""
+	}
+	lane := ""
+	if "" != c.Header {
+		lane = strings.TrimSpace(ctx.HeaderVar(c.Header))
+	}
+	if "" == lane && "" != c.Cookie {
+		if cookie, err := ctx.CookieVar(c.Cookie); nil == err && nil != cookie {
+			lane = strings.TrimSpace(cookie.Value)
+		}
+	}
+	if "" == lane || (len(c.Allow) > 0 && !c.Allow[lane]) {
+		return ""
+	}
+	return lane
+}
+
+// NewLaneAttachmentResolver 标签模式下，将泳道标识设置到 dubbo.tag 附件
+func NewLaneAttachmentResolver(lane LaneConfig, next AttachmentResolver) AttachmentResolver {
+	return func(ctx *flux.Context) (interface{}, error) {
+		att, err := next(ctx)
+		if nil != err {
+			return nil, err
+		}
+		if tag := lane.LaneOf(ctx); "" != tag {
+			att = withAttachment(att, constant.Tagkey, tag)
+			if lane.Force {
+				att = withAttachment(att, constant.ForceUseTag, "true")
+			}
+		}
+		return att, nil
+	}
+}
+
+// NewLaneOptionsFunc 分组/版本模式下，按泳道服务的lane属性设置Reference的Group或者Version
+func NewLaneOptionsFunc(lane LaneConfig) GenericOptionsFunc {
+	return func(service *flux.TransporterService, _ *flux.Configuration, ref *dubgo.ReferenceConfig) *dubgo.ReferenceConfig {
+		attr, ok := service.GetAttrEx(ServiceAttrTagLane)
+		if !ok {
+			return ref
+		}
+		switch lane.Mode {
+		case LaneModeGroup:
+			ref.Group = attr.GetString()
+		case LaneModeVersion:
+			ref.Version = attr.GetString()
+		}
+		return ref
+	}
+}
+
+// LaneServiceOf 返回服务对应泳道的服务定义；复制服务属性并附加lane属性；
+func LaneServiceOf(service flux.TransporterService, lane string) flux.TransporterService {
+	attrs := make([]flux.Attribute, 0, len(service.Attributes)+1)
+	attrs = append(attrs, service.Attributes...)
+	service.Attributes = append(attrs, flux.Attribute{Name: ServiceAttrTagLane, Value: lane})
+	return service
+}
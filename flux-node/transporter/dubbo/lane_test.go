@@ -0,0 +1,118 @@
+package dubbo
+
+import (
+	"github.com/apache/dubbo-go/common"
+	dubgo "github.com/apache/dubbo-go/config"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/ext"
+	"github.com/bytepowered/flux/flux-node/internal/fluxtest"
+	"github.com/bytepowered/flux/flux-node/logger"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func init() {
+	ext.SetLoggerFactory(logger.DefaultFactory)
+}
+
+func newLaneContext(header, cookie string) *flux.Context {
+	request := httptest.NewRequest("GET", "http://mocking/api", nil)
+	if "" != header {
+		request.Header.Set("X-Env-Tag", header)
+	}
+	if "" != cookie {
+		request.AddCookie(&http.Cookie{Name: "env", Value: cookie})
+	}
+	return fluxtest.NewContext(request)
+}
+
+func newLaneTransporter(t *testing.T, force bool) *RpcTransporter {
+	tr := NewTransporter().(*RpcTransporter)
+	err := tr.Init(flux.NewConfigurationOfMap(map[string]interface{}{
+		"protocol": testProtocol,
+		"lane": map[string]interface{}{
+			"enable": true,
+			"header": "X-Env-Tag",
+			"mode":   LaneModeGroup,
+			"force":  force,
+			"allow":  []string{"gray", "blue"},
+		},
+	}))
+	if nil != err {
+		t.Fatal(err)
+	}
+	return tr
+}
+
+// cacheService 预先缓存服务的Reference，避免创建真实的DubboReference
+func cacheService(tr *RpcTransporter, service flux.TransporterService) common.RPCService {
+	srv := dubgo.NewGenericService(service.Interface)
+	_, _ = tr.references.Load(&service, func() (*dubgo.ReferenceConfig, common.RPCService, error) {
+		return nil, srv, nil
+	})
+	return srv
+}
+
+func TestLaneConfig_LaneOf(t *testing.T) {
+	assert := assert2.New(t)
+	lane := LaneConfig{Enable: true, Header: "X-Env-Tag", Cookie: "env", Allow: map[string]bool{"gray": true, "blue": true}}
+	assert.Equal("gray", lane.LaneOf(newLaneContext("gray", "blue")))
+	assert.Equal("blue", lane.LaneOf(newLaneContext("", "blue")))
+	assert.Equal("", lane.LaneOf(newLaneContext("unknown", "")))
+	assert.Equal("", lane.LaneOf(newLaneContext("", "")))
+	lane.Allow = nil
+	assert.Equal("unknown", lane.LaneOf(newLaneContext("unknown", "")))
+	lane.Enable = false
+	assert.Equal("", lane.LaneOf(newLaneContext("gray", "")))
+}
+
+func TestRpcTransporter_InitLaneAllow(t *testing.T) {
+	tr := NewTransporter().(*RpcTransporter)
+	err := tr.Init(flux.NewConfigurationOfMap(map[string]interface{}{
+		"lane": map[string]interface{}{"enable": true, "mode": LaneModeVersion},
+	}))
+	assert2.Error(t, err)
+}
+
+func TestRpcTransporter_LoadLaneServiceFallback(t *testing.T) {
+	assert := assert2.New(t)
+	tr := newLaneTransporter(t, false)
+	service := flux.TransporterService{ServiceId: "lane-s1", Interface: "net.bytepowered.Lane", Method: "foo"}
+	base := cacheService(tr, service)
+	gray := cacheService(tr, LaneServiceOf(service, "gray"))
+	size := tr.references.Size()
+	// 不在允许列表中的泳道，不创建泳道Reference
+	srv, err := tr.LoadLaneService(newLaneContext("unknown", ""), &service)
+	assert.NoError(err)
+	assert.True(base == srv)
+	assert.Equal(size, tr.references.Size())
+	// 泳道无可用服务提供者，回退到基础泳道
+	srv, err = tr.LoadLaneService(newLaneContext("gray", ""), &service)
+	assert.NoError(err)
+	assert.True(base == srv)
+	// 泳道存在可用服务提供者
+	testProto.SetInvokers(newTestInvoker("net.bytepowered.Lane", "gray", ""))
+	srv, err = tr.LoadLaneService(newLaneContext("gray", ""), &service)
+	assert.NoError(err)
+	assert.True(gray == srv)
+	// 服务删除时，同时移除泳道Reference
+	tr.OnServiceEvent(flux.ServiceEvent{EventType: flux.EventTypeRemoved, Service: service})
+	assert.Equal(0, tr.references.Size())
+}
+
+func TestRpcTransporter_LoadLaneServiceForce(t *testing.T) {
+	assert := assert2.New(t)
+	tr := newLaneTransporter(t, true)
+	service := flux.TransporterService{ServiceId: "lane-s2", Interface: "net.bytepowered.Force", Method: "foo"}
+	base := cacheService(tr, service)
+	blue := cacheService(tr, LaneServiceOf(service, "blue"))
+	// 强制泳道：泳道无可用服务提供者时，不回退
+	srv, err := tr.LoadLaneService(newLaneContext("blue", ""), &service)
+	assert.NoError(err)
+	assert.True(blue == srv)
+	srv, err = tr.LoadLaneService(newLaneContext("", ""), &service)
+	assert.NoError(err)
+	assert.True(base == srv)
+}
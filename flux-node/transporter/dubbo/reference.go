@@ -0,0 +1,183 @@
+package dubbo
+
+import (
+	"fmt"
+	"github.com/apache/dubbo-go/common"
+	"github.com/apache/dubbo-go/common/constant"
+	"github.com/apache/dubbo-go/common/extension"
+	dubgo "github.com/apache/dubbo-go/config"
+	"github.com/apache/dubbo-go/protocol"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/logger"
+	"reflect"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+const (
+	// ConfigKeyReferenceReadyTimeout DubboReference创建后，等待服务提供者可用的最长时间
+	ConfigKeyReferenceReadyTimeout = "reference_ready_timeout"
+	// ConfigKeyReferenceDelay 旧配置项；未配置 reference_ready_timeout 时，作为等待服务提供者可用的最长时间
+	ConfigKeyReferenceDelay = "reference_delay"
+)
+
+const referenceReadyInterval = time.Millisecond * 10
+
+// ReferenceKey 返回DubboReference的缓存Key：interface+group+version+remoteHost；
+// 服务属性定义了集群或者负载均衡策略时，附加到Key中；
+func ReferenceKey(service *flux.TransporterService) string {
+	key := fmt.Sprintf("%s:%s:%s@%s", service.Interface, service.RpcGroup(), service.RpcVersion(), service.RemoteHost)
+	for _, tag := range []string{ServiceAttrTagCluster, ServiceAttrTagLoadBalance, ServiceAttrTagLane} {
+		if attr, ok := service.GetAttrEx(tag); ok {
+			key += ";" + tag + "=" + attr.GetString()
+		}
+	}
+	return key
+}
+
+// referenceEntry 缓存的DubboReference；ready在创建完成后关闭；
+type referenceEntry struct {
+	ref     *dubgo.ReferenceConfig
+	service common.RPCService
+	err     error
+	ready   chan struct{}
+}
+
+// ReferenceCache 按 ReferenceKey 缓存Dubbo泛化调用的Service；
+// 同一Key的并发请求只创建一次Reference，其它请求等待创建完成；
+type ReferenceCache struct {
+	entries map[string]*referenceEntry
+	keys    map[string]map[string]bool // ServiceId -> ReferenceKey
+	mu      sync.Mutex
+}
+
+func NewReferenceCache() *ReferenceCache {
+	return &ReferenceCache{
+		entries: make(map[string]*referenceEntry, 16),
+		keys:    make(map[string]map[string]bool, 16),
+	}
+}
+
+// Load 加载缓存的Service；不存在时使用factory创建；
+func (c *ReferenceCache) Load(service *flux.TransporterService, factory func() (*dubgo.ReferenceConfig, common.RPCService, error)) (common.RPCService, error) {
+	key := ReferenceKey(service)
+	c.mu.Lock()
+	if entry, ok := c.entries[key]; ok {
+		c.mu.Unlock()
+		<-entry.ready
+		return entry.service, entry.err
+	}
+	entry := &referenceEntry{ready: make(chan struct{})}
+	c.entries[key] = entry
+	for _, id := range []string{service.ServiceId, service.AliasId} {
+		if "" == id {
+			continue
+		}
+		if _, ok := c.keys[id]; !ok {
+			c.keys[id] = make(map[string]bool, 1)
+		}
+		c.keys[id][key] = true
+	}
+	c.mu.Unlock()
+	func() {
+		defer func() {
+			if r := recover(); nil != r {
+				entry.err = fmt.Errorf("create dubbo reference panic: %v", r)
+			}
+		}()
+		entry.ref, entry.service, entry.err = factory()
+	}()
+	// 创建失败时移除缓存，下次请求重新创建
+	if nil != entry.err {
+		c.mu.Lock()
+		if c.entries[key] == entry {
+			delete(c.entries, key)
+		}
+		c.mu.Unlock()
+	}
+	close(entry.ready)
+	return entry.service, entry.err
+}
+
+// Evict 移除ServiceId关联的全部Reference缓存，并销毁Reference的Invoker
+func (c *ReferenceCache) Evict(serviceId string) {
+	c.mu.Lock()
+	evicted := make([]*referenceEntry, 0, len(c.keys[serviceId]))
+	for key := range c.keys[serviceId] {
+		if entry, ok := c.entries[key]; ok {
+			logger.Infow("DUBBO:GENERIC:EVICT", "service-id", serviceId, "reference", key)
+			delete(c.entries, key)
+			evicted = append(evicted, entry)
+		}
+	}
+	delete(c.keys, serviceId)
+	c.mu.Unlock()
+	for _, entry := range evicted {
+		<-entry.ready
+		if invoker := referenceInvoker(entry.ref); nil != invoker {
+			invoker.Destroy()
+		}
+	}
+}
+
+// Size 返回缓存的Reference数量
+func (c *ReferenceCache) Size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// referenceInvoker 返回Reference的Invoker；dubbo-go(v1.5.1)的ReferenceConfig未导出Invoker字段；
+func referenceInvoker(ref *dubgo.ReferenceConfig) protocol.Invoker {
+	if nil == ref {
+		return nil
+	}
+	field := reflect.ValueOf(ref).Elem().FieldByName("invoker")
+	if !field.IsValid() {
+		return nil
+	}
+	invoker, _ := reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem().Interface().(protocol.Invoker)
+	return invoker
+}
+
+// WaitReferenceReady 等待Reference对应的服务提供者可用，或者等待超时
+func WaitReferenceReady(ref *dubgo.ReferenceConfig, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if IsReferenceReady(ref) {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		<-time.After(referenceReadyInterval)
+	}
+}
+
+// IsReferenceReady 判断Reference对应的服务提供者是否存在可用的Invoker
+func IsReferenceReady(ref *dubgo.ReferenceConfig) bool {
+	return HasAvailableInvoker(ref.Protocol, ref.InterfaceName, ref.Group, ref.Version)
+}
+
+// HasAvailableInvoker 判断指定协议中，是否存在匹配 interface+group+version 的可用Invoker
+func HasAvailableInvoker(protoName, iface, group, version string) bool {
+	proto, ok := extension.GetProtocol(protoName).(interface {
+		Invokers() []protocol.Invoker
+	})
+	if !ok {
+		return true
+	}
+	for _, invoker := range proto.Invokers() {
+		if nil == invoker || !invoker.IsAvailable() {
+			continue
+		}
+		url := invoker.GetUrl()
+		if url.Service() == iface &&
+			url.GetParam(constant.GROUP_KEY, "") == group &&
+			url.GetParam(constant.VERSION_KEY, "") == version {
+			return true
+		}
+	}
+	return false
+}
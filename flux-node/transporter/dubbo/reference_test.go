@@ -0,0 +1,154 @@
+package dubbo
+
+import (
+	"context"
+	"errors"
+	"github.com/apache/dubbo-go/common"
+	"github.com/apache/dubbo-go/common/constant"
+	"github.com/apache/dubbo-go/common/extension"
+	dubgo "github.com/apache/dubbo-go/config"
+	"github.com/apache/dubbo-go/protocol"
+	"github.com/bytepowered/flux/flux-node"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/url"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+const testProtocol = "fluxtest"
+
+var testProto = &mockProtocol{BaseProtocol: protocol.NewBaseProtocol()}
+
+func init() {
+	extension.SetProtocol(testProtocol, func() protocol.Protocol {
+		return testProto
+	})
+}
+
+// mockProtocol 并发安全的Invoker列表
+type mockProtocol struct {
+	protocol.BaseProtocol
+	invokers []protocol.Invoker
+	mu       sync.Mutex
+}
+
+func (p *mockProtocol) SetInvokers(invoker protocol.Invoker) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.invokers = append(p.invokers, invoker)
+}
+
+func (p *mockProtocol) Invokers() []protocol.Invoker {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]protocol.Invoker{}, p.invokers...)
+}
+
+func newTestInvoker(iface, group, version string) protocol.Invoker {
+	params := url.Values{}
+	params.Set(constant.INTERFACE_KEY, iface)
+	params.Set(constant.GROUP_KEY, group)
+	params.Set(constant.VERSION_KEY, version)
+	u := common.NewURLWithOptions(common.WithProtocol(testProtocol), common.WithPath(iface), common.WithParams(params))
+	return protocol.NewBaseInvoker(*u)
+}
+
+func newTestReference(iface string, invoker protocol.Invoker) *dubgo.ReferenceConfig {
+	ref := dubgo.NewReferenceConfig(iface, context.Background())
+	ref.Protocol = testProtocol
+	ref.InterfaceName = iface
+	if nil != invoker {
+		field := reflect.ValueOf(ref).Elem().FieldByName("invoker")
+		reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem().Set(reflect.ValueOf(invoker))
+	}
+	return ref
+}
+
+func TestReferenceCache_Load(t *testing.T) {
+	assert := assert2.New(t)
+	cache := NewReferenceCache()
+	service := &flux.TransporterService{ServiceId: "s1", Interface: "net.bytepowered.Foo"}
+	var created int32
+	wg := new(sync.WaitGroup)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			srv, err := cache.Load(service, func() (*dubgo.ReferenceConfig, common.RPCService, error) {
+				atomic.AddInt32(&created, 1)
+				time.Sleep(10 * time.Millisecond)
+				return nil, dubgo.NewGenericService(service.Interface), nil
+			})
+			assert.NoError(err)
+			assert.NotNil(srv)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(int32(1), created)
+	assert.Equal(1, cache.Size())
+	// 创建失败或者异常，不缓存
+	failed := &flux.TransporterService{ServiceId: "s2", Interface: "net.bytepowered.Bar"}
+	_, err := cache.Load(failed, func() (*dubgo.ReferenceConfig, common.RPCService, error) {
+		return nil, nil, errors.New("create failed")
+	})
+	assert.Error(err)
+	_, err = cache.Load(failed, func() (*dubgo.ReferenceConfig, common.RPCService, error) {
+		panic("create panic")
+	})
+	assert.Error(err)
+	assert.Equal(1, cache.Size())
+}
+
+func TestReferenceCache_Evict(t *testing.T) {
+	assert := assert2.New(t)
+	cache := NewReferenceCache()
+	invoker := newTestInvoker("net.bytepowered.Foo", "", "")
+	service := &flux.TransporterService{ServiceId: "s1", AliasId: "a1", Interface: "net.bytepowered.Foo"}
+	_, err := cache.Load(service, func() (*dubgo.ReferenceConfig, common.RPCService, error) {
+		return newTestReference(service.Interface, invoker), dubgo.NewGenericService(service.Interface), nil
+	})
+	assert.NoError(err)
+	assert.Equal(1, cache.Size())
+	cache.Evict("unknown")
+	assert.Equal(1, cache.Size())
+	assert.True(invoker.IsAvailable())
+	cache.Evict("a1")
+	assert.Equal(0, cache.Size())
+	assert.False(invoker.IsAvailable())
+	assert.True(invoker.(*protocol.BaseInvoker).IsDestroyed())
+}
+
+func TestWaitReferenceReady(t *testing.T) {
+	assert := assert2.New(t)
+	ref := newTestReference("net.bytepowered.Ready", nil)
+	ref.Group, ref.Version = "g1", "1.0"
+	assert.False(WaitReferenceReady(ref, 30*time.Millisecond))
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		// 不匹配分组的Invoker
+		testProto.SetInvokers(newTestInvoker("net.bytepowered.Ready", "g2", "1.0"))
+		testProto.SetInvokers(newTestInvoker("net.bytepowered.Ready", "g1", "1.0"))
+	}()
+	assert.True(WaitReferenceReady(ref, time.Second))
+	assert.False(HasAvailableInvoker(testProtocol, "net.bytepowered.Ready", "g3", "1.0"))
+}
+
+func TestRpcTransporter_InitReferenceDelay(t *testing.T) {
+	assert := assert2.New(t)
+	for _, tc := range []struct {
+		config  map[string]interface{}
+		timeout time.Duration
+	}{
+		{config: map[string]interface{}{}, timeout: 3 * time.Second},
+		{config: map[string]interface{}{ConfigKeyReferenceDelay: "50ms"}, timeout: 50 * time.Millisecond},
+		{config: map[string]interface{}{ConfigKeyReferenceDelay: "50ms", ConfigKeyReferenceReadyTimeout: "1s"}, timeout: time.Second},
+	} {
+		tr := NewTransporter().(*RpcTransporter)
+		assert.NoError(tr.Init(flux.NewConfigurationOfMap(tc.config)))
+		assert.Equal(tc.timeout, tr.configuration.GetDuration(ConfigKeyReferenceReadyTimeout))
+	}
+}
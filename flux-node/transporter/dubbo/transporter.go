@@ -7,7 +7,6 @@ import (
 	"github.com/bytepowered/flux/flux-node"
 	jsoniter "github.com/json-iterator/go"
 	"reflect"
-	"sync"
 	"time"
 )
 
@@ -34,8 +33,7 @@ import (
 )
 
 const (
-	ConfigKeyTraceEnable    = "trace_enable"
-	ConfigKeyReferenceDelay = "reference_delay"
+	ConfigKeyTraceEnable = "trace_enable"
 )
 
 func init() {
@@ -48,8 +46,9 @@ var (
 )
 
 var (
-	_     flux.Transporter = new(RpcTransporter)
-	_json                  = jsoniter.ConfigCompatibleWithStandardLibrary
+	_     flux.Transporter          = new(RpcTransporter)
+	_     flux.ServiceEventListener = new(RpcTransporter)
+	_json                           = jsoniter.ConfigCompatibleWithStandardLibrary
 )
 
 type (
@@ -85,7 +84,8 @@ type RpcTransporter struct {
 	// 内部私有
 	trace         bool
 	configuration *flux.Configuration
-	servmx        sync.RWMutex
+	references    *ReferenceCache
+	lane          LaneConfig
 }
 
 // WithArgumentResolver 用于配置Dubbo参数封装实现函数
@@ -154,7 +154,8 @@ func WithDefaults(defaults map[string]interface{}) Option {
 // NewTransporterWith New dubbo transporter service with optionsf
 func NewTransporterWith(opts ...Option) flux.Transporter {
 	bts := &RpcTransporter{
-		optionsf: make([]GenericOptionsFunc, 0),
+		optionsf:   make([]GenericOptionsFunc, 0),
+		references: NewReferenceCache(),
 	}
 	for _, opt := range opts {
 		opt(bts)
@@ -180,13 +181,18 @@ func NewTransporterOverride(overrides ...Option) flux.Transporter {
 			"password": "dubbo.registry.password",
 		}),
 		WithDefaults(map[string]interface{}{
-			ConfigKeyReferenceDelay: time.Millisecond * 10,
-			ConfigKeyTraceEnable:    false,
-			"timeout":               "5000",
-			"retries":               "0",
-			"cluster":               "failover",
-			"load_balance":          "random",
-			"protocol":              dubbo.DUBBO,
+			ConfigKeyReferenceReadyTimeout: time.Second * 3,
+			ConfigKeyTraceEnable:           false,
+			"timeout":                      "5000",
+			"retries":                      "0",
+			"cluster":                      "failover",
+			"load_balance":                 "random",
+			"protocol":                     dubbo.DUBBO,
+			ConfigKeyLaneEnable:            false,
+			ConfigKeyLaneHeader:            "X-Env-Tag",
+			ConfigKeyLaneMode:              LaneModeTag,
+			ConfigKeyLaneForce:             false,
+			ConfigKeyLaneReadyTimeout:      time.Millisecond * 500,
 		}),
 		WithGenericServiceFunc(func(service *flux.TransporterService) common.RPCService {
 			return dubgo.NewGenericService(service.Interface)
@@ -215,10 +221,16 @@ func (b *RpcTransporter) Writer() flux.TransportWriter {
 // Init init transporter
 func (b *RpcTransporter) Init(config *flux.Configuration) error {
 	logger.Info("Dubbo transporter transporter initializing")
+	// 兼容旧配置项
+	if !config.IsSet(ConfigKeyReferenceReadyTimeout) && config.IsSet(ConfigKeyReferenceDelay) {
+		config.Set(ConfigKeyReferenceReadyTimeout, config.Get(ConfigKeyReferenceDelay))
+	}
 	config.SetDefaults(b.defaults)
 	b.configuration = config
 	b.trace = config.GetBool(ConfigKeyTraceEnable)
 	logger.Infow("Dubbo transporter transporter request trace", "enable", b.trace)
+	// 响应头过滤规则
+	defaultHeaderFilter.Configure(config.GetStringSlice(ConfigKeyResponseHeadersAllow), config.GetStringSlice(ConfigKeyResponseHeadersDeny))
 	// Set default impl if not present
 	if nil == b.optionsf {
 		b.optionsf = make([]GenericOptionsFunc, 0)
@@ -226,6 +238,26 @@ func (b *RpcTransporter) Init(config *flux.Configuration) error {
 	if fluxpkg.IsNil(b.aresolver) {
 		b.aresolver = DefaultArgumentResolver
 	}
+	if fluxpkg.IsNil(b.tresolver) {
+		b.tresolver = DefaultAttachmentResolver
+	}
+	// 泳道路由：标签模式通过Attachment设置dubbo.tag；分组/版本模式通过Reference配置函数设置Group/Version；
+	b.lane = NewLaneConfig(config)
+	if b.lane.Enable {
+		switch b.lane.Mode {
+		case LaneModeTag:
+			b.tresolver = NewLaneAttachmentResolver(b.lane, b.tresolver)
+		case LaneModeGroup, LaneModeVersion:
+			// 每个泳道创建独立的Reference，必须限定泳道标识
+			if len(b.lane.Allow) == 0 {
+				return fmt.Errorf("dubbo lane mode: %s, require config: %s", b.lane.Mode, ConfigKeyLaneAllow)
+			}
+			b.optionsf = append(b.optionsf, NewLaneOptionsFunc(b.lane))
+		default:
+			return fmt.Errorf("unknown dubbo lane mode: %s", b.lane.Mode)
+		}
+		logger.Infow("Dubbo transporter lane routing", "header", b.lane.Header, "cookie", b.lane.Cookie, "mode", b.lane.Mode)
+	}
 	// 修改默认Consumer配置
 	consumerc := dubgo.GetConsumerConfig()
 	// 支持定义Registry
@@ -250,6 +282,17 @@ func (b *RpcTransporter) Shutdown(_ context.Context) error {
 	return nil
 }
 
+// OnServiceEvent 服务定义更新或者删除时，移除缓存的DubboReference
+func (b *RpcTransporter) OnServiceEvent(event flux.ServiceEvent) {
+	if flux.EventTypeAdded == event.EventType {
+		return
+	}
+	b.references.Evict(event.Service.ServiceId)
+	if "" != event.Service.AliasId {
+		b.references.Evict(event.Service.AliasId)
+	}
+}
+
 // Transport do exchange with context
 func (b *RpcTransporter) Transport(ctx *flux.Context) {
 	transporter.DoTransport(ctx, b)
@@ -316,7 +359,27 @@ func (b *RpcTransporter) DoInvoke(types []string, values interface{}, service fl
 		logger.TraceContext(ctx).Infow("TRANSPORTER:DUBBO:INVOKE",
 			"transporter-service", service.ServiceID(), "arg-values", values, "arg-types", types, "attrs", att)
 	}
-	generic := b.LoadGenericService(&service)
+	if hashKey, err := LookupHashKey(ctx, service); nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageDubboAssembleFailed,
+			CauseError: fmt.Errorf("lookup hash key, err: %w", err),
+		}
+	} else if "" != hashKey {
+		att = withAttachment(att, AttachmentKeyHashKey, hashKey)
+	}
+	generic, err := b.LoadLaneService(ctx, &service)
+	if nil != err {
+		logger.TraceContext(ctx).Errorw("TRANSPORTER:DUBBO:REFERENCE",
+			"transporter-service", service.ServiceID(), "error", err)
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageDubboInvokeFailed,
+			CauseError: err,
+		}
+	}
 	goctx := context.WithValue(ctx.Context(), constant.AttachmentKey, att)
 	resultW := b.invokef(goctx, []interface{}{service.Method, types, values}, generic)
 	if cause := resultW.Error(); cause != nil {
@@ -342,33 +405,71 @@ func (b *RpcTransporter) DoInvoke(types []string, values interface{}, service fl
 	}
 }
 
-// LoadGenericService create and cache dubbo generic service
-func (b *RpcTransporter) LoadGenericService(service *flux.TransporterService) common.RPCService {
-	b.servmx.Lock()
-	defer b.servmx.Unlock()
-	if srv := dubgo.GetConsumerService(service.Interface); nil != srv {
-		return srv
-	}
-	newRef := NewReference(service.Interface, service, b.configuration)
-	// Options
-	const msg = "Dubbo option-func return nil reference"
-	for _, optsFunc := range b.optionsf {
-		if nil != optsFunc {
-			newRef = fluxpkg.MustNotNil(optsFunc(service, b.configuration, newRef), msg).(*dubgo.ReferenceConfig)
+// LoadLaneService 加载请求泳道对应的服务；泳道不存在可用的服务提供者时，回退到基础泳道的服务；
+func (b *RpcTransporter) LoadLaneService(ctx *flux.Context, service *flux.TransporterService) (common.RPCService, error) {
+	if lane := b.lane.LaneOf(ctx); "" != lane && LaneModeTag != b.lane.Mode {
+		laneService := LaneServiceOf(*service, lane)
+		srv, err := b.LoadGenericService(&laneService)
+		if nil == err && (b.lane.Force || b.isLaneAvailable(&laneService, lane)) {
+			return srv, nil
+		}
+		logger.TraceContext(ctx).Infow("DUBBO:LANE:FALLBACK", "transporter-service", service.ServiceID(), "lane", lane, "error", err)
+	}
+	return b.LoadGenericService(service)
+}
+
+func (b *RpcTransporter) isLaneAvailable(service *flux.TransporterService, lane string) bool {
+	group, version := service.RpcGroup(), service.RpcVersion()
+	if LaneModeGroup == b.lane.Mode {
+		group = lane
+	} else {
+		version = lane
+	}
+	return HasAvailableInvoker(b.configuration.GetString("protocol"), service.Interface, group, version)
+}
+
+// LoadGenericService create and cache dubbo generic service；
+// 按 interface+group+version+remoteHost 缓存，同一Reference并发请求只创建一次；
+func (b *RpcTransporter) LoadGenericService(service *flux.TransporterService) (common.RPCService, error) {
+	return b.references.Load(service, func() (*dubgo.ReferenceConfig, common.RPCService, error) {
+		newRef := NewReference(service.Interface, service, b.configuration)
+		// Options
+		for _, optsFunc := range b.optionsf {
+			if nil != optsFunc {
+				if newRef = optsFunc(service, b.configuration, newRef); nil == newRef {
+					return nil, nil, errors.New("dubbo option-func return nil reference")
+				}
+			}
+		}
+		logger.Infow("DUBBO:GENERIC:CREATE: PREPARE", "reference", ReferenceKey(service))
+		srv := b.servicef(service)
+		newRef.Refer(srv)
+		newRef.Implement(srv)
+		timeout := b.configuration.GetDuration(ConfigKeyReferenceReadyTimeout)
+		if service.HasAttr(ServiceAttrTagLane) {
+			timeout = b.lane.ReadyTimeout
+		}
+		if !WaitReferenceReady(newRef, timeout) {
+			logger.Warnw("DUBBO:GENERIC:CREATE: NOT_READY", "reference", ReferenceKey(service), "timeout", timeout)
 		}
+		logger.Infow("DUBBO:GENERIC:CREATE: OJBK", "reference", ReferenceKey(service))
+		return newRef, srv, nil
+	})
+}
+
+func withAttachment(att interface{}, key, value string) interface{} {
+	switch m := att.(type) {
+	case map[string]string:
+		m[key] = value
+		return m
+	case map[string]interface{}:
+		m[key] = value
+		return m
+	case nil:
+		return map[string]string{key: value}
+	default:
+		return att
 	}
-	logger.Infow("DUBBO:GENERIC:CREATE: PREPARE", "interface", service.Interface)
-	srv := b.servicef(service)
-	dubgo.SetConsumerService(srv)
-	newRef.Refer(srv)
-	newRef.Implement(srv)
-	t := b.configuration.GetDuration(ConfigKeyReferenceDelay)
-	if t == 0 {
-		t = time.Millisecond * 10
-	}
-	<-time.After(t)
-	logger.Infow("DUBBO:GENERIC:CREATE: OJBK", "interface", service.Interface)
-	return srv
 }
 
 func newConsumerRegistry(config *flux.Configuration) (string, *dubgo.RegistryConfig) {
@@ -405,6 +506,13 @@ func NewReference(refid string, service *flux.TransporterService, config *flux.C
 	ref.Cluster = config.GetString("cluster")
 	ref.Protocol = config.GetString("protocol")
 	ref.Loadbalance = config.GetString("load_balance")
+	// 服务属性定义的集群和负载均衡策略
+	if attr, ok := service.GetAttrEx(ServiceAttrTagCluster); ok {
+		ref.Cluster = attr.GetString()
+	}
+	if attr, ok := service.GetAttrEx(ServiceAttrTagLoadBalance); ok {
+		ref.Loadbalance = attr.GetString()
+	}
 	ref.Generic = true
 	return ref
 }
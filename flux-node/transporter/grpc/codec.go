@@ -0,0 +1,60 @@
+package grpc
+
+import (
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
+	"net/http"
+	"strings"
+)
+
+const (
+	HeaderPrefixMetadata = "Grpc-Metadata-"
+	HeaderPrefixTrailer  = "Grpc-Trailer-"
+)
+
+// Response gRPC调用解码后的响应结果
+type Response struct {
+	Header  http.Header            // 响应头部Metadata
+	Trailer http.Header            // 响应尾部Metadata
+	Body    map[string]interface{} // 响应消息
+}
+
+func NewTransportCodecFunc() flux.TransportCodec {
+	return func(ctx *flux.Context, raw interface{}) (*flux.ResponseBody, error) {
+		resp, ok := raw.(*Response)
+		if !ok {
+			return nil, fmt.Errorf("unexpected grpc response type: %T", raw)
+		}
+		return &flux.ResponseBody{
+			StatusCode: flux.StatusOK,
+			Headers:    MetadataToHeader(resp.Header, resp.Trailer),
+			Body:       resp.Body,
+		}, nil
+	}
+}
+
+// MetadataToHeader 将gRPC响应的Metadata转换为Http响应Header；
+// 头部Metadata以 Grpc-Metadata- 为前缀，尾部Metadata以 Grpc-Trailer- 为前缀；
+func MetadataToHeader(header, trailer http.Header) http.Header {
+	out := make(http.Header, len(header)+len(trailer))
+	copyMetadata(out, header, HeaderPrefixMetadata)
+	copyMetadata(out, trailer, HeaderPrefixTrailer)
+	return out
+}
+
+func copyMetadata(dst, src http.Header, prefix string) {
+	for k, vs := range src {
+		if isReservedMetadata(k) {
+			continue
+		}
+		for _, v := range vs {
+			dst.Add(prefix+k, v)
+		}
+	}
+}
+
+func isReservedMetadata(key string) bool {
+	key = strings.ToLower(key)
+	return strings.HasPrefix(key, "grpc-") || key == headerContentType || key == "te" ||
+		key == "content-length" || key == "date" || key == "trailer"
+}
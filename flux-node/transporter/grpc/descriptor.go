@@ -0,0 +1,155 @@
+package grpc
+
+import (
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+// MethodDescriptor 描述gRPC服务方法的请求与响应消息结构
+type MethodDescriptor struct {
+	FullPath string                      // 调用路径：/package.Service/Method
+	Input    *descriptor.DescriptorProto // 请求消息结构
+	Output   *descriptor.DescriptorProto // 响应消息结构
+	Streams  bool                        // 是否为流式方法
+}
+
+// Descriptors 缓存已加载的Protobuf文件描述，并提供Service/Message/Enum的全名索引
+type Descriptors struct {
+	files    map[string]*descriptor.FileDescriptorProto
+	services map[string]*descriptor.ServiceDescriptorProto
+	messages map[string]*descriptor.DescriptorProto
+	enums    map[string]*descriptor.EnumDescriptorProto
+	mu       sync.RWMutex
+}
+
+func NewDescriptors() *Descriptors {
+	return &Descriptors{
+		files:    make(map[string]*descriptor.FileDescriptorProto, 16),
+		services: make(map[string]*descriptor.ServiceDescriptorProto, 16),
+		messages: make(map[string]*descriptor.DescriptorProto, 64),
+		enums:    make(map[string]*descriptor.EnumDescriptorProto, 16),
+	}
+}
+
+// LoadDescriptorSetFile 加载由 protoc --descriptor_set_out 生成的FileDescriptorSet文件
+func (d *Descriptors) LoadDescriptorSetFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if nil != err {
+		return fmt.Errorf("read descriptor set, path: %s, error: %w", path, err)
+	}
+	set := new(descriptor.FileDescriptorSet)
+	if err := proto.Unmarshal(data, set); nil != err {
+		return fmt.Errorf("decode descriptor set, path: %s, error: %w", path, err)
+	}
+	for _, file := range set.GetFile() {
+		d.AddFile(file)
+	}
+	return nil
+}
+
+// AddFileBytes 添加序列化的FileDescriptorProto数据
+func (d *Descriptors) AddFileBytes(data []byte) (*descriptor.FileDescriptorProto, error) {
+	file := new(descriptor.FileDescriptorProto)
+	if err := proto.Unmarshal(data, file); nil != err {
+		return nil, fmt.Errorf("decode file descriptor, error: %w", err)
+	}
+	d.AddFile(file)
+	return file, nil
+}
+
+// AddFile 添加FileDescriptorProto，并建立其Service/Message/Enum的全名索引
+func (d *Descriptors) AddFile(file *descriptor.FileDescriptorProto) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.files[file.GetName()] = file
+	prefix := ""
+	if pkg := file.GetPackage(); pkg != "" {
+		prefix = pkg + "."
+	}
+	for _, srv := range file.GetService() {
+		d.services[prefix+srv.GetName()] = srv
+	}
+	for _, msg := range file.GetMessageType() {
+		d.indexMessage(prefix, msg)
+	}
+	for _, enum := range file.GetEnumType() {
+		d.enums[prefix+enum.GetName()] = enum
+	}
+}
+
+func (d *Descriptors) indexMessage(prefix string, msg *descriptor.DescriptorProto) {
+	name := prefix + msg.GetName()
+	d.messages[name] = msg
+	for _, nested := range msg.GetNestedType() {
+		d.indexMessage(name+".", nested)
+	}
+	for _, enum := range msg.GetEnumType() {
+		d.enums[name+"."+enum.GetName()] = enum
+	}
+}
+
+// HasFile 判断指定文件名的描述是否已加载
+func (d *Descriptors) HasFile(name string) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	_, ok := d.files[name]
+	return ok
+}
+
+// HasService 判断指定全名的Service描述是否已加载
+func (d *Descriptors) HasService(serviceName string) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	_, ok := d.services[serviceName]
+	return ok
+}
+
+// Message 查找指定全名的Message结构；全名支持以'.'开头的Protobuf类型引用格式；
+func (d *Descriptors) Message(typeName string) (*descriptor.DescriptorProto, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	msg, ok := d.messages[strings.TrimPrefix(typeName, ".")]
+	return msg, ok
+}
+
+// Enum 查找指定全名的Enum结构；全名支持以'.'开头的Protobuf类型引用格式；
+func (d *Descriptors) Enum(typeName string) (*descriptor.EnumDescriptorProto, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	enum, ok := d.enums[strings.TrimPrefix(typeName, ".")]
+	return enum, ok
+}
+
+// Method 查找指定Service全名和方法名的方法描述
+func (d *Descriptors) Method(serviceName, methodName string) (*MethodDescriptor, error) {
+	d.mu.RLock()
+	srv, ok := d.services[serviceName]
+	d.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("service descriptor not found, service: %s", serviceName)
+	}
+	for _, m := range srv.GetMethod() {
+		if m.GetName() != methodName {
+			continue
+		}
+		input, ok := d.Message(m.GetInputType())
+		if !ok {
+			return nil, fmt.Errorf("input message descriptor not found, type: %s", m.GetInputType())
+		}
+		output, ok := d.Message(m.GetOutputType())
+		if !ok {
+			return nil, fmt.Errorf("output message descriptor not found, type: %s", m.GetOutputType())
+		}
+		return &MethodDescriptor{
+			FullPath: "/" + serviceName + "/" + methodName,
+			Input:    input,
+			Output:   output,
+			Streams:  m.GetClientStreaming() || m.GetServerStreaming(),
+		}, nil
+	}
+	return nil, fmt.Errorf("method descriptor not found, service: %s, method: %s", serviceName, methodName)
+}
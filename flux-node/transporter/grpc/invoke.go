@@ -0,0 +1,171 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"golang.org/x/net/http2"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	headerContentType   = "content-type"
+	headerGrpcStatus    = "grpc-status"
+	headerGrpcMessage   = "grpc-message"
+	headerGrpcTimeout   = "grpc-timeout"
+	headerGrpcUserAgent = "user-agent"
+	contentTypeGrpc     = "application/grpc"
+	userAgentGrpc       = "flux-grpc/1.0"
+)
+
+var (
+	ErrCompressedMessage = errors.New("GRPC:COMPRESSED_MESSAGE_UNSUPPORTED")
+)
+
+// UnaryCall 描述一次gRPC一元调用请求
+type UnaryCall struct {
+	Host     string        // 服务地址：host:port
+	Path     string        // 调用路径：/package.Service/Method
+	Secure   bool          // 是否使用TLS连接
+	Timeout  time.Duration // 调用超时时间
+	Metadata http.Header   // 请求Metadata
+	Message  []byte        // 已编码的请求消息
+}
+
+// UnaryResult 描述一次gRPC一元调用的响应结果
+type UnaryResult struct {
+	Code    Code        // gRPC状态码
+	Message string      // gRPC状态消息
+	Header  http.Header // 响应头部Metadata
+	Trailer http.Header // 响应尾部Metadata
+	Body    []byte      // 响应消息；调用失败时为nil
+}
+
+// Client 基于HTTP/2实现的gRPC一元调用客户端，支持明文(h2c)和TLS连接
+type Client struct {
+	plain  *http2.Transport
+	secure *http2.Transport
+}
+
+func NewClient(tlsConfig *tls.Config) *Client {
+	return &Client{
+		plain: &http2.Transport{
+			AllowHTTP: true,
+			DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+				return net.Dial(network, addr)
+			},
+		},
+		secure: &http2.Transport{
+			TLSClientConfig: tlsConfig,
+		},
+	}
+}
+
+// Unary 执行gRPC一元调用。返回error表示传输层错误；gRPC状态错误由UnaryResult.Code表示；
+func (c *Client) Unary(ctx context.Context, call *UnaryCall) (*UnaryResult, error) {
+	scheme, roundTripper := "http", c.plain
+	if call.Secure {
+		scheme, roundTripper = "https", c.secure
+	}
+	if call.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, call.Timeout)
+		defer cancel()
+	}
+	frame := make([]byte, 5+len(call.Message))
+	binary.BigEndian.PutUint32(frame[1:5], uint32(len(call.Message)))
+	copy(frame[5:], call.Message)
+	req, err := http.NewRequest(http.MethodPost, scheme+"://"+call.Host+call.Path, bytes.NewReader(frame))
+	if nil != err {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for k, vs := range call.Metadata {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	req.Header.Set(headerContentType, contentTypeGrpc)
+	req.Header.Set(headerGrpcUserAgent, userAgentGrpc)
+	req.Header.Set("te", "trailers")
+	if deadline, ok := ctx.Deadline(); ok {
+		req.Header.Set(headerGrpcTimeout, encodeTimeout(time.Until(deadline)))
+	}
+	resp, err := roundTripper.RoundTrip(req)
+	if nil != err {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if nil != err {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &UnaryResult{
+			Code:    httpStatusToCode(resp.StatusCode),
+			Message: fmt.Sprintf("unexpected http status: %d", resp.StatusCode),
+			Header:  resp.Header,
+			Trailer: resp.Trailer,
+		}, nil
+	}
+	result := &UnaryResult{Header: resp.Header, Trailer: resp.Trailer}
+	// Trailers-Only 响应时，状态在Header中
+	status := resp.Trailer
+	if "" == status.Get(headerGrpcStatus) {
+		status = resp.Header
+	}
+	code, err := strconv.Atoi(status.Get(headerGrpcStatus))
+	if nil != err {
+		return nil, fmt.Errorf("invalid grpc-status: %s", status.Get(headerGrpcStatus))
+	}
+	result.Code = Code(code)
+	result.Message = decodeGrpcMessage(status.Get(headerGrpcMessage))
+	if result.Code != OK {
+		return result, nil
+	}
+	if len(data) < 5 {
+		return nil, ErrMalformedMessage
+	}
+	if data[0] != 0 {
+		return nil, ErrCompressedMessage
+	}
+	size := binary.BigEndian.Uint32(data[1:5])
+	if uint32(len(data)-5) < size {
+		return nil, ErrMalformedMessage
+	}
+	result.Body = data[5 : 5+size]
+	return result, nil
+}
+
+// encodeTimeout 按gRPC协议格式编码超时时间，最多8位数字
+func encodeTimeout(t time.Duration) string {
+	if t <= 0 {
+		return "1n"
+	}
+	if ms := t.Milliseconds(); ms < 1e8 {
+		if ms == 0 {
+			return strconv.FormatInt(t.Nanoseconds(), 10) + "n"
+		}
+		return strconv.FormatInt(ms, 10) + "m"
+	}
+	return strconv.FormatInt(int64(t/time.Second), 10) + "S"
+}
+
+func decodeGrpcMessage(msg string) string {
+	if !strings.Contains(msg, "%") {
+		return msg
+	}
+	if out, err := url.PathUnescape(msg); nil == err {
+		return out
+	}
+	return msg
+}
@@ -0,0 +1,147 @@
+package grpc
+
+import (
+	"context"
+	"encoding/binary"
+	assert2 "github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestFrame(flag byte, message []byte) []byte {
+	frame := make([]byte, 5+len(message))
+	frame[0] = flag
+	binary.BigEndian.PutUint32(frame[1:5], uint32(len(message)))
+	copy(frame[5:], message)
+	return frame
+}
+
+func newTestServer(handler http.HandlerFunc) (*httptest.Server, string) {
+	server := httptest.NewServer(h2c.NewHandler(handler, &http2.Server{}))
+	return server, strings.TrimPrefix(server.URL, "http://")
+}
+
+func TestClient_UnaryFraming(t *testing.T) {
+	assert := assert2.New(t)
+	var request *http.Request
+	var received []byte
+	server, host := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		request = r
+		received, _ = ioutil.ReadAll(r.Body)
+		w.Header().Set("Trailer", "grpc-status, grpc-message, x-trailer")
+		w.Header().Set("content-type", contentTypeGrpc)
+		w.Header().Set("x-header", "h")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(newTestFrame(0, []byte("pong")))
+		w.Header().Set("grpc-status", "0")
+		w.Header().Set("x-trailer", "t")
+	})
+	defer server.Close()
+	result, err := NewClient(nil).Unary(context.Background(), &UnaryCall{
+		Host:     host,
+		Path:     "/test.Service/Ping",
+		Timeout:  time.Second,
+		Metadata: http.Header{"X-Meta": []string{"m"}},
+		Message:  []byte("ping"),
+	})
+	assert.NoError(err)
+	// 请求帧
+	assert.Equal("HTTP/2.0", request.Proto)
+	assert.Equal(http.MethodPost, request.Method)
+	assert.Equal("/test.Service/Ping", request.URL.Path)
+	assert.Equal(contentTypeGrpc, request.Header.Get(headerContentType))
+	assert.Equal("trailers", request.Header.Get("te"))
+	assert.Equal(userAgentGrpc, request.Header.Get(headerGrpcUserAgent))
+	assert.Equal("m", request.Header.Get("x-meta"))
+	assert.True(strings.HasSuffix(request.Header.Get(headerGrpcTimeout), "m"))
+	assert.Equal(newTestFrame(0, []byte("ping")), received)
+	// 响应帧
+	assert.Equal(OK, result.Code)
+	assert.Equal([]byte("pong"), result.Body)
+	assert.Equal("h", result.Header.Get("x-header"))
+	assert.Equal("t", result.Trailer.Get("x-trailer"))
+}
+
+func TestClient_UnaryErrorStatus(t *testing.T) {
+	assert := assert2.New(t)
+	server, host := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = ioutil.ReadAll(r.Body)
+		w.Header().Set("Trailer", "grpc-status, grpc-message")
+		w.WriteHeader(http.StatusOK)
+		w.Header().Set("grpc-status", "5")
+		w.Header().Set("grpc-message", "user%20not%20found%3A%20%E4%BD%A0%E5%A5%BD")
+	})
+	defer server.Close()
+	result, err := NewClient(nil).Unary(context.Background(), &UnaryCall{Host: host, Path: "/test.Service/Get"})
+	assert.NoError(err)
+	assert.Equal(NotFound, result.Code)
+	assert.Equal("user not found: 你好", result.Message)
+	assert.Nil(result.Body)
+}
+
+func TestClient_UnaryTrailersOnly(t *testing.T) {
+	assert := assert2.New(t)
+	server, host := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = ioutil.ReadAll(r.Body)
+		w.Header().Set("content-type", contentTypeGrpc)
+		w.Header().Set("grpc-status", "14")
+		w.Header().Set("grpc-message", "unavailable")
+		w.WriteHeader(http.StatusOK)
+	})
+	defer server.Close()
+	result, err := NewClient(nil).Unary(context.Background(), &UnaryCall{Host: host, Path: "/test.Service/Get"})
+	assert.NoError(err)
+	assert.Equal(Unavailable, result.Code)
+	assert.Equal("unavailable", result.Message)
+}
+
+func TestClient_UnaryHttpStatus(t *testing.T) {
+	assert := assert2.New(t)
+	server, host := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	defer server.Close()
+	result, err := NewClient(nil).Unary(context.Background(), &UnaryCall{Host: host, Path: "/test.Service/Get"})
+	assert.NoError(err)
+	assert.Equal(Unavailable, result.Code)
+}
+
+func TestClient_UnaryMalformed(t *testing.T) {
+	cases := []struct {
+		body  []byte
+		error error
+	}{
+		{body: newTestFrame(1, []byte("gzip")), error: ErrCompressedMessage},
+		{body: []byte{0, 0, 0}, error: ErrMalformedMessage},
+		{body: newTestFrame(0, []byte("pong"))[:7], error: ErrMalformedMessage},
+	}
+	for _, tcase := range cases {
+		body := tcase.body
+		server, host := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = ioutil.ReadAll(r.Body)
+			w.Header().Set("Trailer", "grpc-status")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(body)
+			w.Header().Set("grpc-status", "0")
+		})
+		_, err := NewClient(nil).Unary(context.Background(), &UnaryCall{Host: host, Path: "/test.Service/Get"})
+		assert2.Equal(t, tcase.error, err)
+		server.Close()
+	}
+}
+
+func TestEncodeTimeout(t *testing.T) {
+	assert := assert2.New(t)
+	assert.Equal("1n", encodeTimeout(0))
+	assert.Equal("1n", encodeTimeout(-time.Second))
+	assert.Equal("500n", encodeTimeout(500*time.Nanosecond))
+	assert.Equal("1500m", encodeTimeout(1500*time.Millisecond))
+	assert.Equal("99999999m", encodeTimeout(99999999*time.Millisecond))
+	assert.Equal("100000S", encodeTimeout(1e8*time.Millisecond))
+}
@@ -0,0 +1,418 @@
+package grpc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/spf13/cast"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// Protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var (
+	ErrMalformedMessage = errors.New("GRPC:MALFORMED_MESSAGE")
+)
+
+// Marshal 根据Message描述，将键值对编码为Protobuf二进制数据。
+// 键值对的Key可以为字段名或者字段的JsonName；未定义的Key将被忽略；
+func (d *Descriptors) Marshal(msg *descriptor.DescriptorProto, values map[string]interface{}) ([]byte, error) {
+	buf := proto.NewBuffer(make([]byte, 0, 64))
+	for _, field := range msg.GetField() {
+		value, ok := values[field.GetName()]
+		if !ok {
+			value, ok = values[field.GetJsonName()]
+		}
+		if !ok || nil == value {
+			continue
+		}
+		if err := d.encodeField(buf, field, value); nil != err {
+			return nil, fmt.Errorf("encode field: %s, error: %w", field.GetName(), err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal 根据Message描述，将Protobuf二进制数据解码为键值对；键值对的Key为字段的JsonName；
+func (d *Descriptors) Unmarshal(msg *descriptor.DescriptorProto, data []byte) (map[string]interface{}, error) {
+	out := make(map[string]interface{}, len(msg.GetField()))
+	fields := make(map[int32]*descriptor.FieldDescriptorProto, len(msg.GetField()))
+	for _, f := range msg.GetField() {
+		fields[f.GetNumber()] = f
+	}
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, ErrMalformedMessage
+		}
+		data = data[n:]
+		number, wire := int32(tag>>3), int(tag&7)
+		raw, rest, err := readWireValue(data, wire)
+		if nil != err {
+			return nil, err
+		}
+		data = rest
+		field, ok := fields[number]
+		if !ok {
+			continue
+		}
+		if err := d.decodeField(out, field, wire, raw); nil != err {
+			return nil, fmt.Errorf("decode field: %s, error: %w", field.GetName(), err)
+		}
+	}
+	return out, nil
+}
+
+func (d *Descriptors) encodeField(buf *proto.Buffer, field *descriptor.FieldDescriptorProto, value interface{}) error {
+	// Map<K,V>
+	if entry, ok := d.mapEntryOf(field); ok {
+		kv, err := cast.ToStringMapE(value)
+		if nil != err {
+			return err
+		}
+		keys := make([]string, 0, len(kv))
+		for k := range kv {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			data, err := d.Marshal(entry, map[string]interface{}{"key": k, "value": kv[k]})
+			if nil != err {
+				return err
+			}
+			_ = buf.EncodeVarint(uint64(field.GetNumber())<<3 | wireBytes)
+			_ = buf.EncodeRawBytes(data)
+		}
+		return nil
+	}
+	if field.GetLabel() != descriptor.FieldDescriptorProto_LABEL_REPEATED {
+		return d.encodeValue(buf, field, value)
+	}
+	// Repeated: 使用非Packed格式编码，Protobuf解析器须同时支持Packed和非Packed格式
+	rv := reflect.ValueOf(value)
+	if _, isBytes := value.([]byte); isBytes || (rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array) {
+		return d.encodeValue(buf, field, value)
+	}
+	for i := 0; i < rv.Len(); i++ {
+		if err := d.encodeValue(buf, field, rv.Index(i).Interface()); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Descriptors) encodeValue(buf *proto.Buffer, field *descriptor.FieldDescriptorProto, value interface{}) error {
+	number := uint64(field.GetNumber()) << 3
+	switch field.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_DOUBLE:
+		v, err := cast.ToFloat64E(value)
+		if nil != err {
+			return err
+		}
+		_ = buf.EncodeVarint(number | wireFixed64)
+		return buf.EncodeFixed64(math.Float64bits(v))
+	case descriptor.FieldDescriptorProto_TYPE_FLOAT:
+		v, err := cast.ToFloat32E(value)
+		if nil != err {
+			return err
+		}
+		_ = buf.EncodeVarint(number | wireFixed32)
+		return buf.EncodeFixed32(uint64(math.Float32bits(v)))
+	case descriptor.FieldDescriptorProto_TYPE_INT64, descriptor.FieldDescriptorProto_TYPE_INT32:
+		v, err := cast.ToInt64E(value)
+		if nil != err {
+			return err
+		}
+		_ = buf.EncodeVarint(number | wireVarint)
+		return buf.EncodeVarint(uint64(v))
+	case descriptor.FieldDescriptorProto_TYPE_UINT64, descriptor.FieldDescriptorProto_TYPE_UINT32:
+		v, err := cast.ToUint64E(value)
+		if nil != err {
+			return err
+		}
+		_ = buf.EncodeVarint(number | wireVarint)
+		return buf.EncodeVarint(v)
+	case descriptor.FieldDescriptorProto_TYPE_SINT32, descriptor.FieldDescriptorProto_TYPE_SINT64:
+		v, err := cast.ToInt64E(value)
+		if nil != err {
+			return err
+		}
+		_ = buf.EncodeVarint(number | wireVarint)
+		return buf.EncodeZigzag64(uint64(v))
+	case descriptor.FieldDescriptorProto_TYPE_FIXED64, descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+		v, err := cast.ToInt64E(value)
+		if nil != err {
+			return err
+		}
+		_ = buf.EncodeVarint(number | wireFixed64)
+		return buf.EncodeFixed64(uint64(v))
+	case descriptor.FieldDescriptorProto_TYPE_FIXED32, descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+		v, err := cast.ToInt64E(value)
+		if nil != err {
+			return err
+		}
+		_ = buf.EncodeVarint(number | wireFixed32)
+		return buf.EncodeFixed32(uint64(uint32(v)))
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		v, err := cast.ToBoolE(value)
+		if nil != err {
+			return err
+		}
+		_ = buf.EncodeVarint(number | wireVarint)
+		if v {
+			return buf.EncodeVarint(1)
+		}
+		return buf.EncodeVarint(0)
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		v, err := d.enumNumber(field.GetTypeName(), value)
+		if nil != err {
+			return err
+		}
+		_ = buf.EncodeVarint(number | wireVarint)
+		return buf.EncodeVarint(uint64(v))
+	case descriptor.FieldDescriptorProto_TYPE_STRING:
+		v, err := cast.ToStringE(value)
+		if nil != err {
+			return err
+		}
+		_ = buf.EncodeVarint(number | wireBytes)
+		return buf.EncodeStringBytes(v)
+	case descriptor.FieldDescriptorProto_TYPE_BYTES:
+		var v []byte
+		if bs, ok := value.([]byte); ok {
+			v = bs
+		} else if str, err := cast.ToStringE(value); nil == err {
+			v = []byte(str)
+		} else {
+			return err
+		}
+		_ = buf.EncodeVarint(number | wireBytes)
+		return buf.EncodeRawBytes(v)
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE:
+		msg, ok := d.Message(field.GetTypeName())
+		if !ok {
+			return fmt.Errorf("message descriptor not found, type: %s", field.GetTypeName())
+		}
+		sm, err := cast.ToStringMapE(value)
+		if nil != err {
+			return err
+		}
+		data, err := d.Marshal(msg, sm)
+		if nil != err {
+			return err
+		}
+		_ = buf.EncodeVarint(number | wireBytes)
+		return buf.EncodeRawBytes(data)
+	default:
+		return fmt.Errorf("unsupported field type: %s", field.GetType())
+	}
+}
+
+func (d *Descriptors) decodeField(out map[string]interface{}, field *descriptor.FieldDescriptorProto, wire int, raw []byte) error {
+	name := field.GetJsonName()
+	if "" == name {
+		name = field.GetName()
+	}
+	// Map<K,V>
+	if entry, ok := d.mapEntryOf(field); ok {
+		kv, err := d.Unmarshal(entry, raw)
+		if nil != err {
+			return err
+		}
+		m, _ := out[name].(map[string]interface{})
+		if nil == m {
+			m = make(map[string]interface{}, 4)
+			out[name] = m
+		}
+		m[cast.ToString(kv["key"])] = kv["value"]
+		return nil
+	}
+	if field.GetLabel() != descriptor.FieldDescriptorProto_LABEL_REPEATED {
+		v, err := d.decodeValue(field, wire, raw)
+		if nil != err {
+			return err
+		}
+		out[name] = v
+		return nil
+	}
+	list, _ := out[name].([]interface{})
+	// Packed repeated scalar
+	if wire == wireBytes && isPackable(field.GetType()) {
+		elemWire := packedWireType(field.GetType())
+		for len(raw) > 0 {
+			elem, rest, err := readWireValue(raw, elemWire)
+			if nil != err {
+				return err
+			}
+			raw = rest
+			v, err := d.decodeValue(field, elemWire, elem)
+			if nil != err {
+				return err
+			}
+			list = append(list, v)
+		}
+	} else {
+		v, err := d.decodeValue(field, wire, raw)
+		if nil != err {
+			return err
+		}
+		list = append(list, v)
+	}
+	out[name] = list
+	return nil
+}
+
+func (d *Descriptors) decodeValue(field *descriptor.FieldDescriptorProto, wire int, raw []byte) (interface{}, error) {
+	switch field.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_STRING:
+		return string(raw), nil
+	case descriptor.FieldDescriptorProto_TYPE_BYTES:
+		return raw, nil
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE:
+		msg, ok := d.Message(field.GetTypeName())
+		if !ok {
+			return nil, fmt.Errorf("message descriptor not found, type: %s", field.GetTypeName())
+		}
+		return d.Unmarshal(msg, raw)
+	}
+	var x uint64
+	switch wire {
+	case wireVarint:
+		v, n := binary.Uvarint(raw)
+		if n <= 0 {
+			return nil, ErrMalformedMessage
+		}
+		x = v
+	case wireFixed64:
+		x = binary.LittleEndian.Uint64(raw)
+	case wireFixed32:
+		x = uint64(binary.LittleEndian.Uint32(raw))
+	default:
+		return nil, fmt.Errorf("unexpected wire type: %d, field type: %s", wire, field.GetType())
+	}
+	switch field.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_DOUBLE:
+		return math.Float64frombits(x), nil
+	case descriptor.FieldDescriptorProto_TYPE_FLOAT:
+		return math.Float32frombits(uint32(x)), nil
+	case descriptor.FieldDescriptorProto_TYPE_INT64, descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+		return int64(x), nil
+	case descriptor.FieldDescriptorProto_TYPE_INT32:
+		return int32(x), nil
+	case descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+		return int32(uint32(x)), nil
+	case descriptor.FieldDescriptorProto_TYPE_UINT64, descriptor.FieldDescriptorProto_TYPE_FIXED64:
+		return x, nil
+	case descriptor.FieldDescriptorProto_TYPE_UINT32, descriptor.FieldDescriptorProto_TYPE_FIXED32:
+		return uint32(x), nil
+	case descriptor.FieldDescriptorProto_TYPE_SINT32:
+		return int32(uint32(x>>1) ^ -uint32(x&1)), nil
+	case descriptor.FieldDescriptorProto_TYPE_SINT64:
+		return int64(x>>1) ^ -int64(x&1), nil
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		return x != 0, nil
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		return d.enumName(field.GetTypeName(), int32(x)), nil
+	default:
+		return nil, fmt.Errorf("unsupported field type: %s", field.GetType())
+	}
+}
+
+func (d *Descriptors) mapEntryOf(field *descriptor.FieldDescriptorProto) (*descriptor.DescriptorProto, bool) {
+	if field.GetType() != descriptor.FieldDescriptorProto_TYPE_MESSAGE ||
+		field.GetLabel() != descriptor.FieldDescriptorProto_LABEL_REPEATED {
+		return nil, false
+	}
+	msg, ok := d.Message(field.GetTypeName())
+	if !ok || !msg.GetOptions().GetMapEntry() {
+		return nil, false
+	}
+	return msg, true
+}
+
+func (d *Descriptors) enumNumber(typeName string, value interface{}) (int32, error) {
+	if name, ok := value.(string); ok {
+		if enum, ok := d.Enum(typeName); ok {
+			for _, v := range enum.GetValue() {
+				if v.GetName() == name {
+					return v.GetNumber(), nil
+				}
+			}
+		}
+	}
+	return cast.ToInt32E(value)
+}
+
+func (d *Descriptors) enumName(typeName string, number int32) interface{} {
+	if enum, ok := d.Enum(typeName); ok {
+		for _, v := range enum.GetValue() {
+			if v.GetNumber() == number {
+				return v.GetName()
+			}
+		}
+	}
+	return number
+}
+
+func readWireValue(data []byte, wire int) (value []byte, rest []byte, err error) {
+	switch wire {
+	case wireVarint:
+		_, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, nil, ErrMalformedMessage
+		}
+		return data[:n], data[n:], nil
+	case wireFixed64:
+		if len(data) < 8 {
+			return nil, nil, ErrMalformedMessage
+		}
+		return data[:8], data[8:], nil
+	case wireFixed32:
+		if len(data) < 4 {
+			return nil, nil, ErrMalformedMessage
+		}
+		return data[:4], data[4:], nil
+	case wireBytes:
+		size, n := binary.Uvarint(data)
+		if n <= 0 || uint64(len(data)-n) < size {
+			return nil, nil, ErrMalformedMessage
+		}
+		end := n + int(size)
+		return data[n:end], data[end:], nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported wire type: %d", wire)
+	}
+}
+
+func isPackable(t descriptor.FieldDescriptorProto_Type) bool {
+	switch t {
+	case descriptor.FieldDescriptorProto_TYPE_STRING, descriptor.FieldDescriptorProto_TYPE_BYTES,
+		descriptor.FieldDescriptorProto_TYPE_MESSAGE, descriptor.FieldDescriptorProto_TYPE_GROUP:
+		return false
+	default:
+		return true
+	}
+}
+
+func packedWireType(t descriptor.FieldDescriptorProto_Type) int {
+	switch t {
+	case descriptor.FieldDescriptorProto_TYPE_DOUBLE, descriptor.FieldDescriptorProto_TYPE_FIXED64,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+		return wireFixed64
+	case descriptor.FieldDescriptorProto_TYPE_FLOAT, descriptor.FieldDescriptorProto_TYPE_FIXED32,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+		return wireFixed32
+	default:
+		return wireVarint
+	}
+}
@@ -0,0 +1,268 @@
+package grpc
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	assert2 "github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+type testKind int32
+
+const (
+	testKindUnknown testKind = 0
+	testKindBar     testKind = 2
+)
+
+// testNested 对应 test.Nested
+type testNested struct {
+	Name  string `protobuf:"bytes,1,opt,name=name,proto3"`
+	Value int32  `protobuf:"varint,2,opt,name=value,proto3"`
+}
+
+func (m *testNested) Reset()         { *m = testNested{} }
+func (m *testNested) String() string { return proto.CompactTextString(m) }
+func (*testNested) ProtoMessage()    {}
+
+// testMessage 对应 test.Message，覆盖全部字段类型
+type testMessage struct {
+	Double   float64           `protobuf:"fixed64,1,opt,name=double_value,json=doubleValue,proto3"`
+	Float    float32           `protobuf:"fixed32,2,opt,name=float_value,json=floatValue,proto3"`
+	Int32    int32             `protobuf:"varint,3,opt,name=int32_value,json=int32Value,proto3"`
+	Int64    int64             `protobuf:"varint,4,opt,name=int64_value,json=int64Value,proto3"`
+	Uint32   uint32            `protobuf:"varint,5,opt,name=uint32_value,json=uint32Value,proto3"`
+	Uint64   uint64            `protobuf:"varint,6,opt,name=uint64_value,json=uint64Value,proto3"`
+	Sint32   int32             `protobuf:"zigzag32,7,opt,name=sint32_value,json=sint32Value,proto3"`
+	Sint64   int64             `protobuf:"zigzag64,8,opt,name=sint64_value,json=sint64Value,proto3"`
+	Fixed32  uint32            `protobuf:"fixed32,9,opt,name=fixed32_value,json=fixed32Value,proto3"`
+	Fixed64  uint64            `protobuf:"fixed64,10,opt,name=fixed64_value,json=fixed64Value,proto3"`
+	Sfixed32 int32             `protobuf:"fixed32,11,opt,name=sfixed32_value,json=sfixed32Value,proto3"`
+	Sfixed64 int64             `protobuf:"fixed64,12,opt,name=sfixed64_value,json=sfixed64Value,proto3"`
+	Bool     bool              `protobuf:"varint,13,opt,name=bool_value,json=boolValue,proto3"`
+	String_  string            `protobuf:"bytes,14,opt,name=string_value,json=stringValue,proto3"`
+	Bytes    []byte            `protobuf:"bytes,15,opt,name=bytes_value,json=bytesValue,proto3"`
+	Kind     testKind          `protobuf:"varint,16,opt,name=kind,proto3,enum=test.Kind"`
+	Nested   *testNested       `protobuf:"bytes,17,opt,name=nested,proto3"`
+	Packed   []int32           `protobuf:"varint,18,rep,packed,name=packed_values,json=packedValues,proto3"`
+	Strings  []string          `protobuf:"bytes,19,rep,name=string_values,json=stringValues,proto3"`
+	Items    []*testNested     `protobuf:"bytes,20,rep,name=items,proto3"`
+	Labels   map[string]string `protobuf:"bytes,21,rep,name=labels,proto3" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Doubles  []float64         `protobuf:"fixed64,22,rep,packed,name=double_values,json=doubleValues,proto3"`
+}
+
+func (m *testMessage) Reset()         { *m = testMessage{} }
+func (m *testMessage) String() string { return proto.CompactTextString(m) }
+func (*testMessage) ProtoMessage()    {}
+
+func newTestField(name, jsonName string, number int32, typ descriptor.FieldDescriptorProto_Type, repeated bool, typeName string) *descriptor.FieldDescriptorProto {
+	label := descriptor.FieldDescriptorProto_LABEL_OPTIONAL
+	if repeated {
+		label = descriptor.FieldDescriptorProto_LABEL_REPEATED
+	}
+	field := &descriptor.FieldDescriptorProto{
+		Name:     proto.String(name),
+		JsonName: proto.String(jsonName),
+		Number:   proto.Int32(number),
+		Type:     typ.Enum(),
+		Label:    label.Enum(),
+	}
+	if "" != typeName {
+		field.TypeName = proto.String(typeName)
+	}
+	return field
+}
+
+func newTestDescriptors() *Descriptors {
+	type T = descriptor.FieldDescriptorProto_Type
+	nested := &descriptor.DescriptorProto{
+		Name: proto.String("Nested"),
+		Field: []*descriptor.FieldDescriptorProto{
+			newTestField("name", "name", 1, descriptor.FieldDescriptorProto_TYPE_STRING, false, ""),
+			newTestField("value", "value", 2, descriptor.FieldDescriptorProto_TYPE_INT32, false, ""),
+		},
+	}
+	message := &descriptor.DescriptorProto{
+		Name: proto.String("Message"),
+		Field: []*descriptor.FieldDescriptorProto{
+			newTestField("double_value", "doubleValue", 1, T(1), false, ""),
+			newTestField("float_value", "floatValue", 2, T(2), false, ""),
+			newTestField("int32_value", "int32Value", 3, descriptor.FieldDescriptorProto_TYPE_INT32, false, ""),
+			newTestField("int64_value", "int64Value", 4, descriptor.FieldDescriptorProto_TYPE_INT64, false, ""),
+			newTestField("uint32_value", "uint32Value", 5, descriptor.FieldDescriptorProto_TYPE_UINT32, false, ""),
+			newTestField("uint64_value", "uint64Value", 6, descriptor.FieldDescriptorProto_TYPE_UINT64, false, ""),
+			newTestField("sint32_value", "sint32Value", 7, descriptor.FieldDescriptorProto_TYPE_SINT32, false, ""),
+			newTestField("sint64_value", "sint64Value", 8, descriptor.FieldDescriptorProto_TYPE_SINT64, false, ""),
+			newTestField("fixed32_value", "fixed32Value", 9, descriptor.FieldDescriptorProto_TYPE_FIXED32, false, ""),
+			newTestField("fixed64_value", "fixed64Value", 10, descriptor.FieldDescriptorProto_TYPE_FIXED64, false, ""),
+			newTestField("sfixed32_value", "sfixed32Value", 11, descriptor.FieldDescriptorProto_TYPE_SFIXED32, false, ""),
+			newTestField("sfixed64_value", "sfixed64Value", 12, descriptor.FieldDescriptorProto_TYPE_SFIXED64, false, ""),
+			newTestField("bool_value", "boolValue", 13, descriptor.FieldDescriptorProto_TYPE_BOOL, false, ""),
+			newTestField("string_value", "stringValue", 14, descriptor.FieldDescriptorProto_TYPE_STRING, false, ""),
+			newTestField("bytes_value", "bytesValue", 15, descriptor.FieldDescriptorProto_TYPE_BYTES, false, ""),
+			newTestField("kind", "kind", 16, descriptor.FieldDescriptorProto_TYPE_ENUM, false, ".test.Kind"),
+			newTestField("nested", "nested", 17, descriptor.FieldDescriptorProto_TYPE_MESSAGE, false, ".test.Nested"),
+			newTestField("packed_values", "packedValues", 18, descriptor.FieldDescriptorProto_TYPE_INT32, true, ""),
+			newTestField("string_values", "stringValues", 19, descriptor.FieldDescriptorProto_TYPE_STRING, true, ""),
+			newTestField("items", "items", 20, descriptor.FieldDescriptorProto_TYPE_MESSAGE, true, ".test.Nested"),
+			newTestField("labels", "labels", 21, descriptor.FieldDescriptorProto_TYPE_MESSAGE, true, ".test.Message.LabelsEntry"),
+			newTestField("double_values", "doubleValues", 22, T(1), true, ""),
+		},
+		NestedType: []*descriptor.DescriptorProto{{
+			Name: proto.String("LabelsEntry"),
+			Field: []*descriptor.FieldDescriptorProto{
+				newTestField("key", "key", 1, descriptor.FieldDescriptorProto_TYPE_STRING, false, ""),
+				newTestField("value", "value", 2, descriptor.FieldDescriptorProto_TYPE_STRING, false, ""),
+			},
+			Options: &descriptor.MessageOptions{MapEntry: proto.Bool(true)},
+		}},
+	}
+	d := NewDescriptors()
+	d.AddFile(&descriptor.FileDescriptorProto{
+		Name:        proto.String("test.proto"),
+		Package:     proto.String("test"),
+		MessageType: []*descriptor.DescriptorProto{message, nested},
+		EnumType: []*descriptor.EnumDescriptorProto{{
+			Name: proto.String("Kind"),
+			Value: []*descriptor.EnumValueDescriptorProto{
+				{Name: proto.String("UNKNOWN"), Number: proto.Int32(0)},
+				{Name: proto.String("FOO"), Number: proto.Int32(1)},
+				{Name: proto.String("BAR"), Number: proto.Int32(2)},
+			},
+		}},
+	})
+	return d
+}
+
+func newTestMessage() *testMessage {
+	return &testMessage{
+		Double:   3.14159,
+		Float:    -2.5,
+		Int32:    -42,
+		Int64:    math.MinInt64,
+		Uint32:   math.MaxUint32,
+		Uint64:   math.MaxUint64,
+		Sint32:   -123456,
+		Sint64:   -9876543210,
+		Fixed32:  0xDEADBEEF,
+		Fixed64:  0xDEADBEEFCAFEBABE,
+		Sfixed32: -7,
+		Sfixed64: -77777777777,
+		Bool:     true,
+		String_:  "你好, flux",
+		Bytes:    []byte{0x00, 0xFF, 0x7F},
+		Kind:     testKindBar,
+		Nested:   &testNested{Name: "nested", Value: -1},
+		Packed:   []int32{1, -2, 300, math.MinInt32},
+		Strings:  []string{"a", "", "c"},
+		Items:    []*testNested{{Name: "i1", Value: 1}, {Name: "i2", Value: -2}},
+		Labels:   map[string]string{"k1": "v1", "k2": ""},
+		Doubles:  []float64{0.5, -1.5},
+	}
+}
+
+func TestDescriptors_Unmarshal(t *testing.T) {
+	assert := assert2.New(t)
+	d := newTestDescriptors()
+	msg, _ := d.Message("test.Message")
+	data, err := proto.Marshal(newTestMessage())
+	assert.NoError(err)
+	values, err := d.Unmarshal(msg, data)
+	assert.NoError(err)
+	assert.Equal(3.14159, values["doubleValue"])
+	assert.Equal(float32(-2.5), values["floatValue"])
+	assert.Equal(int32(-42), values["int32Value"])
+	assert.Equal(int64(math.MinInt64), values["int64Value"])
+	assert.Equal(uint32(math.MaxUint32), values["uint32Value"])
+	assert.Equal(uint64(math.MaxUint64), values["uint64Value"])
+	assert.Equal(int32(-123456), values["sint32Value"])
+	assert.Equal(int64(-9876543210), values["sint64Value"])
+	assert.Equal(uint32(0xDEADBEEF), values["fixed32Value"])
+	assert.Equal(uint64(0xDEADBEEFCAFEBABE), values["fixed64Value"])
+	assert.Equal(int32(-7), values["sfixed32Value"])
+	assert.Equal(int64(-77777777777), values["sfixed64Value"])
+	assert.Equal(true, values["boolValue"])
+	assert.Equal("你好, flux", values["stringValue"])
+	assert.Equal([]byte{0x00, 0xFF, 0x7F}, values["bytesValue"])
+	assert.Equal("BAR", values["kind"])
+	assert.Equal(map[string]interface{}{"name": "nested", "value": int32(-1)}, values["nested"])
+	assert.Equal([]interface{}{int32(1), int32(-2), int32(300), int32(math.MinInt32)}, values["packedValues"])
+	assert.Equal([]interface{}{"a", "", "c"}, values["stringValues"])
+	assert.Equal([]interface{}{
+		map[string]interface{}{"name": "i1", "value": int32(1)},
+		map[string]interface{}{"name": "i2", "value": int32(-2)},
+	}, values["items"])
+	// Map的默认值不编码，解码时按字段类型补全默认值
+	assert.Equal(map[string]interface{}{"k1": "v1", "k2": ""}, values["labels"])
+	assert.Equal([]interface{}{0.5, -1.5}, values["doubleValues"])
+}
+
+func TestDescriptors_Marshal(t *testing.T) {
+	assert := assert2.New(t)
+	d := newTestDescriptors()
+	msg, _ := d.Message("test.Message")
+	data, err := d.Marshal(msg, map[string]interface{}{
+		"doubleValue":    3.14159,
+		"floatValue":     "-2.5",
+		"int32Value":     -42,
+		"int64_value":    int64(math.MinInt64),
+		"uint32Value":    uint64(math.MaxUint32),
+		"uint64Value":    uint64(math.MaxUint64),
+		"sint32Value":    "-123456",
+		"sint64Value":    -9876543210,
+		"fixed32Value":   0xDEADBEEF,
+		"fixed64Value":   uint64(0xDEADBEEFCAFEBABE),
+		"sfixed32Value":  -7,
+		"sfixed64Value":  int64(-77777777777),
+		"boolValue":      "true",
+		"stringValue":    "你好, flux",
+		"bytesValue":     []byte{0x00, 0xFF, 0x7F},
+		"kind":           "BAR",
+		"nested":         map[string]interface{}{"name": "nested", "value": -1},
+		"packedValues":   []interface{}{1, -2, "300", math.MinInt32},
+		"stringValues":   []string{"a", "", "c"},
+		"items":          []interface{}{map[string]interface{}{"name": "i1", "value": 1}, map[string]interface{}{"name": "i2", "value": -2}},
+		"labels":         map[string]interface{}{"k1": "v1", "k2": ""},
+		"doubleValues":   []float64{0.5, -1.5},
+		"undefinedField": "ignored",
+	})
+	assert.NoError(err)
+	out := new(testMessage)
+	assert.NoError(proto.Unmarshal(data, out))
+	assert.True(proto.Equal(newTestMessage(), out), out.String())
+	// 枚举值支持数值
+	data, err = d.Marshal(msg, map[string]interface{}{"kind": 2})
+	assert.NoError(err)
+	out.Reset()
+	assert.NoError(proto.Unmarshal(data, out))
+	assert.Equal(testKindBar, out.Kind)
+	// 类型不匹配
+	_, err = d.Marshal(msg, map[string]interface{}{"int32Value": "abc"})
+	assert.Error(err)
+}
+
+func TestDescriptors_RoundTrip(t *testing.T) {
+	assert := assert2.New(t)
+	d := newTestDescriptors()
+	msg, _ := d.Message("test.Message")
+	data, err := proto.Marshal(newTestMessage())
+	assert.NoError(err)
+	values, err := d.Unmarshal(msg, data)
+	assert.NoError(err)
+	encoded, err := d.Marshal(msg, values)
+	assert.NoError(err)
+	out := new(testMessage)
+	assert.NoError(proto.Unmarshal(encoded, out))
+	assert.True(proto.Equal(newTestMessage(), out), out.String())
+}
+
+func TestDescriptors_UnmarshalMalformed(t *testing.T) {
+	assert := assert2.New(t)
+	d := newTestDescriptors()
+	msg, _ := d.Message("test.Message")
+	data, _ := proto.Marshal(newTestMessage())
+	_, err := d.Unmarshal(msg, data[:len(data)-1])
+	assert.Error(err)
+	_, err = d.Unmarshal(msg, []byte{0xFF})
+	assert.Error(err)
+}
@@ -0,0 +1,122 @@
+package grpc
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"time"
+)
+
+const (
+	reflectionPath = "/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo"
+)
+
+// ServerReflection 通过gRPC服务端反射(grpc.reflection.v1alpha)加载服务描述
+type ServerReflection struct {
+	client  *Client
+	timeout time.Duration
+}
+
+func NewServerReflection(client *Client, timeout time.Duration) *ServerReflection {
+	return &ServerReflection{client: client, timeout: timeout}
+}
+
+// LoadService 从目标服务端加载指定Service的描述，以及其依赖的全部文件描述
+func (r *ServerReflection) LoadService(ctx context.Context, host string, secure bool, service string, descs *Descriptors) error {
+	files, err := r.request(ctx, host, secure, 4, service)
+	if nil != err {
+		return err
+	}
+	for len(files) > 0 {
+		pending := make([]string, 0)
+		for _, data := range files {
+			file, err := descs.AddFileBytes(data)
+			if nil != err {
+				return err
+			}
+			pending = append(pending, file.GetDependency()...)
+		}
+		files = files[:0]
+		for _, dep := range pending {
+			if descs.HasFile(dep) {
+				continue
+			}
+			loaded, err := r.request(ctx, host, secure, 3, dep)
+			if nil != err {
+				return err
+			}
+			files = append(files, loaded...)
+		}
+	}
+	if !descs.HasService(service) {
+		return fmt.Errorf("service not found by reflection, service: %s", service)
+	}
+	return nil
+}
+
+// request 发送ServerReflectionRequest，返回FileDescriptorResponse中的文件描述数据
+func (r *ServerReflection) request(ctx context.Context, host string, secure bool, field uint64, value string) ([][]byte, error) {
+	buf := proto.NewBuffer(nil)
+	_ = buf.EncodeVarint(1<<3 | wireBytes)
+	_ = buf.EncodeStringBytes(host)
+	_ = buf.EncodeVarint(field<<3 | wireBytes)
+	_ = buf.EncodeStringBytes(value)
+	result, err := r.client.Unary(ctx, &UnaryCall{
+		Host:    host,
+		Path:    reflectionPath,
+		Secure:  secure,
+		Timeout: r.timeout,
+		Message: buf.Bytes(),
+	})
+	if nil != err {
+		return nil, fmt.Errorf("reflection request, host: %s, error: %w", host, err)
+	}
+	if result.Code != OK {
+		return nil, fmt.Errorf("reflection request, host: %s, status: %s, message: %s", host, result.Code, result.Message)
+	}
+	files := make([][]byte, 0, 4)
+	err = walkFields(result.Body, func(number int32, raw []byte) error {
+		switch number {
+		case 4: // file_descriptor_response
+			return walkFields(raw, func(n int32, file []byte) error {
+				if n == 1 {
+					files = append(files, file)
+				}
+				return nil
+			})
+		case 7: // error_response
+			var code uint64
+			var msg string
+			_ = walkFields(raw, func(n int32, v []byte) error {
+				if n == 1 {
+					code, _ = binary.Uvarint(v)
+				} else if n == 2 {
+					msg = string(v)
+				}
+				return nil
+			})
+			return fmt.Errorf("reflection error, symbol: %s, status: %s, message: %s", value, Code(code), msg)
+		}
+		return nil
+	})
+	return files, err
+}
+
+func walkFields(data []byte, fun func(number int32, raw []byte) error) error {
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return ErrMalformedMessage
+		}
+		raw, rest, err := readWireValue(data[n:], int(tag&7))
+		if nil != err {
+			return err
+		}
+		data = rest
+		if err := fun(int32(tag>>3), raw); nil != err {
+			return err
+		}
+	}
+	return nil
+}
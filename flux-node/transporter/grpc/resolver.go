@@ -0,0 +1,36 @@
+package grpc
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/spf13/cast"
+	"net/http"
+	"strings"
+)
+
+// DefaultArgumentResolver 默认gRPC参数封装处理：以参数名作为请求消息的字段名。
+func DefaultArgumentResolver(arguments []flux.Argument, ctx *flux.Context) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(arguments))
+	for _, arg := range arguments {
+		if val, err := arg.Resolve(ctx); nil != err {
+			return nil, err
+		} else {
+			values[arg.Name] = val
+		}
+	}
+	return values, nil
+}
+
+// DefaultMetadataResolver 默认实现封装gRPC请求Metadata的函数：传递Context的Attributes；
+func DefaultMetadataResolver(ctx *flux.Context) (http.Header, error) {
+	attrs := ctx.Attributes()
+	md := make(http.Header, len(attrs))
+	for k, v := range attrs {
+		sv, err := cast.ToStringE(v)
+		if nil != err || "" == sv {
+			continue
+		}
+		// gRPC Metadata的Key只允许小写字符
+		md[strings.ToLower(k)] = []string{sv}
+	}
+	return md, nil
+}
@@ -0,0 +1,352 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/ext"
+	"github.com/bytepowered/flux/flux-node/logger"
+	"github.com/bytepowered/flux/flux-node/transporter"
+	"github.com/bytepowered/flux/flux-pkg"
+	"github.com/spf13/cast"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ConfigKeyTraceEnable      = "trace_enable"
+	ConfigKeyDescriptorSets   = "descriptor_sets"
+	ConfigKeyReflectionEnable = "reflection_enable"
+	ConfigKeyTimeout          = "timeout"
+)
+
+func init() {
+	ext.RegisterTransporter(flux.ProtoGRPC, NewTransporter())
+}
+
+var _ flux.Transporter = new(RpcTransporter)
+
+type (
+	// Option func to set option
+	Option func(*RpcTransporter)
+	// ArgumentResolver gRPC调用参数封装函数，返回请求消息的字段值
+	ArgumentResolver func(arguments []flux.Argument, ctx *flux.Context) (map[string]interface{}, error)
+	// MetadataResolver 封装gRPC请求Metadata的函数
+	MetadataResolver func(ctx *flux.Context) (http.Header, error)
+)
+
+// RpcTransporter 基于HTTP/2实现gRPC一元调用的Transporter；
+// 服务描述通过descriptor_set文件或者服务端反射加载；
+type RpcTransporter struct {
+	// 可外部配置
+	defaults  map[string]interface{} // 配置默认值
+	aresolver ArgumentResolver       // 请求参数封装函数
+	mresolver MetadataResolver       // 请求Metadata封装函数
+	codec     flux.TransportCodec    // 解析响应结果的函数
+	writer    flux.TransportWriter   // Writer
+	client    *Client
+	// 内部私有
+	trace       bool
+	reflection  bool
+	timeout     time.Duration
+	descriptors *Descriptors
+	reflector   *ServerReflection
+	loadmx      sync.Mutex
+}
+
+// WithArgumentResolver 用于配置gRPC参数封装实现函数
+func WithArgumentResolver(fun ArgumentResolver) Option {
+	return func(service *RpcTransporter) {
+		service.aresolver = fun
+	}
+}
+
+// WithMetadataResolver 用于配置gRPC请求Metadata封装实现函数
+func WithMetadataResolver(fun MetadataResolver) Option {
+	return func(service *RpcTransporter) {
+		service.mresolver = fun
+	}
+}
+
+// WithTransportCodec 用于配置响应数据解析实现函数
+func WithTransportCodec(fun flux.TransportCodec) Option {
+	return func(service *RpcTransporter) {
+		service.codec = fun
+	}
+}
+
+// WithTransportWriter 用于配置响应数据解析实现函数
+func WithTransportWriter(fun flux.TransportWriter) Option {
+	return func(service *RpcTransporter) {
+		service.writer = fun
+	}
+}
+
+// WithClient 用于配置gRPC调用客户端
+func WithClient(client *Client) Option {
+	return func(service *RpcTransporter) {
+		service.client = client
+	}
+}
+
+// WithDescriptors 用于配置预加载的服务描述
+func WithDescriptors(descriptors *Descriptors) Option {
+	return func(service *RpcTransporter) {
+		service.descriptors = descriptors
+	}
+}
+
+// WithDefaults 用于配置默认配置值
+func WithDefaults(defaults map[string]interface{}) Option {
+	return func(service *RpcTransporter) {
+		service.defaults = defaults
+	}
+}
+
+// NewTransporterWith New grpc transporter service with options
+func NewTransporterWith(opts ...Option) flux.Transporter {
+	bts := &RpcTransporter{
+		descriptors: NewDescriptors(),
+	}
+	for _, opt := range opts {
+		opt(bts)
+	}
+	return bts
+}
+
+// NewTransporter New grpc transporter instance
+func NewTransporter() flux.Transporter {
+	return NewTransporterOverride()
+}
+
+// NewTransporterOverride New grpc transporter instance
+func NewTransporterOverride(overrides ...Option) flux.Transporter {
+	opts := []Option{
+		WithDefaults(map[string]interface{}{
+			ConfigKeyTraceEnable:      false,
+			ConfigKeyReflectionEnable: true,
+			ConfigKeyTimeout:          "5s",
+		}),
+		WithClient(NewClient(nil)),
+		WithArgumentResolver(DefaultArgumentResolver),
+		WithMetadataResolver(DefaultMetadataResolver),
+		WithTransportCodec(NewTransportCodecFunc()),
+		WithTransportWriter(new(transporter.DefaultTransportWriter)),
+	}
+	return NewTransporterWith(append(opts, overrides...)...)
+}
+
+func (b *RpcTransporter) Writer() flux.TransportWriter {
+	return b.writer
+}
+
+// Init init transporter
+func (b *RpcTransporter) Init(config *flux.Configuration) error {
+	logger.Info("gRPC transporter initializing")
+	config.SetDefaults(b.defaults)
+	b.trace = config.GetBool(ConfigKeyTraceEnable)
+	b.reflection = config.GetBool(ConfigKeyReflectionEnable)
+	b.timeout = config.GetDuration(ConfigKeyTimeout)
+	if fluxpkg.IsNil(b.aresolver) {
+		b.aresolver = DefaultArgumentResolver
+	}
+	if fluxpkg.IsNil(b.mresolver) {
+		b.mresolver = DefaultMetadataResolver
+	}
+	if nil == b.client {
+		b.client = NewClient(nil)
+	}
+	for _, path := range config.GetStringSlice(ConfigKeyDescriptorSets) {
+		if err := b.descriptors.LoadDescriptorSetFile(path); nil != err {
+			return err
+		}
+		logger.Infow("gRPC transporter load descriptor set", "path", path)
+	}
+	b.reflector = NewServerReflection(b.client, b.timeout)
+	logger.Infow("gRPC transporter request trace", "enable", b.trace, "reflection", b.reflection)
+	return nil
+}
+
+// Startup startup service
+func (b *RpcTransporter) Startup() error {
+	return nil
+}
+
+// Shutdown shutdown service
+func (b *RpcTransporter) Shutdown(_ context.Context) error {
+	b.client.plain.CloseIdleConnections()
+	b.client.secure.CloseIdleConnections()
+	return nil
+}
+
+// Transport do exchange with context
+func (b *RpcTransporter) Transport(ctx *flux.Context) {
+	transporter.DoTransport(ctx, b)
+}
+
+func (b *RpcTransporter) InvokeCodec(ctx *flux.Context, service flux.TransporterService) (*flux.ResponseBody, *flux.ServeError) {
+	raw, serr := b.Invoke(ctx, service)
+	if nil != serr {
+		logger.TraceContext(ctx).Errorw("TRANSPORTER:GRPC:RPC_ERROR",
+			"transporter-service", service.ServiceID(), "error", serr.CauseError)
+		return nil, serr
+	}
+	// decode response
+	result, err := b.codec(ctx, raw)
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageTransportDecodeResponse,
+			CauseError: fmt.Errorf("decode grpc response, err: %w", err),
+		}
+	}
+	return result, nil
+}
+
+// Invoke invoke transporter service with context
+func (b *RpcTransporter) Invoke(ctx *flux.Context, service flux.TransporterService) (interface{}, *flux.ServeError) {
+	secure := isSecureScheme(service.Scheme)
+	method, err := b.LoadMethod(ctx.Context(), &service, secure)
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageGrpcDescriptorMissing,
+			CauseError: err,
+		}
+	}
+	values, err := b.aresolver(service.Arguments, ctx)
+	if nil != err {
+		return nil, newAssembleError(err)
+	}
+	md, err := b.mresolver(ctx)
+	if nil != err {
+		return nil, newAssembleError(err)
+	}
+	message, err := b.descriptors.Marshal(method.Input, unwrapMessageValues(method, values))
+	if nil != err {
+		return nil, newAssembleError(err)
+	}
+	if b.trace {
+		logger.TraceContext(ctx).Infow("TRANSPORTER:GRPC:INVOKE",
+			"transporter-service", service.ServiceID(), "arg-values", values, "metadata", md)
+	}
+	timeout := b.timeout
+	if t := parseTimeout(service.RpcTimeout()); t > 0 {
+		timeout = t
+	}
+	result, err := b.client.Unary(ctx.Context(), &UnaryCall{
+		Host:     service.RemoteHost,
+		Path:     method.FullPath,
+		Secure:   secure,
+		Timeout:  timeout,
+		Metadata: md,
+		Message:  message,
+	})
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusBadGateway,
+			ErrorCode:  flux.ErrorCodeGatewayTransporter,
+			Message:    flux.ErrorMessageGrpcInvokeFailed,
+			CauseError: err,
+		}
+	}
+	if result.Code != OK {
+		return nil, &flux.ServeError{
+			StatusCode: result.Code.HttpStatus(),
+			ErrorCode:  "GRPC:" + result.Code.String(),
+			Message:    result.Message,
+			CauseError: fmt.Errorf("grpc status: %s, message: %s", result.Code, result.Message),
+			Header:     MetadataToHeader(result.Header, result.Trailer),
+			Extras:     map[string]interface{}{"grpc-status": uint32(result.Code)},
+		}
+	}
+	body, err := b.descriptors.Unmarshal(method.Output, result.Body)
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageTransportDecodeResponse,
+			CauseError: err,
+		}
+	}
+	if b.trace {
+		logger.TraceContext(ctx).Infow("TRANSPORTER:GRPC:RECEIVED",
+			"transporter-service", service.ServiceID(), "response", body)
+	}
+	return &Response{Header: result.Header, Trailer: result.Trailer, Body: body}, nil
+}
+
+// LoadMethod 查找服务方法描述；未加载时，如果启用服务端反射，从服务端加载；
+func (b *RpcTransporter) LoadMethod(ctx context.Context, service *flux.TransporterService, secure bool) (*MethodDescriptor, error) {
+	method, err := b.descriptors.Method(service.Interface, service.Method)
+	if nil == err || !b.reflection {
+		return b.checkUnary(method, err)
+	}
+	b.loadmx.Lock()
+	defer b.loadmx.Unlock()
+	if !b.descriptors.HasService(service.Interface) {
+		logger.Infow("GRPC:REFLECTION:LOAD", "service", service.Interface, "remote-host", service.RemoteHost)
+		if err := b.reflector.LoadService(ctx, service.RemoteHost, secure, service.Interface, b.descriptors); nil != err {
+			return nil, err
+		}
+	}
+	return b.checkUnary(b.descriptors.Method(service.Interface, service.Method))
+}
+
+func (b *RpcTransporter) checkUnary(method *MethodDescriptor, err error) (*MethodDescriptor, error) {
+	if nil != err {
+		return nil, err
+	}
+	if method.Streams {
+		return nil, fmt.Errorf("streaming method not supported, method: %s", method.FullPath)
+	}
+	return method, nil
+}
+
+// unwrapMessageValues 当仅有一个参数，且参数名不是请求消息的字段时，参数值作为完整的请求消息
+func unwrapMessageValues(method *MethodDescriptor, values map[string]interface{}) map[string]interface{} {
+	if len(values) != 1 {
+		return values
+	}
+	for name, value := range values {
+		for _, field := range method.Input.GetField() {
+			if field.GetName() == name || field.GetJsonName() == name {
+				return values
+			}
+		}
+		if sm, err := cast.ToStringMapE(value); nil == err {
+			delete(sm, "class")
+			return sm
+		}
+	}
+	return values
+}
+
+func newAssembleError(err error) *flux.ServeError {
+	return &flux.ServeError{
+		StatusCode: flux.StatusServerError,
+		ErrorCode:  flux.ErrorCodeGatewayInternal,
+		Message:    flux.ErrorMessageGrpcAssembleFailed,
+		CauseError: err,
+	}
+}
+
+func isSecureScheme(scheme string) bool {
+	scheme = strings.ToLower(scheme)
+	return scheme == "https" || scheme == "grpcs"
+}
+
+// parseTimeout 解析超时配置：纯数字为毫秒，或者Duration格式字符串
+func parseTimeout(value string) time.Duration {
+	if "" == value {
+		return 0
+	}
+	if ms, err := cast.ToInt64E(value); nil == err {
+		return time.Duration(ms) * time.Millisecond
+	}
+	return cast.ToDuration(value)
+}
@@ -1,52 +1,96 @@
 package http
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/ext"
 	"github.com/bytepowered/flux/flux-node/logger"
 	"github.com/spf13/cast"
 	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
 	"net/url"
+	"regexp"
 	"strings"
 	"time"
 )
 
+const (
+	// ServiceAttrTagBodyEncoding 定义Http服务请求Body的编码方式
+	ServiceAttrTagBodyEncoding = "body_encoding"
+)
+
+// Http请求Body的编码方式
+const (
+	BodyEncodingForm      = "form"
+	BodyEncodingJson      = "json"
+	BodyEncodingMultipart = "multipart"
+	BodyEncodingRaw       = "raw"
+)
+
+var pathTemplatePattern = regexp.MustCompile(`\{([^{}/]+)\}`)
+
 func DefaultArgumentResolver(service *flux.TransporterService, inURL *url.URL, bodyReader io.ReadCloser, ctx *flux.Context) (*http.Request, error) {
-	inParams := service.Arguments
 	newQuery := inURL.RawQuery
 	// 使用可重复读的GetBody函数
-	defer bodyReader.Close()
-	var newBodyReader io.Reader = bodyReader
+	var newBodyReader io.Reader = http.NoBody
+	if nil != bodyReader {
+		defer bodyReader.Close()
+		newBodyReader = bodyReader
+	}
+	encoding := strings.ToLower(service.GetAttr(ServiceAttrTagBodyEncoding).GetString())
+	contentType := ""
+	// 路径模板：{name}占位符使用的参数，不再作为Query或Body参数传递
+	vars, inParams, err := ResolveTemplateArguments(service, ctx)
+	if nil != err {
+		return nil, err
+	}
+	newHost, newPath, newRawPath := service.RemoteHost, service.Interface, inURL.RawPath
+	if len(vars) > 0 {
+		newHost = ExpandTemplate(service.RemoteHost, vars)
+		newRawPath = ExpandTemplate(service.Interface, vars)
+		if newPath, err = url.PathUnescape(newRawPath); nil != err {
+			return nil, fmt.Errorf("expand path template, path: %s, err: %w", newRawPath, err)
+		}
+		// 参数全部被路径模板使用时，不透传原始请求Body
+		if len(inParams) == 0 && http.MethodGet != service.Method && BodyEncodingRaw != encoding {
+			newBodyReader = http.NoBody
+		}
+	}
 	if len(inParams) > 0 {
 		// 如果Endpoint定义了参数，即表示限定参数传递
-		var data string
-		if values, err := AssembleHttpValues(inParams, ctx); nil != err {
-			return nil, err
-		} else {
-			data = values.Encode()
-		}
-		// GET：参数拼接到URL中；
-		if http.MethodGet == service.Method {
+		// GET/RAW：参数拼接到URL中；RAW模式的Body透传原始请求数据；
+		if http.MethodGet == service.Method || BodyEncodingRaw == encoding {
+			values, err := AssembleHttpValues(inParams, ctx)
+			if nil != err {
+				return nil, err
+			}
 			if newQuery == "" {
-				newQuery = data
+				newQuery = values.Encode()
 			} else {
-				newQuery += "&" + data
+				newQuery += "&" + values.Encode()
 			}
 		} else {
-			// 其它方法：拼接到Body中，并设置form-data/x-www-url-encoded
-			newBodyReader = strings.NewReader(data)
+			// 其它方法：按编码方式拼接到Body中
+			reader, ctype, err := AssembleHttpBody(encoding, inParams, newBodyReader, ctx)
+			if nil != err {
+				return nil, err
+			}
+			newBodyReader, contentType = reader, ctype
 		}
 	}
 	// 未定义参数，即透传Http请求：Rewrite inRequest path
 	newUrl := &url.URL{
-		Host:       service.RemoteHost,
-		Path:       service.Interface,
+		Host:       newHost,
+		Path:       newPath,
 		Scheme:     service.Scheme,
 		Opaque:     inURL.Opaque,
 		User:       inURL.User,
-		RawPath:    inURL.RawPath,
+		RawPath:    newRawPath,
 		ForceQuery: inURL.ForceQuery,
 		RawQuery:   newQuery,
 		Fragment:   inURL.Fragment,
@@ -62,14 +106,192 @@ func DefaultArgumentResolver(service *flux.TransporterService, inURL *url.URL, b
 	if nil != err {
 		return nil, fmt.Errorf("new request, method: %s, url: %s, err: %w", service.Method, newUrl, err)
 	}
-	// Body数据设置application/x-www-url-encoded
-	if http.MethodGet != service.Method {
-		newRequest.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if "" != contentType {
+		newRequest.Header.Set(flux.HeaderContentType, contentType)
 	}
 	newRequest.Header.Set("User-Agent", "FluxGo/Transporter/v1")
 	return newRequest, err
 }
 
+// ResolveTemplateArguments 解析Interface和RemoteHost中{name}占位符对应的参数值；
+// 返回占位符参数值，以及未被占位符使用的参数列表；
+func ResolveTemplateArguments(service *flux.TransporterService, ctx *flux.Context) (map[string]string, []flux.Argument, error) {
+	names := make(map[string]bool, 4)
+	for _, template := range []string{service.Interface, service.RemoteHost} {
+		for _, match := range pathTemplatePattern.FindAllStringSubmatch(template, -1) {
+			names[match[1]] = true
+		}
+	}
+	if len(names) == 0 {
+		return nil, service.Arguments, nil
+	}
+	vars := make(map[string]string, len(names))
+	remains := make([]flux.Argument, 0, len(service.Arguments))
+	for _, arg := range service.Arguments {
+		if !names[arg.Name] {
+			remains = append(remains, arg)
+			continue
+		}
+		val, err := arg.Resolve(ctx)
+		if nil != err {
+			return nil, nil, err
+		}
+		vars[arg.Name] = cast.ToString(val)
+	}
+	for name := range names {
+		if _, ok := vars[name]; !ok {
+			return nil, nil, fmt.Errorf("path template argument not found, name: %s", name)
+		}
+	}
+	return vars, remains, nil
+}
+
+// ExpandTemplate 使用参数值替换模板中的{name}占位符；参数值按URL路径片段规则转义；
+func ExpandTemplate(template string, vars map[string]string) string {
+	return pathTemplatePattern.ReplaceAllStringFunc(template, func(placeholder string) string {
+		if val, ok := vars[placeholder[1:len(placeholder)-1]]; ok {
+			return url.PathEscape(val)
+		}
+		return placeholder
+	})
+}
+
+// AssembleHttpBody 按编码方式封装请求Body数据，返回Body数据和ContentType。
+// 支持的编码方式：form(默认)，json，multipart；
+func AssembleHttpBody(encoding string, arguments []flux.Argument, bodyReader io.Reader, ctx *flux.Context) (io.Reader, string, error) {
+	switch encoding {
+	case BodyEncodingJson:
+		values, err := AssembleJsonValues(arguments, ctx)
+		if nil != err {
+			return nil, "", err
+		}
+		data, err := ext.JSONMarshal(values)
+		if nil != err {
+			return nil, "", fmt.Errorf("encode json body, err: %w", err)
+		}
+		return bytes.NewReader(data), flux.MIMEApplicationJSONCharsetUTF8, nil
+	case BodyEncodingMultipart:
+		return AssembleMultipartBody(arguments, bodyReader, ctx)
+	case BodyEncodingForm, "":
+		values, err := AssembleHttpValues(arguments, ctx)
+		if nil != err {
+			return nil, "", err
+		}
+		return strings.NewReader(values.Encode()), flux.MIMEApplicationForm, nil
+	default:
+		return nil, "", fmt.Errorf("unsupported body encoding: %s", encoding)
+	}
+}
+
+// AssembleJsonValues 封装JSON对象参数；POJO参数按其字段结构生成嵌套JSON对象；
+func AssembleJsonValues(arguments []flux.Argument, ctx *flux.Context) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(arguments))
+	for _, arg := range arguments {
+		if val, err := arg.Resolve(ctx); nil != err {
+			return nil, err
+		} else {
+			values[arg.Name] = trimPOJOClass(arg, val)
+		}
+	}
+	return values, nil
+}
+
+// AssembleMultipartBody 封装multipart/form-data请求Body：参数作为表单字段，
+// 文件类型的参数值以及原始请求中的文件，作为文件部分转发；
+func AssembleMultipartBody(arguments []flux.Argument, bodyReader io.Reader, ctx *flux.Context) (io.Reader, string, error) {
+	buf := new(bytes.Buffer)
+	writer := multipart.NewWriter(buf)
+	names := make(map[string]bool, len(arguments))
+	for _, arg := range arguments {
+		val, err := arg.Resolve(ctx)
+		if nil != err {
+			return nil, "", err
+		}
+		names[arg.Name] = true
+		if err := writeMultipartValue(writer, arg.Name, val); nil != err {
+			return nil, "", err
+		}
+	}
+	// 转发原始请求中未被参数定义的文件
+	if _, params, err := mime.ParseMediaType(ctx.HeaderVar(flux.HeaderContentType)); nil == err && "" != params["boundary"] {
+		reader := multipart.NewReader(bodyReader, params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			} else if nil != err {
+				return nil, "", fmt.Errorf("read multipart body, err: %w", err)
+			}
+			if "" == part.FileName() || names[part.FormName()] {
+				continue
+			}
+			if err := writeMultipartFile(writer, part.FormName(), part.FileName(), part.Header.Get(flux.HeaderContentType), part); nil != err {
+				return nil, "", err
+			}
+		}
+	}
+	if err := writer.Close(); nil != err {
+		return nil, "", err
+	}
+	return buf, writer.FormDataContentType(), nil
+}
+
+func writeMultipartValue(writer *multipart.Writer, name string, value interface{}) error {
+	switch v := value.(type) {
+	case *multipart.FileHeader:
+		file, err := v.Open()
+		if nil != err {
+			return fmt.Errorf("open multipart file, name: %s, err: %w", name, err)
+		}
+		defer file.Close()
+		return writeMultipartFile(writer, name, v.Filename, v.Header.Get(flux.HeaderContentType), file)
+	case []*multipart.FileHeader:
+		for _, fh := range v {
+			if err := writeMultipartValue(writer, name, fh); nil != err {
+				return err
+			}
+		}
+		return nil
+	default:
+		return writer.WriteField(name, cast.ToString(value))
+	}
+}
+
+func writeMultipartFile(writer *multipart.Writer, name, filename, contentType string, reader io.Reader) error {
+	if "" == contentType {
+		contentType = "application/octet-stream"
+	}
+	header := make(textproto.MIMEHeader, 2)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		quoteEscaper.Replace(name), quoteEscaper.Replace(filename)))
+	header.Set(flux.HeaderContentType, contentType)
+	w, err := writer.CreatePart(header)
+	if nil != err {
+		return err
+	}
+	if _, err := io.Copy(w, reader); nil != err {
+		return fmt.Errorf("write multipart file, name: %s, err: %w", name, err)
+	}
+	return nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// trimPOJOClass 移除POJO参数值中用于Java类型描述的class字段
+func trimPOJOClass(arg flux.Argument, value interface{}) interface{} {
+	sm, ok := value.(map[string]interface{})
+	if !ok || len(arg.Fields) == 0 {
+		return value
+	}
+	delete(sm, "class")
+	for _, field := range arg.Fields {
+		if fv, ok := sm[field.Name]; ok {
+			sm[field.Name] = trimPOJOClass(field, fv)
+		}
+	}
+	return sm
+}
+
 func AssembleHttpValues(arguments []flux.Argument, ctx *flux.Context) (url.Values, error) {
 	values := make(url.Values, len(arguments))
 	for _, arg := range arguments {
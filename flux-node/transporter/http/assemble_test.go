@@ -0,0 +1,238 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/ext"
+	"github.com/bytepowered/flux/flux-node/internal/fluxtest"
+	assert2 "github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// stdJsonSerializer 使用标准库实现的JSON序列化，校验JSON编码的Body结构
+type stdJsonSerializer struct{}
+
+func (stdJsonSerializer) Marshal(any interface{}) ([]byte, error) {
+	return json.Marshal(any)
+}
+
+func (stdJsonSerializer) Unmarshal(data []byte, obj interface{}) error {
+	return json.Unmarshal(data, obj)
+}
+
+func newEncodingService(method, encoding string, args ...flux.Argument) flux.TransporterService {
+	return flux.TransporterService{
+		Scheme: "http", RemoteHost: "127.0.0.1:8080", Interface: "/api", Method: method,
+		Arguments: args,
+		EmbeddedAttributes: flux.EmbeddedAttributes{Attributes: []flux.Attribute{
+			{Name: ServiceAttrTagBodyEncoding, Value: encoding},
+		}},
+	}
+}
+
+func TestDefaultArgumentResolver_FormEncoding(t *testing.T) {
+	assert := assert2.New(t)
+	ctx := fluxtest.NewContext(httptest.NewRequest("POST", "http://mocking/api", nil))
+	for _, encoding := range []string{"", BodyEncodingForm, "FORM"} {
+		service := newEncodingService("POST", encoding, ext.NewStringArgumentWith("name", "foo"), ext.NewIntegerArgumentWith("age", 18))
+		req, err := DefaultArgumentResolver(&service, &url.URL{Path: "/api"}, nil, ctx)
+		assert.NoError(err)
+		assert.Equal(flux.MIMEApplicationForm, req.Header.Get(flux.HeaderContentType))
+		data, _ := ioutil.ReadAll(req.Body)
+		assert.Equal("age=18&name=foo", string(data))
+		assert.Equal("", req.URL.RawQuery)
+	}
+}
+
+func TestDefaultArgumentResolver_JsonEncoding(t *testing.T) {
+	ext.RegisterSerializer(ext.TypeNameSerializerJson, stdJsonSerializer{})
+	assert := assert2.New(t)
+	ctx := fluxtest.NewContext(httptest.NewRequest("POST", "http://mocking/api", nil))
+	pojo := ext.NewPrimitiveArgumentWithLoader("com.foo.User", "user", func() flux.MTValue {
+		return flux.WrapStrMapMTValue(map[string]interface{}{
+			"class": "com.foo.User", "name": "foo", "address": map[string]interface{}{"class": "com.foo.Address", "city": "sz"},
+		})
+	})
+	address := ext.NewComplexArgument("com.foo.Address", "address")
+	address.Fields = []flux.Argument{ext.NewStringArgument("city")}
+	pojo.Fields = []flux.Argument{ext.NewStringArgument("name"), address}
+	service := newEncodingService("POST", BodyEncodingJson, ext.NewIntegerArgumentWith("id", 1), pojo)
+	req, err := DefaultArgumentResolver(&service, &url.URL{Path: "/api"}, nil, ctx)
+	assert.NoError(err)
+	assert.Equal(flux.MIMEApplicationJSONCharsetUTF8, req.Header.Get(flux.HeaderContentType))
+	data, _ := ioutil.ReadAll(req.Body)
+	values := make(map[string]interface{})
+	assert.NoError(json.Unmarshal(data, &values))
+	// POJO参数的class字段被移除，按字段结构生成嵌套对象
+	assert.Equal(map[string]interface{}{
+		"id":   float64(1),
+		"user": map[string]interface{}{"name": "foo", "address": map[string]interface{}{"city": "sz"}},
+	}, values)
+}
+
+func TestDefaultArgumentResolver_MultipartEncoding(t *testing.T) {
+	assert := assert2.New(t)
+	buf := new(bytes.Buffer)
+	w := multipart.NewWriter(buf)
+	_ = w.WriteField("ignored", "field")
+	for _, f := range []struct{ name, filename, data string }{
+		{"avatar", "a.png", "PNG-DATA"},
+		{"doc", "d.txt", "TEXT"},
+	} {
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", `form-data; name="`+f.name+`"; filename="`+f.filename+`"`)
+		part, _ := w.CreatePart(h)
+		_, _ = part.Write([]byte(f.data))
+	}
+	_ = w.Close()
+	body := buf.Bytes()
+	ctx := fluxtest.NewContext(httptest.NewRequest("POST", "http://mocking/upload", bytes.NewReader(body)))
+	ctx.Request().Header.Set(flux.HeaderContentType, w.FormDataContentType())
+	// doc 由参数定义，不转发原始请求中的同名文件
+	service := newEncodingService("POST", BodyEncodingMultipart, ext.NewStringArgumentWith("name", "foo"), ext.NewStringArgumentWith("doc", "v"))
+	req, err := DefaultArgumentResolver(&service, &url.URL{Path: "/upload"}, ioutil.NopCloser(bytes.NewReader(body)), ctx)
+	assert.NoError(err)
+	mediaType, params, err := mime.ParseMediaType(req.Header.Get(flux.HeaderContentType))
+	assert.NoError(err)
+	assert.Equal("multipart/form-data", mediaType)
+	form, err := multipart.NewReader(req.Body, params["boundary"]).ReadForm(1 << 20)
+	assert.NoError(err)
+	assert.Equal(map[string][]string{"name": {"foo"}, "doc": {"v"}}, form.Value)
+	assert.Equal(1, len(form.File))
+	assert.Equal(1, len(form.File["avatar"]))
+	file, _ := form.File["avatar"][0].Open()
+	data, _ := ioutil.ReadAll(file)
+	assert.Equal("PNG-DATA", string(data))
+	assert.Equal("application/octet-stream", form.File["avatar"][0].Header.Get(flux.HeaderContentType))
+}
+
+func TestDefaultArgumentResolver_RawEncoding(t *testing.T) {
+	assert := assert2.New(t)
+	ctx := fluxtest.NewContext(httptest.NewRequest("POST", "http://mocking/api?a=1", strings.NewReader("RAW-BODY")))
+	service := newEncodingService("POST", BodyEncodingRaw, ext.NewStringArgumentWith("name", "foo"))
+	req, err := DefaultArgumentResolver(&service, &url.URL{Path: "/api", RawQuery: "a=1"}, ioutil.NopCloser(strings.NewReader("RAW-BODY")), ctx)
+	assert.NoError(err)
+	assert.Equal("a=1&name=foo", req.URL.RawQuery)
+	assert.Equal("", req.Header.Get(flux.HeaderContentType))
+	data, _ := ioutil.ReadAll(req.Body)
+	assert.Equal("RAW-BODY", string(data))
+}
+
+func TestDefaultArgumentResolver_UnsupportedEncoding(t *testing.T) {
+	ctx := fluxtest.NewContext(httptest.NewRequest("POST", "http://mocking/api", nil))
+	service := newEncodingService("POST", "xml", ext.NewStringArgumentWith("name", "foo"))
+	_, err := DefaultArgumentResolver(&service, &url.URL{Path: "/api"}, nil, ctx)
+	assert2.Error(t, err)
+}
+
+func TestRpcTransporter_ExecuteRequestContentType(t *testing.T) {
+	assert := assert2.New(t)
+	var header http.Header
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer server.Close()
+	ctx := fluxtest.NewContext(httptest.NewRequest("POST", "http://mocking/api", strings.NewReader(`{"name":"raw"}`)))
+	ctx.Request().Header.Set(flux.HeaderContentType, flux.MIMEApplicationJSON)
+	ctx.Request().Header.Set("X-Origin", "origin")
+	service := newEncodingService("POST", BodyEncodingForm, ext.NewStringArgumentWith("name", "foo"))
+	service.RemoteHost = strings.TrimPrefix(server.URL, "http://")
+	resp, serr := NewRpcHttpTransporterWith().Invoke(ctx, service)
+	assert.Nil(serr)
+	resp.(*http.Response).Body.Close()
+	// 编码方式的Content-Type覆盖原始请求的Content-Type，其它Header仍然透传
+	assert.Equal(flux.MIMEApplicationForm, header.Get(flux.HeaderContentType))
+	assert.Equal("origin", header.Get("X-Origin"))
+	assert.Equal("name=foo", string(body))
+}
+
+func TestExpandTemplate(t *testing.T) {
+	assert := assert2.New(t)
+	vars := map[string]string{"id": "a b/c", "tenant": "t1"}
+	assert.Equal("/users/a%20b%2Fc/orders", ExpandTemplate("/users/{id}/orders", vars))
+	assert.Equal("t1.svc:8080", ExpandTemplate("{tenant}.svc:8080", vars))
+	assert.Equal("/users/{name}", ExpandTemplate("/users/{name}", vars))
+	assert.Equal("/users", ExpandTemplate("/users", vars))
+}
+
+func TestResolveTemplateArguments(t *testing.T) {
+	assert := assert2.New(t)
+	ctx := fluxtest.NewContext(httptest.NewRequest("GET", "http://mocking/api", nil))
+	service := flux.TransporterService{
+		RemoteHost: "{tenant}.svc", Interface: "/users/{id}",
+		Arguments: []flux.Argument{ext.NewIntegerArgumentWith("id", 100), ext.NewStringArgumentWith("tenant", "t1"), ext.NewStringArgumentWith("name", "foo")},
+	}
+	vars, remains, err := ResolveTemplateArguments(&service, ctx)
+	assert.NoError(err)
+	assert.Equal(map[string]string{"id": "100", "tenant": "t1"}, vars)
+	assert.Equal(1, len(remains))
+	assert.Equal("name", remains[0].Name)
+	// 无模板：参数全部保留
+	service.RemoteHost, service.Interface = "127.0.0.1", "/users"
+	vars, remains, err = ResolveTemplateArguments(&service, ctx)
+	assert.NoError(err)
+	assert.Nil(vars)
+	assert.Equal(3, len(remains))
+	// 模板参数未定义
+	service.Interface = "/users/{uid}"
+	_, _, err = ResolveTemplateArguments(&service, ctx)
+	assert.Error(err)
+}
+
+func TestDefaultArgumentResolver_PathTemplate(t *testing.T) {
+	assert := assert2.New(t)
+	ctx := fluxtest.NewContext(httptest.NewRequest("GET", "http://mocking/api", nil))
+	service := flux.TransporterService{
+		Scheme: "http", RemoteHost: "{tenant}.svc:8080", Interface: "/users/{id}/orders", Method: "GET",
+		Arguments: []flux.Argument{ext.NewStringArgumentWith("id", "a b/c"), ext.NewStringArgumentWith("tenant", "t1"), ext.NewIntegerArgumentWith("page", 2)},
+	}
+	req, err := DefaultArgumentResolver(&service, &url.URL{Path: "/api"}, nil, ctx)
+	assert.NoError(err)
+	assert.Equal("t1.svc:8080", req.URL.Host)
+	assert.Equal("/users/a b/c/orders", req.URL.Path)
+	assert.Equal("/users/a%20b%2Fc/orders", req.URL.EscapedPath())
+	// 模板参数不作为Query参数传递
+	assert.Equal("page=2", req.URL.RawQuery)
+}
+
+func TestDefaultArgumentResolver_PathTemplateBody(t *testing.T) {
+	assert := assert2.New(t)
+	ctx := fluxtest.NewContext(httptest.NewRequest("POST", "http://mocking/api", strings.NewReader("ORIGIN")))
+	// 模板之外的参数按编码方式封装到Body中
+	service := newEncodingService("POST", BodyEncodingForm, ext.NewIntegerArgumentWith("id", 1), ext.NewStringArgumentWith("name", "foo"))
+	service.Interface = "/users/{id}"
+	req, err := DefaultArgumentResolver(&service, &url.URL{Path: "/api"}, ioutil.NopCloser(strings.NewReader("ORIGIN")), ctx)
+	assert.NoError(err)
+	assert.Equal("/users/1", req.URL.Path)
+	data, _ := ioutil.ReadAll(req.Body)
+	assert.Equal("name=foo", string(data))
+	// 参数全部被模板使用时，不透传原始请求Body
+	service.Arguments = service.Arguments[:1]
+	req, err = DefaultArgumentResolver(&service, &url.URL{Path: "/api"}, ioutil.NopCloser(strings.NewReader("ORIGIN")), ctx)
+	assert.NoError(err)
+	assert.Equal(http.NoBody, req.Body)
+	// RAW模式仍然透传原始请求Body
+	service.Attributes = []flux.Attribute{{Name: ServiceAttrTagBodyEncoding, Value: BodyEncodingRaw}}
+	req, err = DefaultArgumentResolver(&service, &url.URL{Path: "/api"}, ioutil.NopCloser(strings.NewReader("ORIGIN")), ctx)
+	assert.NoError(err)
+	data, _ = ioutil.ReadAll(req.Body)
+	assert.Equal("ORIGIN", string(data))
+}
+
+func TestDefaultArgumentResolver_PathTemplateMissing(t *testing.T) {
+	ctx := fluxtest.NewContext(httptest.NewRequest("GET", "http://mocking/api", nil))
+	service := flux.TransporterService{Scheme: "http", RemoteHost: "127.0.0.1", Interface: "/users/{id}", Method: "GET"}
+	_, err := DefaultArgumentResolver(&service, &url.URL{Path: "/api"}, nil, ctx)
+	assert2.Error(t, err)
+}
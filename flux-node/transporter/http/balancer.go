@@ -0,0 +1,181 @@
+package http
+
+import (
+	"github.com/spaolacci/murmur3"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// 负载均衡策略
+const (
+	LoadBalanceRoundRobin     = "round_robin"
+	LoadBalanceWeighted       = "weighted"
+	LoadBalanceLeastInFlight  = "least_inflight"
+	LoadBalanceConsistentHash = "consistent_hash"
+)
+
+type (
+	// LoadBalancer 从一组目标实例中选择一个实例；
+	// 优先在可用实例中选择，全部实例不可用时，在全部实例中选择；
+	LoadBalancer interface {
+		Select(hashKey string) *Target
+	}
+	// LoadBalancerFactory 构建负载均衡器的工厂函数
+	LoadBalancerFactory func(targets []*Target) LoadBalancer
+)
+
+var (
+	balancerFactories = make(map[string]LoadBalancerFactory, 4)
+	balancerMu        sync.RWMutex
+)
+
+func init() {
+	RegisterLoadBalancerFactory(LoadBalanceRoundRobin, NewRoundRobinBalancer)
+	RegisterLoadBalancerFactory(LoadBalanceWeighted, NewWeightedBalancer)
+	RegisterLoadBalancerFactory(LoadBalanceLeastInFlight, NewLeastInFlightBalancer)
+	RegisterLoadBalancerFactory(LoadBalanceConsistentHash, NewConsistentHashBalancer)
+}
+
+// RegisterLoadBalancerFactory 注册负载均衡策略
+func RegisterLoadBalancerFactory(strategy string, factory LoadBalancerFactory) {
+	balancerMu.Lock()
+	defer balancerMu.Unlock()
+	balancerFactories[strategy] = factory
+}
+
+// LoadBalancerFactoryBy 获取负载均衡策略的工厂函数
+func LoadBalancerFactoryBy(strategy string) (LoadBalancerFactory, bool) {
+	balancerMu.RLock()
+	defer balancerMu.RUnlock()
+	f, ok := balancerFactories[strategy]
+	return f, ok
+}
+
+func availableTargets(targets []*Target) []*Target {
+	out := make([]*Target, 0, len(targets))
+	for _, t := range targets {
+		if t.Available() {
+			out = append(out, t)
+		}
+	}
+	if len(out) == 0 {
+		return targets
+	}
+	return out
+}
+
+// RoundRobin
+
+type roundRobinBalancer struct {
+	targets []*Target
+	next    uint64
+}
+
+func NewRoundRobinBalancer(targets []*Target) LoadBalancer {
+	return &roundRobinBalancer{targets: targets}
+}
+
+func (b *roundRobinBalancer) Select(_ string) *Target {
+	targets := availableTargets(b.targets)
+	if len(targets) == 0 {
+		return nil
+	}
+	return targets[(atomic.AddUint64(&b.next, 1)-1)%uint64(len(targets))]
+}
+
+// Weighted: 平滑加权轮询
+
+type weightedBalancer struct {
+	targets []*Target
+	current map[*Target]int
+	mu      sync.Mutex
+}
+
+func NewWeightedBalancer(targets []*Target) LoadBalancer {
+	return &weightedBalancer{targets: targets, current: make(map[*Target]int, len(targets))}
+}
+
+func (b *weightedBalancer) Select(_ string) *Target {
+	targets := availableTargets(b.targets)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var best *Target
+	total := 0
+	for _, t := range targets {
+		b.current[t] += t.Weight
+		total += t.Weight
+		if nil == best || b.current[t] > b.current[best] {
+			best = t
+		}
+	}
+	if nil != best {
+		b.current[best] -= total
+	}
+	return best
+}
+
+// LeastInFlight: 最少处理中请求数
+
+type leastInFlightBalancer struct {
+	targets []*Target
+}
+
+func NewLeastInFlightBalancer(targets []*Target) LoadBalancer {
+	return &leastInFlightBalancer{targets: targets}
+}
+
+func (b *leastInFlightBalancer) Select(_ string) *Target {
+	var best *Target
+	for _, t := range availableTargets(b.targets) {
+		if nil == best || t.InFlight() < best.InFlight() {
+			best = t
+		}
+	}
+	return best
+}
+
+// ConsistentHash: 按参数值的一致性Hash；目标实例不可用时，顺延选择下一个可用实例
+
+const virtualNodesPerWeight = 160
+
+type hashNode struct {
+	hash   uint32
+	target *Target
+}
+
+type consistentHashBalancer struct {
+	targets []*Target
+	ring    []hashNode
+}
+
+func NewConsistentHashBalancer(targets []*Target) LoadBalancer {
+	ring := make([]hashNode, 0, len(targets)*virtualNodesPerWeight)
+	for _, t := range targets {
+		for i := 0; i < t.Weight*virtualNodesPerWeight; i++ {
+			ring = append(ring, hashNode{hash: murmur3.Sum32([]byte(t.Host + "#" + strconv.Itoa(i))), target: t})
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool {
+		return ring[i].hash < ring[j].hash
+	})
+	return &consistentHashBalancer{targets: targets, ring: ring}
+}
+
+func (b *consistentHashBalancer) Select(hashKey string) *Target {
+	if len(b.ring) == 0 {
+		return nil
+	}
+	hash := murmur3.Sum32([]byte(hashKey))
+	idx := sort.Search(len(b.ring), func(i int) bool {
+		return b.ring[i].hash >= hash
+	})
+	for i := 0; i < len(b.ring); i++ {
+		node := b.ring[(idx+i)%len(b.ring)]
+		if node.target.Available() {
+			return node.target
+		}
+	}
+	return b.ring[idx%len(b.ring)].target
+}
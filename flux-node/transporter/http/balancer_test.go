@@ -0,0 +1,89 @@
+package http
+
+import (
+	assert2 "github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRoundRobinBalancer_Select(t *testing.T) {
+	assert := assert2.New(t)
+	targets := ParseTargets("a:80,b:80,c:80")
+	lb := NewRoundRobinBalancer(targets)
+	hosts := make([]string, 0, 6)
+	for i := 0; i < 6; i++ {
+		hosts = append(hosts, lb.Select("").Host)
+	}
+	assert.Equal([]string{"a:80", "b:80", "c:80", "a:80", "b:80", "c:80"}, hosts)
+	// 跳过不可用实例
+	targets[1].setHealthy(false, errTest)
+	for i := 0; i < 4; i++ {
+		assert.NotEqual("b:80", lb.Select("").Host)
+	}
+	// 全部不可用时，在全部实例中选择
+	targets[0].setHealthy(false, errTest)
+	targets[2].setHealthy(false, errTest)
+	assert.NotNil(lb.Select(""))
+}
+
+func TestWeightedBalancer_Select(t *testing.T) {
+	assert := assert2.New(t)
+	lb := NewWeightedBalancer(ParseTargets("a:80;weight=3,b:80"))
+	counts := make(map[string]int)
+	for i := 0; i < 40; i++ {
+		counts[lb.Select("").Host]++
+	}
+	assert.Equal(map[string]int{"a:80": 30, "b:80": 10}, counts)
+}
+
+func TestLeastInFlightBalancer_Select(t *testing.T) {
+	assert := assert2.New(t)
+	targets := ParseTargets("a:80,b:80")
+	lb := NewLeastInFlightBalancer(targets)
+	targets[0].acquire()
+	assert.Equal("b:80", lb.Select("").Host)
+	targets[1].acquire()
+	targets[1].acquire()
+	assert.Equal("a:80", lb.Select("").Host)
+	targets[1].release()
+	targets[1].release()
+	targets[0].release()
+	assert.Equal(int64(0), targets[0].InFlight())
+}
+
+func TestConsistentHashBalancer_Select(t *testing.T) {
+	assert := assert2.New(t)
+	targets := ParseTargets("a:80,b:80,c:80")
+	lb := NewConsistentHashBalancer(targets)
+	selected := make(map[string]*Target)
+	counts := make(map[string]int)
+	for i := 0; i < 300; i++ {
+		key := "user-" + strconv.Itoa(i)
+		target := lb.Select(key)
+		selected[key] = target
+		counts[target.Host]++
+		// 相同Key选择相同实例
+		assert.Equal(target, lb.Select(key))
+	}
+	assert.Equal(3, len(counts))
+	for _, n := range counts {
+		assert.True(n > 50)
+	}
+	// 实例被剔除时，只有该实例的Key迁移到其它实例
+	targets[0].ejectUntil = time.Now().Add(time.Minute).UnixNano()
+	for key, prev := range selected {
+		target := lb.Select(key)
+		if prev == targets[0] {
+			assert.True(targets[0] != target)
+		} else {
+			assert.Equal(prev, target)
+		}
+	}
+}
+
+func TestUpstream_BalancerFallback(t *testing.T) {
+	up := &Upstream{Targets: ParseTargets("a:80"), balancers: make(map[string]LoadBalancer)}
+	_, ok := up.Balancer("unknown").(*roundRobinBalancer)
+	assert2.True(t, ok)
+}
@@ -0,0 +1,252 @@
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/spf13/cast"
+	"golang.org/x/net/http2"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HttpClient配置项；同名的服务属性可覆盖全局配置
+const (
+	ConfigKeyTimeout               = "timeout"
+	ConfigKeyMaxIdleConns          = "max_idle_conns"
+	ConfigKeyMaxIdleConnsPerHost   = "max_idle_conns_per_host"
+	ConfigKeyMaxConnsPerHost       = "max_conns_per_host"
+	ConfigKeyIdleConnTimeout       = "idle_conn_timeout"
+	ConfigKeyDialTimeout           = "dial_timeout"
+	ConfigKeyKeepAlive             = "keepalive"
+	ConfigKeyTLSHandshakeTimeout   = "tls_handshake_timeout"
+	ConfigKeyResponseHeaderTimeout = "response_header_timeout"
+	ConfigKeyHttp2Enable           = "http2_enable"
+	ConfigKeyProxy                 = "proxy"
+	ConfigKeyTLSCAFiles            = "tls_ca_files"
+	ConfigKeyTLSCertFile           = "tls_cert_file"
+	ConfigKeyTLSKeyFile            = "tls_key_file"
+	ConfigKeyTLSInsecureSkipVerify = "tls_insecure_skip_verify"
+)
+
+var clientConfigKeys = []string{
+	ConfigKeyTimeout, ConfigKeyMaxIdleConns, ConfigKeyMaxIdleConnsPerHost, ConfigKeyMaxConnsPerHost,
+	ConfigKeyIdleConnTimeout, ConfigKeyDialTimeout, ConfigKeyKeepAlive, ConfigKeyTLSHandshakeTimeout,
+	ConfigKeyResponseHeaderTimeout, ConfigKeyHttp2Enable, ConfigKeyProxy, ConfigKeyTLSCAFiles,
+	ConfigKeyTLSCertFile, ConfigKeyTLSKeyFile, ConfigKeyTLSInsecureSkipVerify,
+}
+
+// ClientConfig 构建HttpClient的连接池配置
+type ClientConfig struct {
+	Timeout               time.Duration
+	MaxIdleConns          int
+	MaxIdleConnsPerHost   int
+	MaxConnsPerHost       int
+	IdleConnTimeout       time.Duration
+	DialTimeout           time.Duration
+	KeepAlive             time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	Http2Enable           bool
+	Proxy                 string // 代理地址；env 表示使用环境变量配置；
+	TLSCAFiles            []string
+	TLSCertFile           string
+	TLSKeyFile            string
+	TLSInsecureSkipVerify bool
+}
+
+// DefaultClientConfig 返回默认的HttpClient配置
+func DefaultClientConfig() ClientConfig {
+	return ClientConfig{
+		Timeout:             time.Second * 10,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     time.Second * 90,
+		DialTimeout:         time.Second * 30,
+		KeepAlive:           time.Second * 30,
+		TLSHandshakeTimeout: time.Second * 10,
+		Http2Enable:         true,
+		Proxy:               "env",
+	}
+}
+
+// NewClientConfig 从配置中读取HttpClient配置，未配置项使用默认值
+func NewClientConfig(config *flux.Configuration) ClientConfig {
+	return DefaultClientConfig().override(func(key string) (interface{}, bool) {
+		if !config.IsSet(key) {
+			return nil, false
+		}
+		return config.Get(key), true
+	})
+}
+
+// WithAttributes 使用服务属性覆盖HttpClient配置
+func (c ClientConfig) WithAttributes(service *flux.TransporterService) ClientConfig {
+	return c.override(func(key string) (interface{}, bool) {
+		if attr, ok := service.GetAttrEx(key); ok {
+			return attr.Value, true
+		}
+		return nil, false
+	})
+}
+
+// HasOverrides 判断服务是否通过属性覆盖了HttpClient配置
+func HasOverrides(service *flux.TransporterService) bool {
+	for _, key := range clientConfigKeys {
+		if service.HasAttr(key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c ClientConfig) override(lookup func(key string) (interface{}, bool)) ClientConfig {
+	durations := map[string]*time.Duration{
+		ConfigKeyTimeout:               &c.Timeout,
+		ConfigKeyIdleConnTimeout:       &c.IdleConnTimeout,
+		ConfigKeyDialTimeout:           &c.DialTimeout,
+		ConfigKeyKeepAlive:             &c.KeepAlive,
+		ConfigKeyTLSHandshakeTimeout:   &c.TLSHandshakeTimeout,
+		ConfigKeyResponseHeaderTimeout: &c.ResponseHeaderTimeout,
+	}
+	for key, ptr := range durations {
+		if v, ok := lookup(key); ok {
+			*ptr = cast.ToDuration(v)
+		}
+	}
+	ints := map[string]*int{
+		ConfigKeyMaxIdleConns:        &c.MaxIdleConns,
+		ConfigKeyMaxIdleConnsPerHost: &c.MaxIdleConnsPerHost,
+		ConfigKeyMaxConnsPerHost:     &c.MaxConnsPerHost,
+	}
+	for key, ptr := range ints {
+		if v, ok := lookup(key); ok {
+			*ptr = cast.ToInt(v)
+		}
+	}
+	if v, ok := lookup(ConfigKeyHttp2Enable); ok {
+		c.Http2Enable = cast.ToBool(v)
+	}
+	if v, ok := lookup(ConfigKeyTLSInsecureSkipVerify); ok {
+		c.TLSInsecureSkipVerify = cast.ToBool(v)
+	}
+	if v, ok := lookup(ConfigKeyProxy); ok {
+		c.Proxy = cast.ToString(v)
+	}
+	if v, ok := lookup(ConfigKeyTLSCertFile); ok {
+		c.TLSCertFile = cast.ToString(v)
+	}
+	if v, ok := lookup(ConfigKeyTLSKeyFile); ok {
+		c.TLSKeyFile = cast.ToString(v)
+	}
+	if v, ok := lookup(ConfigKeyTLSCAFiles); ok {
+		if str, isStr := v.(string); isStr {
+			c.TLSCAFiles = strings.Split(str, ",")
+		} else {
+			c.TLSCAFiles = cast.ToStringSlice(v)
+		}
+	}
+	return c
+}
+
+// Key 返回配置的唯一标识，用于缓存HttpClient
+func (c ClientConfig) Key() string {
+	return fmt.Sprintf("%+v", c)
+}
+
+// NewHttpClient 根据配置构建HttpClient
+func NewHttpClient(c ClientConfig) (*http.Client, error) {
+	tlsConfig, err := c.newTLSConfig()
+	if nil != err {
+		return nil, err
+	}
+	transport := &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout:   c.DialTimeout,
+			KeepAlive: c.KeepAlive,
+		}).DialContext,
+		MaxIdleConns:          c.MaxIdleConns,
+		MaxIdleConnsPerHost:   c.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       c.MaxConnsPerHost,
+		IdleConnTimeout:       c.IdleConnTimeout,
+		TLSHandshakeTimeout:   c.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: c.ResponseHeaderTimeout,
+		TLSClientConfig:       tlsConfig,
+	}
+	switch strings.ToLower(c.Proxy) {
+	case "env":
+		transport.Proxy = http.ProxyFromEnvironment
+	case "", "none":
+		transport.Proxy = nil
+	default:
+		proxy, err := url.Parse(c.Proxy)
+		if nil != err {
+			return nil, fmt.Errorf("invalid http proxy: %s, err: %w", c.Proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	if c.Http2Enable {
+		if err := http2.ConfigureTransport(transport); nil != err {
+			return nil, fmt.Errorf("configure http2 transport, err: %w", err)
+		}
+	}
+	return &http.Client{Timeout: c.Timeout, Transport: transport}, nil
+}
+
+func (c ClientConfig) newTLSConfig() (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: c.TLSInsecureSkipVerify}
+	if len(c.TLSCAFiles) > 0 {
+		pool, err := x509.SystemCertPool()
+		if nil != err || nil == pool {
+			pool = x509.NewCertPool()
+		}
+		for _, file := range c.TLSCAFiles {
+			if file = strings.TrimSpace(file); "" == file {
+				continue
+			}
+			data, err := ioutil.ReadFile(file)
+			if nil != err {
+				return nil, fmt.Errorf("read ca file: %s, err: %w", file, err)
+			}
+			if !pool.AppendCertsFromPEM(data) {
+				return nil, fmt.Errorf("invalid ca file: %s", file)
+			}
+		}
+		config.RootCAs = pool
+	}
+	if "" != c.TLSCertFile || "" != c.TLSKeyFile {
+		cert, err := tls.LoadX509KeyPair(c.TLSCertFile, c.TLSKeyFile)
+		if nil != err {
+			return nil, fmt.Errorf("load client certificate, cert: %s, key: %s, err: %w", c.TLSCertFile, c.TLSKeyFile, err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// clientPool 按配置缓存HttpClient，用于服务属性覆盖配置的场景
+type clientPool struct {
+	clients map[string]*http.Client
+	mu      sync.Mutex
+}
+
+func (p *clientPool) load(c ClientConfig) (*http.Client, error) {
+	key := c.Key()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if client, ok := p.clients[key]; ok {
+		return client, nil
+	}
+	client, err := NewHttpClient(c)
+	if nil != err {
+		return nil, err
+	}
+	p.clients[key] = client
+	return client, nil
+}
@@ -0,0 +1,171 @@
+package http
+
+import (
+	"encoding/pem"
+	"github.com/bytepowered/flux/flux-node"
+	assert2 "github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestNewClientConfig(t *testing.T) {
+	assert := assert2.New(t)
+	// 未配置项使用默认值
+	assert.Equal(DefaultClientConfig(), NewClientConfig(flux.NewConfigurationOfMap(map[string]interface{}{})))
+	c := NewClientConfig(flux.NewConfigurationOfMap(map[string]interface{}{
+		ConfigKeyTimeout:             "3s",
+		ConfigKeyMaxIdleConnsPerHost: "32",
+		ConfigKeyMaxConnsPerHost:     64,
+		ConfigKeyHttp2Enable:         false,
+		ConfigKeyProxy:               "none",
+		ConfigKeyTLSCAFiles:          []string{"a.pem", "b.pem"},
+	}))
+	assert.Equal(3*time.Second, c.Timeout)
+	assert.Equal(32, c.MaxIdleConnsPerHost)
+	assert.Equal(64, c.MaxConnsPerHost)
+	assert.Equal(100, c.MaxIdleConns)
+	assert.False(c.Http2Enable)
+	assert.Equal("none", c.Proxy)
+	assert.Equal([]string{"a.pem", "b.pem"}, c.TLSCAFiles)
+}
+
+func TestClientConfig_WithAttributes(t *testing.T) {
+	assert := assert2.New(t)
+	service := &flux.TransporterService{}
+	assert.False(HasOverrides(service))
+	assert.Equal(DefaultClientConfig(), DefaultClientConfig().WithAttributes(service))
+	service.Attributes = []flux.Attribute{
+		{Name: ConfigKeyTimeout, Value: "500ms"},
+		{Name: ConfigKeyTLSCAFiles, Value: "a.pem,b.pem"},
+		{Name: ConfigKeyTLSInsecureSkipVerify, Value: "true"},
+	}
+	assert.True(HasOverrides(service))
+	c := DefaultClientConfig().WithAttributes(service)
+	assert.Equal(500*time.Millisecond, c.Timeout)
+	assert.Equal([]string{"a.pem", "b.pem"}, c.TLSCAFiles)
+	assert.True(c.TLSInsecureSkipVerify)
+	assert.Equal(DefaultClientConfig().MaxIdleConns, c.MaxIdleConns)
+	assert.True(c.Key() != DefaultClientConfig().Key())
+}
+
+func TestNewHttpClient(t *testing.T) {
+	assert := assert2.New(t)
+	config := DefaultClientConfig()
+	config.Timeout = 2 * time.Second
+	config.MaxConnsPerHost = 8
+	client, err := NewHttpClient(config)
+	assert.NoError(err)
+	assert.Equal(2*time.Second, client.Timeout)
+	transport := client.Transport.(*http.Transport)
+	assert.Equal(8, transport.MaxConnsPerHost)
+	assert.NotNil(transport.Proxy)
+	// http2_enable 配置h2协议
+	assert.NotNil(transport.TLSNextProto["h2"])
+	config.Http2Enable = false
+	config.Proxy = "none"
+	client, err = NewHttpClient(config)
+	assert.NoError(err)
+	transport = client.Transport.(*http.Transport)
+	assert.Nil(transport.TLSNextProto["h2"])
+	assert.Nil(transport.Proxy)
+	config.Proxy = "http://127.0.0.1:3128"
+	client, err = NewHttpClient(config)
+	assert.NoError(err)
+	proxy, err := client.Transport.(*http.Transport).Proxy(httptest.NewRequest("GET", "http://example.com", nil))
+	assert.NoError(err)
+	assert.Equal("127.0.0.1:3128", proxy.Host)
+	config.Proxy = "http://%zz"
+	_, err = NewHttpClient(config)
+	assert.Error(err)
+}
+
+// newCAFile 将TLS测试服务的证书写入临时的CA证书文件
+func newCAFile(t *testing.T, server *httptest.Server) string {
+	file, err := ioutil.TempFile("", "flux-ca-*.pem")
+	if nil != err {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = os.Remove(file.Name())
+	})
+	_ = pem.Encode(file, &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	_ = file.Close()
+	return file.Name()
+}
+
+func TestNewHttpClient_TLS(t *testing.T) {
+	assert := assert2.New(t)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	caFile := newCAFile(t, server)
+	config := DefaultClientConfig()
+	config.Proxy = "none"
+	// 未信任的证书
+	client, err := NewHttpClient(config)
+	assert.NoError(err)
+	_, err = client.Get(server.URL)
+	assert.Error(err)
+	// 自定义CA证书
+	config.TLSCAFiles = []string{caFile}
+	client, err = NewHttpClient(config)
+	assert.NoError(err)
+	resp, err := client.Get(server.URL)
+	assert.NoError(err)
+	data, _ := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	assert.Equal("ok", string(data))
+	// 无效的证书文件
+	config.TLSCAFiles = []string{caFile + ".missing"}
+	_, err = NewHttpClient(config)
+	assert.Error(err)
+	config.TLSCAFiles = nil
+	config.TLSCertFile = caFile
+	_, err = NewHttpClient(config)
+	assert.Error(err)
+}
+
+func TestRpcTransporter_HttpClientOf(t *testing.T) {
+	assert := assert2.New(t)
+	tr := NewRpcHttpTransporterWith()
+	assert.NoError(tr.Init(flux.NewConfigurationOfMap(map[string]interface{}{
+		ConfigKeyTimeout: "3s",
+	})))
+	service := &flux.TransporterService{}
+	client, err := tr.HttpClientOf(service)
+	assert.NoError(err)
+	assert.Equal(3*time.Second, client.Timeout)
+	// 服务属性覆盖配置时，使用独立并缓存的HttpClient
+	service.Attributes = []flux.Attribute{{Name: ConfigKeyTimeout, Value: "1s"}}
+	custom, err := tr.HttpClientOf(service)
+	assert.NoError(err)
+	assert.Equal(time.Second, custom.Timeout)
+	assert.True(custom != client)
+	cached, err := tr.HttpClientOf(&flux.TransporterService{EmbeddedAttributes: service.EmbeddedAttributes})
+	assert.NoError(err)
+	assert.True(custom == cached)
+	service.Attributes = []flux.Attribute{{Name: ConfigKeyTLSCAFiles, Value: "missing.pem"}}
+	_, err = tr.HttpClientOf(service)
+	assert.Error(err)
+}
+
+func TestRpcTransporter_CustomizedHttpClient(t *testing.T) {
+	assert := assert2.New(t)
+	client := &http.Client{}
+	tr := NewRpcHttpTransporterWith(WithHttpClient(client))
+	assert.NoError(tr.Init(flux.NewConfigurationOfMap(map[string]interface{}{
+		ConfigKeyTimeout: "3s",
+	})))
+	// 自定义HttpClient不被配置和服务属性替换
+	service := &flux.TransporterService{EmbeddedAttributes: flux.EmbeddedAttributes{Attributes: []flux.Attribute{
+		{Name: ConfigKeyTimeout, Value: "1s"},
+	}}}
+	actual, err := tr.HttpClientOf(service)
+	assert.NoError(err)
+	assert.True(client == actual)
+}
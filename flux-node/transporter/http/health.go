@@ -0,0 +1,128 @@
+package http
+
+import (
+	"fmt"
+	"github.com/bytepowered/flux/flux-node/logger"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	ConfigKeyHealthCheckEnable    = "health_check.enable"
+	ConfigKeyHealthCheckInterval  = "health_check.interval"
+	ConfigKeyHealthCheckTimeout   = "health_check.timeout"
+	ConfigKeyHealthCheckPath      = "health_check.path"
+	ConfigKeyOutlierConsecutive   = "outlier.consecutive_errors"
+	ConfigKeyOutlierEjectDuration = "outlier.eject_duration"
+)
+
+// HealthChecker 定时主动探测后端服务实例的健康状态
+type HealthChecker struct {
+	upstreams *Upstreams
+	client    *http.Client
+	interval  time.Duration
+	path      string
+	stop      chan struct{}
+	once      sync.Once
+}
+
+// NewHealthChecker 创建健康检查；探测请求使用指定的HttpClient，与服务调用共享TLS证书和代理配置；
+func NewHealthChecker(upstreams *Upstreams, client *http.Client, interval time.Duration, path string) *HealthChecker {
+	return &HealthChecker{
+		upstreams: upstreams,
+		client:    client,
+		interval:  interval,
+		path:      path,
+		stop:      make(chan struct{}),
+	}
+}
+
+// Start 启动定时健康检查
+func (c *HealthChecker) Start() {
+	go func() {
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.CheckAll()
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止定时健康检查
+func (c *HealthChecker) Stop() {
+	c.once.Do(func() {
+		close(c.stop)
+	})
+}
+
+// CheckAll 探测全部后端服务实例
+func (c *HealthChecker) CheckAll() {
+	wg := new(sync.WaitGroup)
+	for _, up := range c.upstreams.Upstreams() {
+		path := up.HealthPath
+		if "" == path {
+			path = c.path
+		}
+		for _, target := range up.Targets {
+			wg.Add(1)
+			go func(scheme string, target *Target) {
+				defer wg.Done()
+				err := c.probe(scheme, target.Host, path)
+				if healthy := nil == err; healthy != (atomic.LoadInt32(&target.unhealthy) == 0) {
+					logger.Infow("TRANSPORTER:HTTP:HEALTH_CHANGED", "host", target.Host, "healthy", healthy, "error", err)
+				}
+				target.setHealthy(nil == err, err)
+			}(up.Scheme, target)
+		}
+	}
+	wg.Wait()
+}
+
+func (c *HealthChecker) probe(scheme, host, path string) error {
+	if "" == scheme {
+		scheme = "http"
+	}
+	resp, err := c.client.Get(scheme + "://" + host + path)
+	if nil != err {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("unhealthy status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// OutlierDetector 被动检测：目标实例连续失败达到阈值时，在指定时间内将其剔除
+type OutlierDetector struct {
+	consecutive int32
+	duration    time.Duration
+}
+
+func NewOutlierDetector(consecutive int, duration time.Duration) *OutlierDetector {
+	return &OutlierDetector{consecutive: int32(consecutive), duration: duration}
+}
+
+// Report 报告目标实例的请求结果
+func (d *OutlierDetector) Report(target *Target, err error) {
+	if nil == err {
+		atomic.StoreInt32(&target.failures, 0)
+		return
+	}
+	target.lastError.Store(err.Error())
+	if d.consecutive <= 0 {
+		return
+	}
+	if failures := atomic.AddInt32(&target.failures, 1); failures >= d.consecutive {
+		atomic.StoreInt32(&target.failures, 0)
+		atomic.StoreInt64(&target.ejectUntil, time.Now().Add(d.duration).UnixNano())
+		logger.Warnw("TRANSPORTER:HTTP:OUTLIER_EJECTED", "host", target.Host, "failures", failures, "duration", d.duration, "error", err)
+	}
+}
@@ -0,0 +1,79 @@
+package http
+
+import (
+	"errors"
+	"github.com/bytepowered/flux/flux-node"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var errTest = errors.New("test error")
+
+func TestHealthChecker_CheckAll(t *testing.T) {
+	assert := assert2.New(t)
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer healthy.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	ups := NewUpstreams()
+	hosts := strings.TrimPrefix(healthy.URL, "http://") + "," + strings.TrimPrefix(failing.URL, "http://")
+	up := ups.Load("svc", "http", hosts, "/health")
+	checker := NewHealthChecker(ups, &http.Client{Timeout: time.Second}, time.Second, "/")
+	checker.CheckAll()
+	assert.True(up.Targets[0].Available())
+	assert.False(up.Targets[1].Available())
+	for i := 0; i < 4; i++ {
+		assert.Equal(up.Targets[0], up.Select(LoadBalanceRoundRobin, ""))
+	}
+	states := ups.States()
+	assert.Equal(2, len(states))
+}
+
+func TestOutlierDetector_Report(t *testing.T) {
+	assert := assert2.New(t)
+	target := ParseTargets("a:80")[0]
+	d := NewOutlierDetector(2, time.Minute)
+	d.Report(target, errTest)
+	assert.True(target.Available())
+	// 成功请求重置连续失败计数
+	d.Report(target, nil)
+	d.Report(target, errTest)
+	assert.True(target.Available())
+	d.Report(target, errTest)
+	assert.False(target.Available())
+}
+
+func TestRpcTransporter_HealthCheckTLS(t *testing.T) {
+	assert := assert2.New(t)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "https://")
+	newChecker := func(config map[string]interface{}) *HealthChecker {
+		config[ConfigKeyHealthCheckEnable] = true
+		config[ConfigKeyProxy] = "none"
+		tr := NewRpcHttpTransporterWith()
+		tr.upstreams = NewUpstreams()
+		assert.NoError(tr.Init(flux.NewConfigurationOfMap(config)))
+		return tr.checker
+	}
+	// 未信任的证书
+	checker := newChecker(map[string]interface{}{})
+	up := checker.upstreams.Load("svc", "https", host+","+host, "/health")
+	checker.CheckAll()
+	assert.False(up.Targets[0].Available())
+	// 健康检查使用服务调用的CA证书配置
+	checker = newChecker(map[string]interface{}{ConfigKeyTLSCAFiles: []string{newCAFile(t, server)}})
+	up = checker.upstreams.Load("svc", "https", host+","+host, "/health")
+	checker.CheckAll()
+	assert.True(up.Targets[0].Available())
+}
@@ -1,22 +1,31 @@
 package http
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/bytepowered/flux/flux-node"
 	"github.com/bytepowered/flux/flux-node/ext"
+	"github.com/bytepowered/flux/flux-node/logger"
 	"github.com/bytepowered/flux/flux-node/transporter"
 	"github.com/spf13/cast"
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 )
 
+const (
+	ConfigKeyStreamEnable = "stream_enable"
+)
+
 func init() {
 	ext.RegisterTransporter(flux.ProtoHttp, NewRpcHttpTransporter())
 }
 
 var _ flux.Transporter = new(RpcTransporter)
+var _ flux.ServiceEventListener = new(RpcTransporter)
 
 type (
 	// Option 配置函数
@@ -30,6 +39,13 @@ type RpcTransporter struct {
 	codec       flux.TransportCodec
 	writer      flux.TransportWriter
 	argResolver ArgumentResolver
+	upstreams   *Upstreams
+	checker     *HealthChecker
+	outlier     *OutlierDetector
+	// HttpClient连接池配置
+	customized bool
+	clientConf ClientConfig
+	clients    *clientPool
 }
 
 func (b *RpcTransporter) Writer() flux.TransportWriter {
@@ -41,8 +57,12 @@ func NewRpcHttpTransporter() *RpcTransporter {
 		httpClient: &http.Client{
 			Timeout: time.Second * 10,
 		},
-		codec:  NewTransportCodecFunc(),
-		writer: new(transporter.DefaultTransportWriter),
+		codec:       NewTransportCodecFunc(),
+		writer:      NewStreamTransportWriter(false),
+		argResolver: DefaultArgumentResolver,
+		upstreams:   defaultUpstreams,
+		clientConf:  DefaultClientConfig(),
+		clients:     &clientPool{clients: make(map[string]*http.Client, 4)},
 	}
 }
 
@@ -51,7 +71,12 @@ func NewRpcHttpTransporterWith(opts ...Option) *RpcTransporter {
 		httpClient: &http.Client{
 			Timeout: time.Second * 10,
 		},
-		codec: NewTransportCodecFunc(),
+		codec:       NewTransportCodecFunc(),
+		writer:      NewStreamTransportWriter(false),
+		argResolver: DefaultArgumentResolver,
+		upstreams:   defaultUpstreams,
+		clientConf:  DefaultClientConfig(),
+		clients:     &clientPool{clients: make(map[string]*http.Client, 4)},
 	}
 	for _, opt := range opts {
 		opt(bts)
@@ -59,10 +84,11 @@ func NewRpcHttpTransporterWith(opts ...Option) *RpcTransporter {
 	return bts
 }
 
-// WithHttpClient 用于配置HttpClient客户端
+// WithHttpClient 用于配置HttpClient客户端；配置后，不再使用配置文件构建HttpClient；
 func WithHttpClient(client *http.Client) Option {
 	return func(s *RpcTransporter) {
 		s.httpClient = client
+		s.customized = true
 	}
 }
 
@@ -87,6 +113,71 @@ func WithTransportWriter(fun flux.TransportWriter) Option {
 	}
 }
 
+// Init init transporter
+func (b *RpcTransporter) Init(config *flux.Configuration) error {
+	// HttpClient连接池
+	b.clientConf = NewClientConfig(config)
+	if !b.customized {
+		client, err := NewHttpClient(b.clientConf)
+		if nil != err {
+			return err
+		}
+		b.httpClient = client
+		logger.Infow("Http transporter client", "config", b.clientConf)
+	}
+	// 流式透传模式：上游响应直接复制到客户端
+	if w, ok := b.writer.(*StreamTransportWriter); ok {
+		w.streaming = config.GetBool(ConfigKeyStreamEnable)
+		logger.Infow("Http transporter response streaming", "enable", w.streaming)
+	}
+	// 多实例地址的主动健康检查与被动熔断剔除
+	config.SetDefaults(map[string]interface{}{
+		ConfigKeyHealthCheckEnable:    false,
+		ConfigKeyHealthCheckInterval:  "10s",
+		ConfigKeyHealthCheckTimeout:   "2s",
+		ConfigKeyHealthCheckPath:      "/",
+		ConfigKeyOutlierConsecutive:   5,
+		ConfigKeyOutlierEjectDuration: "30s",
+	})
+	b.outlier = NewOutlierDetector(config.GetInt(ConfigKeyOutlierConsecutive), config.GetDuration(ConfigKeyOutlierEjectDuration))
+	if config.GetBool(ConfigKeyHealthCheckEnable) {
+		client, err := b.newProbeClient(config.GetDuration(ConfigKeyHealthCheckTimeout))
+		if nil != err {
+			return err
+		}
+		b.checker = NewHealthChecker(b.upstreams, client, config.GetDuration(ConfigKeyHealthCheckInterval),
+			config.GetString(ConfigKeyHealthCheckPath))
+	}
+	logger.Infow("Http transporter upstream health check", "enable", nil != b.checker)
+	return nil
+}
+
+// newProbeClient 创建健康检查的HttpClient：使用服务调用的连接池配置，超时时间为健康检查超时时间
+func (b *RpcTransporter) newProbeClient(timeout time.Duration) (*http.Client, error) {
+	if b.customized {
+		return &http.Client{Transport: b.httpClient.Transport, Timeout: timeout}, nil
+	}
+	probe := b.clientConf
+	probe.Timeout = timeout
+	return NewHttpClient(probe)
+}
+
+// Startup startup transporter
+func (b *RpcTransporter) Startup() error {
+	if nil != b.checker {
+		b.checker.Start()
+	}
+	return nil
+}
+
+// Shutdown shutdown transporter
+func (b *RpcTransporter) Shutdown(_ context.Context) error {
+	if nil != b.checker {
+		b.checker.Stop()
+	}
+	return nil
+}
+
 func (b *RpcTransporter) Transport(ctx *flux.Context) {
 	transporter.DoTransport(ctx, b)
 }
@@ -110,9 +201,38 @@ func (b *RpcTransporter) InvokeCodec(ctx *flux.Context, service flux.Transporter
 }
 
 func (b *RpcTransporter) Invoke(ctx *flux.Context, service flux.TransporterService) (interface{}, *flux.ServeError) {
-	body, _ := ctx.BodyReader()
-	newRequest, err := b.argResolver(&service, ctx.URL(), body, ctx)
+	// 多实例地址：按负载均衡策略选择目标实例
+	target := b.SelectTarget(&service, ctx)
+	if nil == target {
+		return b.invoke(ctx, service)
+	}
+	service.RemoteHost = target.Host
+	target.acquire()
+	resp, serr := b.invoke(ctx, service)
+	if r, ok := resp.(*http.Response); ok {
+		if nil != b.outlier {
+			b.outlier.Report(target, upstreamError(resp, serr))
+		}
+		// 响应Body可能以流式透传，在Body关闭时才释放实例的请求计数
+		r.Body = &releaseBody{ReadCloser: r.Body, release: target.release}
+	} else {
+		if nil != b.outlier && nil != serr && flux.ErrorCodeGatewayTransporter == serr.ErrorCode {
+			b.outlier.Report(target, upstreamError(resp, serr))
+		}
+		target.release()
+	}
+	return resp, serr
+}
+
+func (b *RpcTransporter) invoke(ctx *flux.Context, service flux.TransporterService) (interface{}, *flux.ServeError) {
+	body, err := ctx.BodyReader()
 	if nil != err {
+		return nil, flux.NewRequestBodyError(err)
+	}
+	newRequest, err := b.argResolver(&service, ctx.URL(), body, ctx)
+	if errors.Is(err, flux.ErrRequestBodyTooLarge) {
+		return nil, flux.NewRequestBodyError(err)
+	} else if nil != err {
 		return nil, &flux.ServeError{
 			StatusCode: flux.StatusServerError,
 			ErrorCode:  flux.ErrorCodeGatewayInternal,
@@ -123,13 +243,83 @@ func (b *RpcTransporter) Invoke(ctx *flux.Context, service flux.TransporterServi
 	return b.ExecuteRequest(newRequest, service, ctx)
 }
 
-func (b *RpcTransporter) ExecuteRequest(newRequest *http.Request, _ flux.TransporterService, ctx *flux.Context) (interface{}, *flux.ServeError) {
+// SelectTarget 从RemoteHost定义的地址列表中选择目标实例；RemoteHost包含路径模板时，不做选择；
+func (b *RpcTransporter) SelectTarget(service *flux.TransporterService, ctx *flux.Context) *Target {
+	if nil == b.upstreams || "" == service.RemoteHost || strings.Contains(service.RemoteHost, "{") {
+		return nil
+	}
+	up := b.upstreams.Load(service.ServiceID(), service.Scheme, service.RemoteHost, service.GetAttr(ServiceAttrTagHealthPath).GetString())
+	strategy := service.GetAttr(ServiceAttrTagLoadBalance).GetString()
+	hashKey := ""
+	if LoadBalanceConsistentHash == strategy {
+		name := service.GetAttr(ServiceAttrTagHashKey).GetString()
+		for _, arg := range service.Arguments {
+			if arg.Name != name {
+				continue
+			}
+			if val, err := arg.Resolve(ctx); nil == err {
+				hashKey = cast.ToString(val)
+			} else {
+				ctx.Logger().Warnw("TRANSPORTER:HTTP:HASH_KEY", "argument", name, "error", err)
+			}
+			break
+		}
+	}
+	return up.Select(strategy, hashKey)
+}
+
+func upstreamError(resp interface{}, serr *flux.ServeError) error {
+	if nil != serr {
+		return serr.CauseError
+	}
+	if r, ok := resp.(*http.Response); ok && r.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("upstream status: %d", r.StatusCode)
+	}
+	return nil
+}
+
+// OnServiceEvent 后端服务定义删除或者地址变更时，删除不再使用的Upstream
+func (b *RpcTransporter) OnServiceEvent(event flux.ServiceEvent) {
+	if nil == b.upstreams {
+		return
+	}
+	switch event.EventType {
+	case flux.EventTypeUpdated:
+		b.upstreams.Evict(event.Service.ServiceID(), event.Service.Scheme, event.Service.RemoteHost)
+	case flux.EventTypeRemoved:
+		b.upstreams.Evict(event.Service.ServiceID(), "", "")
+	}
+}
+
+// HttpClientOf 返回服务使用的HttpClient；服务属性覆盖了连接池配置时，使用独立的HttpClient；
+func (b *RpcTransporter) HttpClientOf(service *flux.TransporterService) (*http.Client, error) {
+	if b.customized || !HasOverrides(service) {
+		return b.httpClient, nil
+	}
+	return b.clients.load(b.clientConf.WithAttributes(service))
+}
+
+func (b *RpcTransporter) ExecuteRequest(newRequest *http.Request, service flux.TransporterService, ctx *flux.Context) (interface{}, *flux.ServeError) {
+	client, err := b.HttpClientOf(&service)
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageHttpAssembleFailed,
+			CauseError: err,
+		}
+	}
 	// Header透传以及传递AttrValues
-	newRequest.Header = ctx.HeaderVars()
+	// 参数封装函数设置的Header(如Content-Type)优先于原始请求Header
+	header := ctx.HeaderVars().Clone()
+	for k, v := range newRequest.Header {
+		header[k] = v
+	}
+	newRequest.Header = header
 	for k, v := range ctx.Attributes() {
 		newRequest.Header.Set(k, cast.ToString(v))
 	}
-	resp, err := b.httpClient.Do(newRequest)
+	resp, err := client.Do(newRequest)
 	if nil != err {
 		msg := flux.ErrorMessageHttpInvokeFailed
 		if uErr, ok := err.(*url.Error); ok {
@@ -0,0 +1,36 @@
+package http
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/internal/fluxtest"
+	assert2 "github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestRpcTransporter_InvokeBodyTooLarge(t *testing.T) {
+	assert := assert2.New(t)
+	ctx := fluxtest.NewContext(httptest.NewRequest("POST", "http://mocking/upload", nil))
+	ctx.Request().GetBody = func() (io.ReadCloser, error) {
+		return nil, flux.ErrRequestBodyTooLarge
+	}
+	tr := NewRpcHttpTransporterWith()
+	_, serr := tr.Invoke(ctx, flux.TransporterService{
+		RemoteHost: "127.0.0.1:1", Interface: "/upload", Method: "POST", Scheme: "http",
+	})
+	assert.NotNil(serr)
+	assert.Equal(flux.StatusTooLarge, serr.StatusCode)
+	assert.Equal(flux.ErrorMessageRequestTooLarge, serr.Message)
+}
+
+func TestDefaultArgumentResolver_NilBody(t *testing.T) {
+	assert := assert2.New(t)
+	ctx := fluxtest.NewContext(httptest.NewRequest("POST", "http://mocking/api", nil))
+	service := flux.TransporterService{RemoteHost: "127.0.0.1:8080", Interface: "/api", Method: "POST", Scheme: "http"}
+	req, err := DefaultArgumentResolver(&service, &url.URL{Path: "/api"}, nil, ctx)
+	assert.NoError(err)
+	assert.Equal(http.NoBody, req.Body)
+}
@@ -0,0 +1,241 @@
+package http
+
+import (
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// ServiceAttrTagLoadBalance 定义Http服务多个目标地址的负载均衡策略
+	ServiceAttrTagLoadBalance = "load_balance"
+	// ServiceAttrTagHashKey 定义一致性Hash负载均衡策略使用的参数名
+	ServiceAttrTagHashKey = "hash_key"
+	// ServiceAttrTagHealthPath 定义主动健康检查的请求路径
+	ServiceAttrTagHealthPath = "health_path"
+)
+
+// Target 后端服务的实例地址及其运行状态
+type Target struct {
+	Host       string // 目标地址：host:port
+	Weight     int    // 权重
+	inflight   int64
+	unhealthy  int32
+	failures   int32
+	ejectUntil int64
+	lastError  atomic.Value
+}
+
+// Available 判断目标实例是否可用：健康检查通过，并且未被熔断剔除
+func (t *Target) Available() bool {
+	return atomic.LoadInt32(&t.unhealthy) == 0 && time.Now().UnixNano() >= atomic.LoadInt64(&t.ejectUntil)
+}
+
+// InFlight 返回目标实例正在处理的请求数
+func (t *Target) InFlight() int64 {
+	return atomic.LoadInt64(&t.inflight)
+}
+
+func (t *Target) acquire() {
+	atomic.AddInt64(&t.inflight, 1)
+}
+
+func (t *Target) release() {
+	atomic.AddInt64(&t.inflight, -1)
+}
+
+func (t *Target) setHealthy(healthy bool, err error) {
+	if healthy {
+		atomic.StoreInt32(&t.unhealthy, 0)
+		t.lastError.Store("")
+	} else {
+		atomic.StoreInt32(&t.unhealthy, 1)
+		t.lastError.Store(err.Error())
+	}
+}
+
+// Upstream 一组后端服务实例，由TransporterService的RemoteHost定义的地址列表构建
+type Upstream struct {
+	Key        string
+	Scheme     string
+	HealthPath string
+	Targets    []*Target
+	balancers  map[string]LoadBalancer
+	mu         sync.Mutex
+}
+
+// Balancer 返回指定策略的负载均衡器；未注册的策略使用RoundRobin策略；
+func (u *Upstream) Balancer(strategy string) LoadBalancer {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if lb, ok := u.balancers[strategy]; ok {
+		return lb
+	}
+	factory, ok := LoadBalancerFactoryBy(strategy)
+	if !ok {
+		factory, _ = LoadBalancerFactoryBy(LoadBalanceRoundRobin)
+	}
+	lb := factory(u.Targets)
+	u.balancers[strategy] = lb
+	return lb
+}
+
+// Select 使用指定负载均衡策略选择可用的目标实例；全部实例不可用时，在全部实例中选择；
+func (u *Upstream) Select(strategy string, hashKey string) *Target {
+	return u.Balancer(strategy).Select(hashKey)
+}
+
+// ParseTargets 解析地址列表；格式：host1:port;weight=3,host2:port
+func ParseTargets(remoteHost string) []*Target {
+	targets := make([]*Target, 0, 4)
+	for _, item := range strings.Split(remoteHost, ",") {
+		parts := strings.Split(strings.TrimSpace(item), ";")
+		if "" == parts[0] {
+			continue
+		}
+		target := &Target{Host: parts[0], Weight: 1}
+		for _, opt := range parts[1:] {
+			if kv := strings.SplitN(strings.TrimSpace(opt), "=", 2); len(kv) == 2 && kv[0] == "weight" {
+				if w, err := strconv.Atoi(kv[1]); nil == err && w > 0 {
+					target.Weight = w
+				}
+			}
+		}
+		target.lastError.Store("")
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+// Upstreams 管理全部Http后端服务实例组；Upstream按服务引用，服务删除或者地址变更后，不再被引用的Upstream被删除；
+type Upstreams struct {
+	upstreams map[string]*Upstream
+	bindings  map[string]string // ServiceId -> Upstream.Key
+	mu        sync.RWMutex
+}
+
+func NewUpstreams() *Upstreams {
+	return &Upstreams{
+		upstreams: make(map[string]*Upstream, 16),
+		bindings:  make(map[string]string, 16),
+	}
+}
+
+// Load 加载或者创建服务的地址列表对应的Upstream
+func (us *Upstreams) Load(serviceId, scheme, remoteHost, healthPath string) *Upstream {
+	key := upstreamKey(scheme, remoteHost)
+	us.mu.RLock()
+	up, ok := us.upstreams[key]
+	bound := us.bindings[serviceId] == key
+	us.mu.RUnlock()
+	if ok && bound {
+		return up
+	}
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	us.bindings[serviceId] = key
+	if up, ok := us.upstreams[key]; ok {
+		return up
+	}
+	up = &Upstream{
+		Key:        key,
+		Scheme:     scheme,
+		HealthPath: healthPath,
+		Targets:    ParseTargets(remoteHost),
+		balancers:  make(map[string]LoadBalancer, 2),
+	}
+	us.upstreams[key] = up
+	return up
+}
+
+// Evict 服务的地址列表不再是 scheme://remoteHost 时，解除服务与Upstream的引用；删除不再被任何服务引用的Upstream；
+func (us *Upstreams) Evict(serviceId, scheme, remoteHost string) {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	key, ok := us.bindings[serviceId]
+	if !ok || key == upstreamKey(scheme, remoteHost) {
+		return
+	}
+	delete(us.bindings, serviceId)
+	for _, bound := range us.bindings {
+		if bound == key {
+			return
+		}
+	}
+	delete(us.upstreams, key)
+}
+
+func upstreamKey(scheme, remoteHost string) string {
+	return scheme + "://" + remoteHost
+}
+
+// Upstreams 返回全部Upstream列表
+func (us *Upstreams) Upstreams() []*Upstream {
+	us.mu.RLock()
+	defer us.mu.RUnlock()
+	out := make([]*Upstream, 0, len(us.upstreams))
+	for _, up := range us.upstreams {
+		out = append(out, up)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Key < out[j].Key
+	})
+	return out
+}
+
+// TargetState 后端服务实例的状态快照
+type TargetState struct {
+	Upstream  string `json:"upstream"`
+	Host      string `json:"host"`
+	Weight    int    `json:"weight"`
+	Healthy   bool   `json:"healthy"`
+	Ejected   bool   `json:"ejected"`
+	InFlight  int64  `json:"inflight"`
+	Failures  int32  `json:"failures"`
+	LastError string `json:"lastError"`
+}
+
+// States 返回全部后端服务实例的状态快照
+func (us *Upstreams) States() []TargetState {
+	now := time.Now().UnixNano()
+	out := make([]TargetState, 0, 16)
+	for _, up := range us.Upstreams() {
+		for _, t := range up.Targets {
+			out = append(out, TargetState{
+				Upstream:  up.Key,
+				Host:      t.Host,
+				Weight:    t.Weight,
+				Healthy:   atomic.LoadInt32(&t.unhealthy) == 0,
+				Ejected:   now < atomic.LoadInt64(&t.ejectUntil),
+				InFlight:  t.InFlight(),
+				Failures:  atomic.LoadInt32(&t.failures),
+				LastError: t.lastError.Load().(string),
+			})
+		}
+	}
+	return out
+}
+
+var defaultUpstreams = NewUpstreams()
+
+// UpstreamStates 返回Http后端服务实例的状态快照
+func UpstreamStates() []TargetState {
+	return defaultUpstreams.States()
+}
+
+// releaseBody 关闭时释放目标实例请求计数的响应Body
+type releaseBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (r *releaseBody) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(r.release)
+	return err
+}
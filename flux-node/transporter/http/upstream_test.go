@@ -0,0 +1,72 @@
+package http
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/internal/fluxtest"
+	assert2 "github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUpstreams_Evict(t *testing.T) {
+	assert := assert2.New(t)
+	ups := NewUpstreams()
+	a := ups.Load("svc-a", "http", "a:80,b:80", "")
+	assert.Equal(a, ups.Load("svc-b", "http", "a:80,b:80", ""))
+	// 地址未变更
+	ups.Evict("svc-a", "http", "a:80,b:80")
+	assert.Equal(1, len(ups.Upstreams()))
+	// 仍被其它服务引用
+	ups.Evict("svc-a", "http", "c:80")
+	assert.Equal(1, len(ups.Upstreams()))
+	ups.Evict("svc-b", "", "")
+	assert.Equal(0, len(ups.Upstreams()))
+	// 未加载的服务
+	ups.Evict("svc-c", "", "")
+	assert.True(a != ups.Load("svc-a", "http", "a:80,b:80", ""))
+}
+
+func TestRpcTransporter_OnServiceEvent(t *testing.T) {
+	assert := assert2.New(t)
+	tr := NewRpcHttpTransporterWith()
+	tr.upstreams = NewUpstreams()
+	service := flux.TransporterService{ServiceId: "svc", Scheme: "http", RemoteHost: "a:80,b:80"}
+	tr.SelectTarget(&service, fluxtest.NewContext(httptest.NewRequest("GET", "http://mocking/api", nil)))
+	assert.Equal(1, len(tr.upstreams.Upstreams()))
+	tr.OnServiceEvent(flux.ServiceEvent{EventType: flux.EventTypeUpdated, Service: service})
+	assert.Equal(1, len(tr.upstreams.Upstreams()))
+	updated := service
+	updated.RemoteHost = "c:80,d:80"
+	tr.OnServiceEvent(flux.ServiceEvent{EventType: flux.EventTypeUpdated, Service: updated})
+	assert.Equal(0, len(tr.upstreams.Upstreams()))
+	tr.SelectTarget(&updated, fluxtest.NewContext(httptest.NewRequest("GET", "http://mocking/api", nil)))
+	tr.OnServiceEvent(flux.ServiceEvent{EventType: flux.EventTypeRemoved, Service: updated})
+	assert.Equal(0, len(tr.upstreams.Upstreams()))
+}
+
+func TestRpcTransporter_InvokeReleaseOnBodyClose(t *testing.T) {
+	assert := assert2.New(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+	tr := NewRpcHttpTransporterWith()
+	tr.upstreams = NewUpstreams()
+	host := strings.TrimPrefix(server.URL, "http://")
+	service := flux.TransporterService{ServiceId: "svc", Scheme: "http", RemoteHost: host, Interface: "/api", Method: "GET"}
+	resp, serr := tr.Invoke(fluxtest.NewContext(httptest.NewRequest("GET", "http://mocking/api", nil)), service)
+	assert.Nil(serr)
+	target := tr.upstreams.Load("svc", "http", host, "").Targets[0]
+	// 响应Body未关闭，实例仍在处理请求
+	assert.Equal(int64(1), target.InFlight())
+	body := resp.(*http.Response).Body
+	data, err := ioutil.ReadAll(body)
+	assert.NoError(err)
+	assert.Equal("hello", string(data))
+	assert.NoError(body.Close())
+	assert.NoError(body.Close())
+	assert.Equal(int64(0), target.InFlight())
+}
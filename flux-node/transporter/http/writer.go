@@ -0,0 +1,90 @@
+package http
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/transporter"
+	"io"
+	"net/http"
+)
+
+const (
+	// ServiceAttrTagStreaming 标识Http服务响应以流式透传到客户端
+	ServiceAttrTagStreaming = "streaming"
+)
+
+// 不透传到客户端的逐跳(Hop-by-hop)响应头
+var hopByHopHeaders = []string{
+	"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization",
+	"Te", "Trailer", "Transfer-Encoding", "Upgrade", "Proxy-Connection",
+}
+
+var _ flux.TransportWriter = new(StreamTransportWriter)
+
+// StreamTransportWriter 支持流式透传的响应Writer：
+// 启用流式模式时，上游Http响应的状态码、Header和Body不经缓存和序列化，直接复制到客户端；
+// 未启用流式模式，或者响应Body不是数据流时，使用 DefaultTransportWriter 处理；
+type StreamTransportWriter struct {
+	transporter.DefaultTransportWriter
+	streaming bool
+}
+
+func NewStreamTransportWriter(streaming bool) *StreamTransportWriter {
+	return &StreamTransportWriter{streaming: streaming}
+}
+
+func (w *StreamTransportWriter) Write(ctx *flux.Context, response *flux.ResponseBody) {
+	reader, ok := response.Body.(io.ReadCloser)
+	if !ok || !w.isStreaming(ctx) {
+		w.DefaultTransportWriter.Write(ctx, response)
+		return
+	}
+	defer reader.Close()
+	header := ctx.ResponseWriter().Header()
+	for k, hv := range response.Headers {
+		for _, v := range hv {
+			header.Add(k, v)
+		}
+	}
+	for _, h := range hopByHopHeaders {
+		header.Del(h)
+	}
+	contentType := response.Headers.Get(flux.HeaderContentType)
+	if "" == contentType {
+		contentType = "application/octet-stream"
+	}
+	header.Add("X-Writer-Id", "Fx-SWriter")
+	var body io.Reader = reader
+	// 未指定长度的响应(Chunked)，每次读取上游数据前刷新已写入的数据
+	if "" == response.Headers.Get(flux.HeaderContentLength) {
+		if flusher, ok := ctx.ResponseWriter().(http.Flusher); ok {
+			body = &flushReader{reader: reader, flusher: flusher}
+		}
+	}
+	if err := ctx.WriteStream(response.StatusCode, contentType, body); nil != err {
+		ctx.Logger().Errorw("TRANSPORT:WRITE:STREAM:ERROR", "error", err)
+	} else {
+		ctx.Logger().Infow("TRANSPORT:WRITE:STREAM:COMPLETED", "content-type", contentType)
+	}
+}
+
+func (w *StreamTransportWriter) isStreaming(ctx *flux.Context) bool {
+	if w.streaming {
+		return true
+	}
+	attr, ok := ctx.Transporter().GetAttrEx(ServiceAttrTagStreaming)
+	return ok && attr.GetBool()
+}
+
+type flushReader struct {
+	reader  io.Reader
+	flusher http.Flusher
+	started bool
+}
+
+func (r *flushReader) Read(p []byte) (int, error) {
+	if r.started {
+		r.flusher.Flush()
+	}
+	r.started = true
+	return r.reader.Read(p)
+}
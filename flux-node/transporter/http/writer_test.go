@@ -0,0 +1,159 @@
+package http
+
+import (
+	"bufio"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/internal/fluxtest"
+	assert2 "github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newGatewayServer 模拟网关：请求经Http协议转发到上游服务，并由transporter的Writer写出响应
+func newGatewayServer(tr *RpcTransporter, service flux.TransporterService) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := fluxtest.NewContextWith(w, r, &flux.Endpoint{Service: service})
+		resp, serr := tr.InvokeCodec(ctx, service)
+		if nil != serr {
+			tr.Writer().WriteError(ctx, serr)
+		} else {
+			tr.Writer().Write(ctx, resp)
+		}
+	}))
+}
+
+func newUpstreamService(upstream *httptest.Server) flux.TransporterService {
+	return flux.TransporterService{
+		Scheme: "http", RemoteHost: strings.TrimPrefix(upstream.URL, "http://"), Interface: "/stream", Method: "GET",
+	}
+}
+
+func TestStreamTransportWriter_Passthrough(t *testing.T) {
+	assert := assert2.New(t)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(flux.HeaderContentType, "image/png")
+		w.Header().Set(flux.HeaderContentLength, "8")
+		w.Header().Set("X-Upstream", "u")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("PNG-DATA"))
+	}))
+	defer upstream.Close()
+	gateway := newGatewayServer(NewRpcHttpTransporterWith(WithTransportWriter(NewStreamTransportWriter(true))), newUpstreamService(upstream))
+	defer gateway.Close()
+	resp, err := http.Get(gateway.URL)
+	assert.NoError(err)
+	defer resp.Body.Close()
+	data, _ := ioutil.ReadAll(resp.Body)
+	// 保留上游的状态码、Content-Type和Content-Length
+	assert.Equal(http.StatusCreated, resp.StatusCode)
+	assert.Equal("image/png", resp.Header.Get(flux.HeaderContentType))
+	assert.Equal(int64(8), resp.ContentLength)
+	assert.Equal("u", resp.Header.Get("X-Upstream"))
+	assert.Equal("Fx-SWriter", resp.Header.Get("X-Writer-Id"))
+	assert.Equal("PNG-DATA", string(data))
+}
+
+func TestStreamTransportWriter_Flush(t *testing.T) {
+	assert := assert2.New(t)
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(flux.HeaderContentType, "text/event-stream")
+		_, _ = w.Write([]byte("first\n"))
+		w.(http.Flusher).Flush()
+		<-release
+		_, _ = w.Write([]byte("second\n"))
+	}))
+	defer upstream.Close()
+	defer func() {
+		select {
+		case <-release:
+		default:
+			close(release)
+		}
+	}()
+	// 服务属性启用流式透传
+	service := newUpstreamService(upstream)
+	service.Attributes = []flux.Attribute{{Name: ServiceAttrTagStreaming, Value: true}}
+	gateway := newGatewayServer(NewRpcHttpTransporterWith(), service)
+	defer gateway.Close()
+	resp, err := http.Get(gateway.URL)
+	assert.NoError(err)
+	defer resp.Body.Close()
+	assert.Equal("text/event-stream", resp.Header.Get(flux.HeaderContentType))
+	assert.Equal(int64(-1), resp.ContentLength)
+	reader := bufio.NewReader(resp.Body)
+	lines := make(chan string, 1)
+	go func() {
+		line, _ := reader.ReadString('\n')
+		lines <- line
+	}()
+	// 上游仍未结束响应时，客户端已收到第一段数据
+	select {
+	case line := <-lines:
+		assert.Equal("first\n", line)
+	case <-time.After(3 * time.Second):
+		t.Fatal("chunked response not flushed")
+	}
+	close(release)
+	rest, _ := ioutil.ReadAll(reader)
+	assert.Equal("second\n", string(rest))
+}
+
+func TestStreamTransportWriter_HopByHopHeaders(t *testing.T) {
+	assert := assert2.New(t)
+	recorder := httptest.NewRecorder()
+	ctx := fluxtest.NewContextWith(recorder, httptest.NewRequest("GET", "http://mocking/stream", nil), &flux.Endpoint{})
+	NewStreamTransportWriter(true).Write(ctx, &flux.ResponseBody{
+		StatusCode: http.StatusOK,
+		Headers: http.Header{
+			"Connection":        {"keep-alive"},
+			"Keep-Alive":        {"timeout=5"},
+			"Transfer-Encoding": {"chunked"},
+			"Upgrade":           {"websocket"},
+			"Proxy-Connection":  {"keep-alive"},
+			"X-Upstream":        {"u"},
+		},
+		Body: ioutil.NopCloser(strings.NewReader("data")),
+	})
+	for _, h := range hopByHopHeaders {
+		assert.Equal("", recorder.Header().Get(h), h)
+	}
+	assert.Equal("u", recorder.Header().Get("X-Upstream"))
+	// 未指定Content-Type时使用二进制流类型
+	assert.Equal("application/octet-stream", recorder.Header().Get(flux.HeaderContentType))
+	assert.Equal("data", recorder.Body.String())
+}
+
+func TestStreamTransportWriter_Fallback(t *testing.T) {
+	assert := assert2.New(t)
+	cases := []struct {
+		streaming bool
+		service   flux.TransporterService
+		body      interface{}
+	}{
+		// 未启用流式透传
+		{streaming: false, body: ioutil.NopCloser(strings.NewReader("data"))},
+		// 服务属性关闭流式透传
+		{streaming: false, body: ioutil.NopCloser(strings.NewReader("data")), service: flux.TransporterService{
+			EmbeddedAttributes: flux.EmbeddedAttributes{Attributes: []flux.Attribute{{Name: ServiceAttrTagStreaming, Value: false}}},
+		}},
+		// 响应Body不是数据流
+		{streaming: true, body: "data"},
+	}
+	for _, tcase := range cases {
+		recorder := httptest.NewRecorder()
+		ctx := fluxtest.NewContextWith(recorder, httptest.NewRequest("GET", "http://mocking/stream", nil), &flux.Endpoint{Service: tcase.service})
+		NewStreamTransportWriter(tcase.streaming).Write(ctx, &flux.ResponseBody{
+			StatusCode: http.StatusOK,
+			Headers:    http.Header{flux.HeaderContentType: {"text/plain"}},
+			Body:       tcase.body,
+		})
+		assert.Equal("Fx-TWriter", recorder.Header().Get("X-Writer-Id"))
+		assert.Equal(flux.MIMEApplicationJSONCharsetUTF8, recorder.Header().Get(flux.HeaderContentType))
+		assert.Equal("data", recorder.Body.String())
+	}
+}
@@ -0,0 +1,178 @@
+package inproc
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/ext"
+	"github.com/bytepowered/flux/flux-node/transporter"
+	"net/http"
+	"runtime/debug"
+)
+
+func init() {
+	ext.RegisterTransporter(flux.ProtoInProc, NewTransporter())
+}
+
+var (
+	_ flux.Transporter = new(RpcTransporter)
+)
+
+var (
+	ErrUnknownInProcResponse = errors.New("TRANSPORTER:INPROC:UNKNOWN_RESPONSE")
+)
+
+type (
+	// Option 配置函数
+	Option func(*RpcTransporter)
+	// ArgumentResolver 进程内服务函数的参数封装函数
+	ArgumentResolver func(arguments []flux.Argument, ctx *flux.Context) (map[string]interface{}, error)
+)
+
+// RpcTransporter 执行网关进程内注册的Go服务函数；服务函数按TransporterService的Interface和Method绑定；
+type RpcTransporter struct {
+	aresolver ArgumentResolver
+	codec     flux.TransportCodec
+	writer    flux.TransportWriter
+}
+
+// WithArgumentResolver 用于配置参数封装实现函数
+func WithArgumentResolver(fun ArgumentResolver) Option {
+	return func(service *RpcTransporter) {
+		service.aresolver = fun
+	}
+}
+
+// WithTransportCodec 用于配置响应数据解析实现函数
+func WithTransportCodec(fun flux.TransportCodec) Option {
+	return func(service *RpcTransporter) {
+		service.codec = fun
+	}
+}
+
+// WithTransportWriter 用于配置响应数据解析实现函数
+func WithTransportWriter(fun flux.TransportWriter) Option {
+	return func(service *RpcTransporter) {
+		service.writer = fun
+	}
+}
+
+func NewTransporter() flux.Transporter {
+	return NewTransporterWith(
+		WithArgumentResolver(DefaultArgumentResolver),
+		WithTransportCodec(NewTransportCodecFunc()),
+		WithTransportWriter(new(transporter.DefaultTransportWriter)),
+	)
+}
+
+func NewTransporterWith(opts ...Option) flux.Transporter {
+	bts := new(RpcTransporter)
+	for _, opt := range opts {
+		opt(bts)
+	}
+	return bts
+}
+
+func (b *RpcTransporter) Writer() flux.TransportWriter {
+	return b.writer
+}
+
+func (b *RpcTransporter) Transport(ctx *flux.Context) {
+	transporter.DoTransport(ctx, b)
+}
+
+func (b *RpcTransporter) InvokeCodec(ctx *flux.Context, service flux.TransporterService) (*flux.ResponseBody, *flux.ServeError) {
+	raw, serr := b.Invoke(ctx, service)
+	if nil != serr {
+		return nil, serr
+	}
+	result, err := b.codec(ctx, raw)
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageTransportDecodeResponse,
+			CauseError: fmt.Errorf("decode inproc response, err: %w", err),
+		}
+	}
+	return result, nil
+}
+
+func (b *RpcTransporter) Invoke(ctx *flux.Context, service flux.TransporterService) (raw interface{}, serr *flux.ServeError) {
+	fun, ok := ext.InProcFuncBy(service.Interface, service.Method)
+	if !ok {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusNotFound,
+			ErrorCode:  flux.ErrorCodeGatewayTransporter,
+			Message:    flux.ErrorMessageInProcFuncNotFound,
+			CauseError: fmt.Errorf("inproc func not found, service: %s", service.ServiceID()),
+		}
+	}
+	arguments, err := b.aresolver(service.Arguments, ctx)
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageInProcAssembleFailed,
+			CauseError: err,
+		}
+	}
+	defer func() {
+		if r := recover(); nil != r {
+			ctx.Logger().Errorw("TRANSPORTER:INPROC:PANIC", "transporter-service", service.ServiceID(),
+				"error", r, "error.trace", string(debug.Stack()))
+			raw, serr = nil, &flux.ServeError{
+				StatusCode: flux.StatusServerError,
+				ErrorCode:  flux.ErrorCodeGatewayTransporter,
+				Message:    flux.ErrorMessageInProcInvokeFailed,
+				CauseError: fmt.Errorf("inproc func panic: %v", r),
+			}
+		}
+	}()
+	response, err := fun(ctx, arguments)
+	if nil != err {
+		// 服务函数可直接返回ServeError来定义响应状态码和错误码
+		if se, ok := err.(*flux.ServeError); ok {
+			return nil, se
+		}
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayTransporter,
+			Message:    flux.ErrorMessageInProcInvokeFailed,
+			CauseError: err,
+		}
+	}
+	return response, nil
+}
+
+// DefaultArgumentResolver 默认参数封装：以参数名为Key的参数值
+func DefaultArgumentResolver(arguments []flux.Argument, ctx *flux.Context) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(arguments))
+	for _, arg := range arguments {
+		if val, err := arg.Resolve(ctx); nil != err {
+			return nil, err
+		} else {
+			values[arg.Name] = val
+		}
+	}
+	return values, nil
+}
+
+func NewTransportCodecFunc() flux.TransportCodec {
+	return func(ctx *flux.Context, value interface{}) (*flux.ResponseBody, error) {
+		response, ok := value.(*flux.ResponseBody)
+		if !ok {
+			return nil, ErrUnknownInProcResponse
+		}
+		if nil == response {
+			response = &flux.ResponseBody{StatusCode: http.StatusNoContent}
+		}
+		if 0 == response.StatusCode {
+			response.StatusCode = http.StatusOK
+		}
+		if nil == response.Headers {
+			response.Headers = make(http.Header, 0)
+		}
+		return response, nil
+	}
+}
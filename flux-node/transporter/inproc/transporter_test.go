@@ -0,0 +1,117 @@
+package inproc
+
+import (
+	"errors"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/ext"
+	"github.com/bytepowered/flux/flux-node/internal/fluxtest"
+	"github.com/bytepowered/flux/flux-node/logger"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func init() {
+	ext.SetLoggerFactory(logger.DefaultFactory)
+}
+
+func newInProcService(iface, method string, args ...flux.Argument) flux.TransporterService {
+	return flux.TransporterService{Interface: iface, Method: method, Arguments: args}
+}
+
+func TestRpcTransporter_Invoke(t *testing.T) {
+	assert := assert2.New(t)
+	ext.RegisterInProcFunc("test.user", "get", func(ctx *flux.Context, arguments map[string]interface{}) (*flux.ResponseBody, error) {
+		return &flux.ResponseBody{Body: arguments}, nil
+	})
+	defer ext.RemoveInProcFunc("test.user", "get")
+	tr := NewTransporter()
+	resp, serr := tr.InvokeCodec(fluxtest.NewContext(httptest.NewRequest("GET", "http://mocking/inproc", nil)), newInProcService("test.user", "get",
+		ext.NewIntegerArgumentWith("id", 100), ext.NewStringArgumentWith("name", "foo")))
+	assert.Nil(serr)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.NotNil(resp.Headers)
+	assert.Equal(map[string]interface{}{"id": 100, "name": "foo"}, resp.Body)
+}
+
+func TestRpcTransporter_InvokeNotFound(t *testing.T) {
+	assert := assert2.New(t)
+	_, serr := NewTransporter().InvokeCodec(fluxtest.NewContext(httptest.NewRequest("GET", "http://mocking/inproc", nil)), newInProcService("test.user", "none"))
+	assert.NotNil(serr)
+	assert.Equal(flux.StatusNotFound, serr.StatusCode)
+	assert.Equal(flux.ErrorMessageInProcFuncNotFound, serr.Message)
+}
+
+func TestRpcTransporter_InvokeArgumentError(t *testing.T) {
+	assert := assert2.New(t)
+	invoked := false
+	ext.RegisterInProcFunc("test.user", "args", func(ctx *flux.Context, arguments map[string]interface{}) (*flux.ResponseBody, error) {
+		invoked = true
+		return nil, nil
+	})
+	defer ext.RemoveInProcFunc("test.user", "args")
+	_, serr := NewTransporter().InvokeCodec(fluxtest.NewContext(httptest.NewRequest("GET", "http://mocking/inproc", nil)), newInProcService("test.user", "args", flux.Argument{Name: "bad"}))
+	assert.NotNil(serr)
+	assert.Equal(flux.StatusServerError, serr.StatusCode)
+	assert.Equal(flux.ErrorMessageInProcAssembleFailed, serr.Message)
+	assert.False(invoked)
+}
+
+func TestRpcTransporter_InvokeError(t *testing.T) {
+	assert := assert2.New(t)
+	ext.RegisterInProcFunc("test.user", "error", func(ctx *flux.Context, arguments map[string]interface{}) (*flux.ResponseBody, error) {
+		return nil, errors.New("failed")
+	})
+	ext.RegisterInProcFunc("test.user", "serve-error", func(ctx *flux.Context, arguments map[string]interface{}) (*flux.ResponseBody, error) {
+		return nil, &flux.ServeError{StatusCode: flux.StatusBadRequest, ErrorCode: "USER:INVALID", Message: "invalid user"}
+	})
+	ext.RegisterInProcFunc("test.user", "panic", func(ctx *flux.Context, arguments map[string]interface{}) (*flux.ResponseBody, error) {
+		panic("boom")
+	})
+	defer func() {
+		for _, method := range []string{"error", "serve-error", "panic"} {
+			ext.RemoveInProcFunc("test.user", method)
+		}
+	}()
+	tr := NewTransporter()
+	_, serr := tr.InvokeCodec(fluxtest.NewContext(httptest.NewRequest("GET", "http://mocking/inproc", nil)), newInProcService("test.user", "error"))
+	assert.NotNil(serr)
+	assert.Equal(flux.StatusServerError, serr.StatusCode)
+	assert.Equal(flux.ErrorMessageInProcInvokeFailed, serr.Message)
+	assert.Equal("failed", serr.CauseError.Error())
+	// 服务函数返回的ServeError直接作为响应错误
+	_, serr = tr.InvokeCodec(fluxtest.NewContext(httptest.NewRequest("GET", "http://mocking/inproc", nil)), newInProcService("test.user", "serve-error"))
+	assert.NotNil(serr)
+	assert.Equal(flux.StatusBadRequest, serr.StatusCode)
+	assert.Equal("USER:INVALID", serr.ErrorCode)
+	// 服务函数Panic被恢复为错误响应
+	_, serr = tr.InvokeCodec(fluxtest.NewContext(httptest.NewRequest("GET", "http://mocking/inproc", nil)), newInProcService("test.user", "panic"))
+	assert.NotNil(serr)
+	assert.Equal(flux.StatusServerError, serr.StatusCode)
+	assert.Equal(flux.ErrorMessageInProcInvokeFailed, serr.Message)
+}
+
+func TestTransportCodecFunc(t *testing.T) {
+	assert := assert2.New(t)
+	codec := NewTransportCodecFunc()
+	resp, err := codec(nil, (*flux.ResponseBody)(nil))
+	assert.NoError(err)
+	assert.Equal(http.StatusNoContent, resp.StatusCode)
+	assert.NotNil(resp.Headers)
+	resp, err = codec(nil, &flux.ResponseBody{StatusCode: http.StatusCreated, Headers: http.Header{"X-Id": {"1"}}})
+	assert.NoError(err)
+	assert.Equal(http.StatusCreated, resp.StatusCode)
+	assert.Equal("1", resp.Headers.Get("X-Id"))
+	_, err = codec(nil, "unknown")
+	assert.Equal(ErrUnknownInProcResponse, err)
+	// 服务函数返回nil响应时，响应204状态码
+	tr := NewTransporter()
+	ext.RegisterInProcFunc("test.user", "nil", func(ctx *flux.Context, arguments map[string]interface{}) (*flux.ResponseBody, error) {
+		return nil, nil
+	})
+	defer ext.RemoveInProcFunc("test.user", "nil")
+	resp, serr := tr.InvokeCodec(fluxtest.NewContext(httptest.NewRequest("GET", "http://mocking/inproc", nil)), newInProcService("test.user", "nil"))
+	assert.Nil(serr)
+	assert.Equal(http.StatusNoContent, resp.StatusCode)
+}
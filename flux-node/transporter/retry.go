@@ -0,0 +1,163 @@
+package transporter
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/spf13/cast"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 重试策略的服务属性
+const (
+	ServiceAttrTagRetryAttempts   = "retry_attempts"    // 最大调用次数(包含首次调用)
+	ServiceAttrTagRetryBackoff    = "retry_backoff"     // 首次重试的退避时间
+	ServiceAttrTagRetryMaxBackoff = "retry_max_backoff" // 最大退避时间
+	ServiceAttrTagRetryOn         = "retry_on"          // 触发重试的响应状态码或者错误码列表
+	ServiceAttrTagIdempotent      = "idempotent"        // 标识服务调用是否幂等
+)
+
+const (
+	defaultRetryBackoff    = time.Millisecond * 100
+	defaultRetryMaxBackoff = time.Second * 2
+)
+
+// 幂等调用默认重试的状态码
+var defaultRetryStatus = map[int]bool{
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+	http.StatusGatewayTimeout:     true,
+}
+
+// RetryPolicy 服务调用的重试策略
+type RetryPolicy struct {
+	Attempts   int             // 最大调用次数(包含首次调用)
+	Backoff    time.Duration   // 首次重试的退避时间，之后按指数增长
+	MaxBackoff time.Duration   // 最大退避时间
+	RetryOn    map[string]bool // 触发重试的响应状态码或者错误码
+	Idempotent bool            // 服务调用是否幂等
+}
+
+// NewRetryPolicy 根据服务属性构建重试策略。
+// 未定义 retry_attempts 属性时，除Dubbo和Http以外的协议服务使用 rpcretries 属性作为重试次数；
+// Dubbo协议由Reference处理重试；Http协议的重试需要显式定义 retry_attempts 属性；
+func NewRetryPolicy(service flux.TransporterService, ctx *flux.Context) RetryPolicy {
+	policy := RetryPolicy{
+		Attempts:   1,
+		Backoff:    defaultRetryBackoff,
+		MaxBackoff: defaultRetryMaxBackoff,
+		RetryOn:    make(map[string]bool, 4),
+	}
+	if attr, ok := service.GetAttrEx(ServiceAttrTagRetryAttempts); ok {
+		policy.Attempts = attr.GetInt()
+	} else if proto := service.RpcProto(); flux.ProtoDubbo != proto && flux.ProtoHttp != proto {
+		policy.Attempts = cast.ToInt(service.RpcRetries()) + 1
+	}
+	if attr, ok := service.GetAttrEx(ServiceAttrTagRetryBackoff); ok {
+		policy.Backoff = cast.ToDuration(attr.GetString())
+	}
+	if attr, ok := service.GetAttrEx(ServiceAttrTagRetryMaxBackoff); ok {
+		policy.MaxBackoff = cast.ToDuration(attr.GetString())
+	}
+	for _, attr := range service.GetAttrs(ServiceAttrTagRetryOn) {
+		for _, code := range attr.GetStringSlice() {
+			for _, c := range strings.Split(code, ",") {
+				if c = strings.TrimSpace(c); "" != c {
+					policy.RetryOn[c] = true
+				}
+			}
+		}
+	}
+	if attr, ok := service.GetAttrEx(ServiceAttrTagIdempotent); ok {
+		policy.Idempotent = attr.GetBool()
+	} else {
+		// Http协议使用后端服务的Method，其它协议使用请求的Method
+		method := ctx.Method()
+		if flux.ProtoHttp == service.RpcProto() {
+			method = service.Method
+		}
+		policy.Idempotent = isIdempotentMethod(method)
+	}
+	return policy
+}
+
+// ShouldRetry 判断调用结果是否需要重试；只有幂等调用才会重试：
+// 1. 响应状态码或者错误码在 retry_on 列表中；
+// 2. 发生传输错误，或者响应状态码为502/503/504；
+func (p RetryPolicy) ShouldRetry(response *flux.ResponseBody, serr *flux.ServeError) bool {
+	if !p.Idempotent {
+		return false
+	}
+	status := 0
+	if nil != serr {
+		status = serr.StatusCode
+		if p.RetryOn[serr.GetErrorCode()] {
+			return true
+		}
+	} else if nil != response {
+		status = response.StatusCode
+	}
+	if p.RetryOn[strconv.Itoa(status)] {
+		return true
+	}
+	if nil != serr && flux.ErrorCodeGatewayTransporter == serr.GetErrorCode() {
+		return true
+	}
+	return defaultRetryStatus[status]
+}
+
+// BackoffOf 返回第N次重试(从1开始)的退避时间：指数增长并附加随机抖动
+func (p RetryPolicy) BackoffOf(retry int) time.Duration {
+	if p.Backoff <= 0 {
+		return 0
+	}
+	backoff := p.Backoff << uint(retry-1)
+	if backoff <= 0 || (p.MaxBackoff > 0 && backoff > p.MaxBackoff) {
+		backoff = p.MaxBackoff
+	}
+	// 随机抖动：[backoff/2, backoff]
+	half := int64(backoff / 2)
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// InvokeCodecWithRetry 按服务的重试策略执行调用；总耗时受请求Context的Deadline限制；每次调用均记录Metric；
+func InvokeCodecWithRetry(ctx *flux.Context, transport flux.Transporter, service flux.TransporterService) (*flux.ResponseBody, *flux.ServeError) {
+	policy := NewRetryPolicy(service, ctx)
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		response, serr := transport.InvokeCodec(ctx, service)
+		ctx.AddMetric("transporter.attempt."+strconv.Itoa(attempt), time.Since(start))
+		if attempt >= policy.Attempts || !policy.ShouldRetry(response, serr) {
+			return response, serr
+		}
+		backoff := policy.BackoffOf(attempt)
+		if deadline, ok := ctx.Context().Deadline(); ok && time.Now().Add(backoff).After(deadline) {
+			return response, serr
+		}
+		ctx.Logger().Warnw("TRANSPORTER:RETRY", "transporter-service", service.ServiceID(),
+			"attempt", attempt, "backoff", backoff, "error", serr)
+		select {
+		case <-ctx.Context().Done():
+			return response, serr
+		case <-time.After(backoff):
+		}
+		// 丢弃需要重试的响应
+		if nil != response {
+			if closer, ok := response.Body.(io.Closer); ok {
+				_ = closer.Close()
+			}
+		}
+	}
+}
+
+func isIdempotentMethod(method string) bool {
+	switch strings.ToUpper(method) {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
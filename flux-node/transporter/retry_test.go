@@ -0,0 +1,132 @@
+package transporter
+
+import (
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/internal/fluxtest"
+	assert2 "github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newRetryContext(method string) *flux.Context {
+	return fluxtest.NewContext(httptest.NewRequest(method, "http://mocking/api", nil))
+}
+
+func newRetryService(proto, method string, attrs ...flux.Attribute) flux.TransporterService {
+	attrs = append(attrs, flux.Attribute{Name: flux.ServiceAttrTagRpcProto, Value: proto})
+	return flux.TransporterService{
+		Interface: "/api", Method: method,
+		EmbeddedAttributes: flux.EmbeddedAttributes{Attributes: attrs},
+	}
+}
+
+// retryTransporter 按顺序返回预设的调用结果
+type retryTransporter struct {
+	results []*flux.ServeError
+	calls   int
+}
+
+func (r *retryTransporter) Invoke(*flux.Context, flux.TransporterService) (interface{}, *flux.ServeError) {
+	return nil, nil
+}
+
+func (r *retryTransporter) InvokeCodec(*flux.Context, flux.TransporterService) (*flux.ResponseBody, *flux.ServeError) {
+	serr := r.results[r.calls]
+	r.calls++
+	if nil != serr {
+		return nil, serr
+	}
+	return &flux.ResponseBody{StatusCode: http.StatusOK}, nil
+}
+
+func (r *retryTransporter) Transport(*flux.Context) {}
+
+func (r *retryTransporter) Writer() flux.TransportWriter {
+	return nil
+}
+
+var errTransport = &flux.ServeError{StatusCode: flux.StatusBadGateway, ErrorCode: flux.ErrorCodeGatewayTransporter}
+
+func TestInvokeCodecWithRetry(t *testing.T) {
+	cases := []struct {
+		name    string
+		method  string
+		service flux.TransporterService
+		results []*flux.ServeError
+		calls   int
+		success bool
+	}{
+		{
+			name:    "http-get-retry",
+			service: newRetryService(flux.ProtoHttp, "GET", flux.Attribute{Name: ServiceAttrTagRetryAttempts, Value: 3}),
+			results: []*flux.ServeError{errTransport, errTransport, nil},
+			calls:   3, success: true,
+		},
+		{
+			name:    "http-post-not-retry",
+			service: newRetryService(flux.ProtoHttp, "POST", flux.Attribute{Name: ServiceAttrTagRetryAttempts, Value: 3}),
+			results: []*flux.ServeError{errTransport, nil},
+			calls:   1,
+		},
+		{
+			name: "http-post-retry-on-not-retry",
+			service: newRetryService(flux.ProtoHttp, "POST", flux.Attribute{Name: ServiceAttrTagRetryAttempts, Value: 3},
+				flux.Attribute{Name: ServiceAttrTagRetryOn, Value: "502"}),
+			results: []*flux.ServeError{errTransport, nil},
+			calls:   1,
+		},
+		{
+			name: "http-post-idempotent-retry",
+			service: newRetryService(flux.ProtoHttp, "POST", flux.Attribute{Name: ServiceAttrTagRetryAttempts, Value: 3},
+				flux.Attribute{Name: ServiceAttrTagIdempotent, Value: true}),
+			results: []*flux.ServeError{errTransport, nil},
+			calls:   2, success: true,
+		},
+		{
+			name:    "http-rpcretries-not-retry",
+			service: newRetryService(flux.ProtoHttp, "GET", flux.Attribute{Name: flux.ServiceAttrTagRpcRetries, Value: 2}),
+			results: []*flux.ServeError{errTransport, nil},
+			calls:   1,
+		},
+		{
+			name:    "inproc-rpcretries-retry",
+			method:  "GET",
+			service: newRetryService(flux.ProtoInProc, "", flux.Attribute{Name: flux.ServiceAttrTagRpcRetries, Value: 2}),
+			results: []*flux.ServeError{errTransport, errTransport, errTransport},
+			calls:   3,
+		},
+		{
+			name:    "inproc-post-not-retry",
+			method:  "POST",
+			service: newRetryService(flux.ProtoInProc, "", flux.Attribute{Name: flux.ServiceAttrTagRpcRetries, Value: 2}),
+			results: []*flux.ServeError{errTransport, nil},
+			calls:   1,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert2.New(t)
+			method := tc.method
+			if "" == method {
+				method = "GET"
+			}
+			tc.service.EmbeddedAttributes.Attributes = append(tc.service.Attributes, flux.Attribute{Name: ServiceAttrTagRetryBackoff, Value: "1ms"})
+			tr := &retryTransporter{results: tc.results}
+			response, serr := InvokeCodecWithRetry(newRetryContext(method), tr, tc.service)
+			assert.Equal(tc.calls, tr.calls)
+			assert.Equal(tc.success, nil == serr && nil != response)
+		})
+	}
+}
+
+func TestRetryPolicy_BackoffOf(t *testing.T) {
+	assert := assert2.New(t)
+	policy := RetryPolicy{Backoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+	for retry, max := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 300 * time.Millisecond, 10: 300 * time.Millisecond} {
+		backoff := policy.BackoffOf(retry)
+		assert.True(backoff >= max/2 && backoff <= max)
+	}
+	assert.Equal(time.Duration(0), RetryPolicy{}.BackoffOf(1))
+}
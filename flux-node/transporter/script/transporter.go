@@ -0,0 +1,335 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/ext"
+	"github.com/bytepowered/flux/flux-node/logger"
+	"github.com/bytepowered/flux/flux-node/transporter"
+	"github.com/bytepowered/flux/flux-script"
+	"github.com/spf13/cast"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ServiceAttrTagScript 定义内联的JavaScript脚本源码
+	ServiceAttrTagScript = "script"
+	// ServiceAttrTagScriptFile 定义JavaScript脚本文件路径；相对路径基于脚本根目录，脚本文件必须位于脚本根目录内；
+	ServiceAttrTagScriptFile = "script_file"
+)
+
+const (
+	// ConfigKeyScriptRoot 脚本文件的根目录
+	ConfigKeyScriptRoot = "script_root"
+	// ConfigKeyTimeout 脚本执行的默认超时时间；服务可通过 rpctimeout 属性覆盖；
+	ConfigKeyTimeout = "timeout"
+)
+
+// 脚本返回的响应对象字段：包含body字段的对象，作为完整响应；否则整个返回值作为响应Body；
+const (
+	ResponseKeyStatus  = "status"
+	ResponseKeyHeaders = "headers"
+	ResponseKeyBody    = "body"
+)
+
+func init() {
+	ext.RegisterTransporter(flux.ProtoScript, NewTransporter())
+}
+
+var (
+	_ flux.Transporter = new(RpcTransporter)
+	_ flux.Initializer = new(RpcTransporter)
+)
+
+var (
+	ErrScriptNotDefined = errors.New("TRANSPORTER:SCRIPT:NOT_DEFINED")
+	ErrScriptOutOfRoot  = errors.New("TRANSPORTER:SCRIPT:OUT_OF_ROOT")
+)
+
+type (
+	// Option 配置函数
+	Option func(*RpcTransporter)
+	// ArgumentResolver 脚本参数封装函数
+	ArgumentResolver func(arguments []flux.Argument, ctx *flux.Context) (map[string]interface{}, error)
+)
+
+// RpcTransporter 执行TransporterService定义的JavaScript脚本；Method为脚本入口函数名，默认为entry；
+type RpcTransporter struct {
+	engine    *fluxscript.Engine
+	aresolver ArgumentResolver
+	codec     flux.TransportCodec
+	writer    flux.TransportWriter
+	root      string
+	timeout   time.Duration
+	files     map[string]scriptFile
+	filemx    sync.Mutex
+}
+
+type scriptFile struct {
+	scriptId string
+	modTime  time.Time
+}
+
+// WithArgumentResolver 用于配置参数封装实现函数
+func WithArgumentResolver(fun ArgumentResolver) Option {
+	return func(service *RpcTransporter) {
+		service.aresolver = fun
+	}
+}
+
+// WithTransportCodec 用于配置响应数据解析实现函数
+func WithTransportCodec(fun flux.TransportCodec) Option {
+	return func(service *RpcTransporter) {
+		service.codec = fun
+	}
+}
+
+// WithTransportWriter 用于配置响应数据解析实现函数
+func WithTransportWriter(fun flux.TransportWriter) Option {
+	return func(service *RpcTransporter) {
+		service.writer = fun
+	}
+}
+
+func NewTransporter() flux.Transporter {
+	return NewTransporterWith(
+		WithArgumentResolver(DefaultArgumentResolver),
+		WithTransportCodec(NewTransportCodecFunc()),
+		WithTransportWriter(new(transporter.DefaultTransportWriter)),
+	)
+}
+
+func NewTransporterWith(opts ...Option) flux.Transporter {
+	bts := &RpcTransporter{
+		engine:  fluxscript.NewEngine(),
+		root:    "./scripts",
+		timeout: 10 * time.Second,
+		files:   make(map[string]scriptFile, 8),
+	}
+	for _, opt := range opts {
+		opt(bts)
+	}
+	return bts
+}
+
+// Init init transporter
+func (b *RpcTransporter) Init(config *flux.Configuration) error {
+	config.SetDefaults(map[string]interface{}{
+		ConfigKeyScriptRoot: b.root,
+		ConfigKeyTimeout:    b.timeout,
+	})
+	b.root = config.GetString(ConfigKeyScriptRoot)
+	b.timeout = config.GetDuration(ConfigKeyTimeout)
+	logger.Infow("Script transporter init", "script-root", b.root, "timeout", b.timeout)
+	return nil
+}
+
+func (b *RpcTransporter) Writer() flux.TransportWriter {
+	return b.writer
+}
+
+func (b *RpcTransporter) Transport(ctx *flux.Context) {
+	transporter.DoTransport(ctx, b)
+}
+
+func (b *RpcTransporter) InvokeCodec(ctx *flux.Context, service flux.TransporterService) (*flux.ResponseBody, *flux.ServeError) {
+	raw, serr := b.Invoke(ctx, service)
+	if nil != serr {
+		return nil, serr
+	}
+	result, err := b.codec(ctx, raw)
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageTransportDecodeResponse,
+			CauseError: fmt.Errorf("decode script response, err: %w", err),
+		}
+	}
+	return result, nil
+}
+
+func (b *RpcTransporter) Invoke(ctx *flux.Context, service flux.TransporterService) (interface{}, *flux.ServeError) {
+	scriptId, err := b.LoadScript(&service)
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageScriptLoadFailed,
+			CauseError: err,
+		}
+	}
+	arguments, err := b.aresolver(service.Arguments, ctx)
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayInternal,
+			Message:    flux.ErrorMessageScriptAssembleFailed,
+			CauseError: err,
+		}
+	}
+	sctx := fluxscript.NewScriptContext(ctx, ctx.Endpoint().HttpPattern)
+	sctx.Arguments = arguments
+	sctx.Attributes = ctx.Attributes()
+	entry := service.Method
+	if "" == entry {
+		entry = fluxscript.ScriptEntryFunName
+	}
+	timeout := b.timeout
+	if t, err := time.ParseDuration(service.RpcTimeout()); nil == err && t > 0 {
+		timeout = t
+	}
+	evalctx, cancel := context.WithTimeout(ctx.Context(), timeout)
+	defer cancel()
+	ret, err := b.engine.EvalScriptIdContext(evalctx, scriptId, entry, sctx)
+	if nil != err && context.DeadlineExceeded == evalctx.Err() {
+		return nil, &flux.ServeError{
+			StatusCode: http.StatusGatewayTimeout,
+			ErrorCode:  flux.ErrorCodeGatewayTransporter,
+			Message:    flux.ErrorMessageScriptInvokeTimeout,
+			CauseError: err,
+		}
+	}
+	if nil != err {
+		return nil, &flux.ServeError{
+			StatusCode: flux.StatusServerError,
+			ErrorCode:  flux.ErrorCodeGatewayTransporter,
+			Message:    flux.ErrorMessageScriptInvokeFailed,
+			CauseError: err,
+		}
+	}
+	return ret, nil
+}
+
+// LoadScript 加载服务定义的脚本，返回ScriptId；脚本文件修改后自动重新加载；
+// 服务必须定义script或者script_file属性；
+func (b *RpcTransporter) LoadScript(service *flux.TransporterService) (string, error) {
+	if attr, ok := service.GetAttrEx(ServiceAttrTagScript); ok {
+		return b.engine.Load(attr.GetString())
+	}
+	file := service.GetAttr(ServiceAttrTagScriptFile).GetString()
+	if "" == file {
+		return "", ErrScriptNotDefined
+	}
+	path, err := b.ResolvePath(file)
+	if nil != err {
+		return "", err
+	}
+	stat, err := os.Stat(path)
+	if nil != err {
+		return "", fmt.Errorf("stat script file: %s, err: %w", path, err)
+	}
+	b.filemx.Lock()
+	defer b.filemx.Unlock()
+	if f, ok := b.files[path]; ok && f.modTime.Equal(stat.ModTime()) && b.engine.Exist(f.scriptId) {
+		return f.scriptId, nil
+	}
+	source, err := ioutil.ReadFile(path)
+	if nil != err {
+		return "", fmt.Errorf("read script file: %s, err: %w", path, err)
+	}
+	scriptId, err := b.engine.Load(string(source))
+	if nil != err {
+		return "", fmt.Errorf("load script file: %s, err: %w", path, err)
+	}
+	prev, replaced := b.files[path]
+	b.files[path] = scriptFile{scriptId: scriptId, modTime: stat.ModTime()}
+	// 脚本文件内容变更：从脚本引擎中删除不再被引用的旧脚本
+	if replaced && prev.scriptId != scriptId && !b.referenced(prev.scriptId) {
+		b.engine.Remove(prev.scriptId)
+	}
+	return scriptId, nil
+}
+
+// referenced 判断ScriptId是否仍被其它脚本文件引用；调用方需持有filemx锁；
+func (b *RpcTransporter) referenced(scriptId string) bool {
+	for _, f := range b.files {
+		if f.scriptId == scriptId {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolvePath 解析脚本文件的真实路径；相对路径基于脚本根目录；解析后不在脚本根目录内的路径，返回 ErrScriptOutOfRoot 错误；
+func (b *RpcTransporter) ResolvePath(file string) (string, error) {
+	root, err := filepath.Abs(b.root)
+	if nil == err {
+		root, err = filepath.EvalSymlinks(root)
+	}
+	if nil != err {
+		return "", fmt.Errorf("resolve script root: %s, err: %w", b.root, err)
+	}
+	path := file
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	path, err = filepath.EvalSymlinks(path)
+	if nil != err {
+		return "", fmt.Errorf("resolve script file: %s, err: %w", file, err)
+	}
+	if rel, err := filepath.Rel(root, path); nil != err || ".." == rel || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("script file: %s, root: %s, err: %w", file, b.root, ErrScriptOutOfRoot)
+	}
+	return path, nil
+}
+
+// DefaultArgumentResolver 默认参数封装：以参数名为Key的参数值
+func DefaultArgumentResolver(arguments []flux.Argument, ctx *flux.Context) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(arguments))
+	for _, arg := range arguments {
+		if val, err := arg.Resolve(ctx); nil != err {
+			return nil, err
+		} else {
+			values[arg.Name] = val
+		}
+	}
+	return values, nil
+}
+
+// NewTransportCodecFunc 解析脚本返回值：包含body字段的对象，读取其status、headers和body字段作为响应；
+func NewTransportCodecFunc() flux.TransportCodec {
+	return func(ctx *flux.Context, value interface{}) (*flux.ResponseBody, error) {
+		response := &flux.ResponseBody{
+			StatusCode: http.StatusOK,
+			Headers:    make(http.Header, 0),
+			Body:       value,
+		}
+		sm, ok := value.(map[string]interface{})
+		if !ok {
+			return response, nil
+		}
+		body, ok := sm[ResponseKeyBody]
+		if !ok {
+			return response, nil
+		}
+		response.Body = body
+		if status, ok := sm[ResponseKeyStatus]; ok {
+			code, err := cast.ToIntE(status)
+			if nil != err || code < 100 || code > 999 {
+				return nil, fmt.Errorf("invalid script response status: %v", status)
+			}
+			response.StatusCode = code
+		}
+		if headers, ok := sm[ResponseKeyHeaders].(map[string]interface{}); ok {
+			for k, v := range headers {
+				if str, isStr := v.(string); isStr {
+					response.Headers.Add(k, str)
+					continue
+				}
+				for _, hv := range cast.ToStringSlice(v) {
+					response.Headers.Add(k, hv)
+				}
+			}
+		}
+		return response, nil
+	}
+}
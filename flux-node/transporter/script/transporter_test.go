@@ -0,0 +1,132 @@
+package script
+
+import (
+	"errors"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/bytepowered/flux/flux-node/internal/fluxtest"
+	assert2 "github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newScriptService(attrs ...flux.Attribute) flux.TransporterService {
+	return flux.TransporterService{
+		Interface:          "/etc/passwd",
+		EmbeddedAttributes: flux.EmbeddedAttributes{Attributes: attrs},
+	}
+}
+
+func newTestTransporter(t *testing.T) (*RpcTransporter, string) {
+	root, err := ioutil.TempDir("", "flux-script")
+	if nil != err {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = os.RemoveAll(root)
+	})
+	tr := NewTransporter().(*RpcTransporter)
+	if err := tr.Init(flux.NewConfigurationOfMap(map[string]interface{}{
+		ConfigKeyScriptRoot: root,
+		ConfigKeyTimeout:    "100ms",
+	})); nil != err {
+		t.Fatal(err)
+	}
+	return tr, root
+}
+
+func TestRpcTransporter_InvokeScript(t *testing.T) {
+	assert := assert2.New(t)
+	tr, root := newTestTransporter(t)
+	assert.NoError(ioutil.WriteFile(filepath.Join(root, "hello.js"),
+		[]byte(`function entry(ctx) { return {status: 201, body: "hello"}; }`), 0644))
+	for _, service := range []flux.TransporterService{
+		newScriptService(flux.Attribute{Name: ServiceAttrTagScript, Value: `function entry(ctx) { return {status: 201, body: "hello"}; }`}),
+		newScriptService(flux.Attribute{Name: ServiceAttrTagScriptFile, Value: "hello.js"}),
+		newScriptService(flux.Attribute{Name: ServiceAttrTagScriptFile, Value: filepath.Join(root, "hello.js")}),
+	} {
+		resp, serr := tr.InvokeCodec(fluxtest.NewContext(httptest.NewRequest("GET", "http://mocking/api", nil)), service)
+		assert.Nil(serr)
+		assert.Equal(http.StatusCreated, resp.StatusCode)
+		assert.Equal("hello", resp.Body)
+	}
+}
+
+func TestRpcTransporter_LoadScriptRequired(t *testing.T) {
+	assert := assert2.New(t)
+	tr, _ := newTestTransporter(t)
+	// 不使用Interface作为脚本路径
+	_, err := tr.LoadScript(&flux.TransporterService{Interface: "/etc/passwd"})
+	assert.Equal(ErrScriptNotDefined, err)
+}
+
+func TestRpcTransporter_ResolvePathOutOfRoot(t *testing.T) {
+	assert := assert2.New(t)
+	tr, root := newTestTransporter(t)
+	outside, err := ioutil.TempFile("", "outside-*.js")
+	assert.NoError(err)
+	_ = outside.Close()
+	defer os.Remove(outside.Name())
+	assert.NoError(os.Symlink(outside.Name(), filepath.Join(root, "link.js")))
+	for _, file := range []string{outside.Name(), "../" + filepath.Base(outside.Name()), "link.js"} {
+		_, err := tr.ResolvePath(file)
+		assert.True(errors.Is(err, ErrScriptOutOfRoot))
+	}
+	_, serr := tr.Invoke(fluxtest.NewContext(httptest.NewRequest("GET", "http://mocking/api", nil)), newScriptService(flux.Attribute{Name: ServiceAttrTagScriptFile, Value: outside.Name()}))
+	assert.NotNil(serr)
+	assert.Equal(flux.ErrorMessageScriptLoadFailed, serr.Message)
+}
+
+func TestRpcTransporter_InvokeTimeout(t *testing.T) {
+	assert := assert2.New(t)
+	tr, _ := newTestTransporter(t)
+	start := time.Now()
+	_, serr := tr.Invoke(fluxtest.NewContext(httptest.NewRequest("GET", "http://mocking/api", nil)), newScriptService(flux.Attribute{Name: ServiceAttrTagScript, Value: `function entry(ctx) { while (true) {} }`}))
+	assert.NotNil(serr)
+	assert.Equal(http.StatusGatewayTimeout, serr.StatusCode)
+	assert.Equal(flux.ErrorMessageScriptInvokeTimeout, serr.Message)
+	assert.True(time.Since(start) < 2*time.Second)
+	// 服务超时属性覆盖默认配置
+	start = time.Now()
+	_, serr = tr.Invoke(fluxtest.NewContext(httptest.NewRequest("GET", "http://mocking/api", nil)), newScriptService(
+		flux.Attribute{Name: ServiceAttrTagScript, Value: `function entry(ctx) { while (true) {} }`},
+		flux.Attribute{Name: flux.ServiceAttrTagRpcTimeout, Value: "20ms"},
+	))
+	assert.NotNil(serr)
+	assert.True(time.Since(start) < 100*time.Millisecond)
+}
+
+func TestRpcTransporter_LoadScriptReplaced(t *testing.T) {
+	assert := assert2.New(t)
+	tr, root := newTestTransporter(t)
+	path := filepath.Join(root, "replace.js")
+	assert.NoError(ioutil.WriteFile(path, []byte(`function entry(ctx) { return {body: "v1"}; }`), 0644))
+	service := newScriptService(flux.Attribute{Name: ServiceAttrTagScriptFile, Value: "replace.js"})
+	oldId, err := tr.LoadScript(&service)
+	assert.NoError(err)
+	// 相同内容的另一个脚本文件共享ScriptId
+	assert.NoError(ioutil.WriteFile(filepath.Join(root, "shared.js"), []byte(`function entry(ctx) { return {body: "v1"}; }`), 0644))
+	sharedId, err := tr.LoadScript(&flux.TransporterService{EmbeddedAttributes: flux.EmbeddedAttributes{Attributes: []flux.Attribute{
+		{Name: ServiceAttrTagScriptFile, Value: "shared.js"},
+	}}})
+	assert.NoError(err)
+	assert.Equal(oldId, sharedId)
+	assert.NoError(ioutil.WriteFile(path, []byte(`function entry(ctx) { return {body: "v2"}; }`), 0644))
+	assert.NoError(os.Chtimes(path, time.Now(), time.Now().Add(time.Second)))
+	newId, err := tr.LoadScript(&service)
+	assert.NoError(err)
+	assert.True(newId != oldId)
+	assert.True(tr.engine.Exist(oldId))
+	// 旧脚本不再被引用时，从脚本引擎中删除
+	assert.NoError(ioutil.WriteFile(path, []byte(`function entry(ctx) { return {body: "v3"}; }`), 0644))
+	assert.NoError(os.Chtimes(path, time.Now(), time.Now().Add(2*time.Second)))
+	latestId, err := tr.LoadScript(&service)
+	assert.NoError(err)
+	assert.False(tr.engine.Exist(newId))
+	assert.True(tr.engine.Exist(latestId))
+	assert.True(tr.engine.Exist(oldId))
+}
@@ -7,13 +7,20 @@ import (
 	"github.com/bytepowered/flux/flux-node/ext"
 	"github.com/bytepowered/flux/flux-pkg"
 	"github.com/spf13/cast"
+	"io"
 )
 
 func DoTransport(ctx *flux.Context, transport flux.Transporter) {
-	response, serr := transport.InvokeCodec(ctx, ctx.Transporter())
+	response, serr := InvokeCodecWithRetry(ctx, transport, ctx.Transporter())
 	select {
 	case <-ctx.Context().Done():
 		ctx.Logger().Warnw("TRANSPORTER:CANCELED/BYCLIENT")
+		// 释放未被写出的响应数据流
+		if nil != response {
+			if c, ok := response.Body.(io.Closer); ok {
+				_ = c.Close()
+			}
+		}
 		return
 	default:
 		break
@@ -42,7 +49,7 @@ func DoInvokeCodec(ctx *flux.Context, service flux.TransporterService) (*flux.Re
 			CauseError: fmt.Errorf("unknown rpc protocol:%s", proto),
 		}
 	}
-	return transport.InvokeCodec(ctx, service)
+	return InvokeCodecWithRetry(ctx, transport, service)
 }
 
 // DefaultTransportWriter
@@ -70,12 +77,16 @@ func (r *DefaultTransportWriter) Write(ctx *flux.Context, response *flux.Respons
 }
 
 func (r *DefaultTransportWriter) WriteError(ctx *flux.Context, err *flux.ServeError) {
-	bytes, _ := common.SerializeObject(map[string]interface{}{
+	data := map[string]interface{}{
 		"status":  "error",
 		"code":    err.ErrorCode,
 		"message": err.Message,
 		"error":   cast.ToString(err.CauseError),
-	})
+	}
+	if nil != err.Details {
+		data["details"] = err.Details
+	}
+	bytes, _ := common.SerializeObject(data)
 	r.write(ctx, err.StatusCode, bytes)
 }
 
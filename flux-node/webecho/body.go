@@ -0,0 +1,191 @@
+package webecho
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/bytepowered/flux/flux-node"
+	"github.com/labstack/echo/v4"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+const (
+	// 默认内存缓存请求体的最大大小，超出部分写入临时文件
+	defaultBodyBufferSize = 1 << 20
+)
+
+var (
+	// ErrRequestBodyTooLarge 请求体超出大小限制；与 flux.ErrRequestBodyTooLarge 相同；
+	ErrRequestBodyTooLarge = flux.ErrRequestBodyTooLarge
+)
+
+var _ flux.WebBodyLimiter = new(BufferedBody)
+
+// BufferedBody 可重复读取的请求体；请求体在首次读取时缓存：
+// 小于内存阈值的保存在内存中，超出阈值的写入临时文件；请求结束时通过 Release 删除临时文件；
+type BufferedBody struct {
+	source     io.ReadCloser
+	length     int64
+	limit      int64
+	bufferSize int64
+	tempDir    string
+	// cached
+	mu     sync.Mutex
+	loaded bool
+	err    error
+	size   int64
+	memory []byte
+	file   *os.File
+	// request.Body
+	reader io.ReadCloser
+}
+
+// NewBufferedBody 创建请求体缓存；limit 为请求体最大字节数，小于等于0表示不限制；
+// bufferSize 为内存缓存的最大字节数；tempDir 为临时文件目录，为空时使用系统临时目录；
+func NewBufferedBody(source io.ReadCloser, length, limit, bufferSize int64, tempDir string) *BufferedBody {
+	if bufferSize <= 0 {
+		bufferSize = defaultBodyBufferSize
+	}
+	return &BufferedBody{
+		source:     source,
+		length:     length,
+		limit:      limit,
+		bufferSize: bufferSize,
+		tempDir:    tempDir,
+	}
+}
+
+// SetBodyLimit 设置请求体的最大字节数；需在首次读取请求体之前设置；请求体已缓存时，只校验已缓存的大小；
+func (b *BufferedBody) SetBodyLimit(limit int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.limit = limit
+	if b.loaded && nil == b.err && b.exceeded(b.size) {
+		b.err = ErrRequestBodyTooLarge
+	}
+}
+
+// Exceeded 判断请求声明的Content-Length是否超出限制
+func (b *BufferedBody) Exceeded() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.exceeded(b.length)
+}
+
+// NewReader 返回从头读取请求体的Reader；每次调用返回独立的Reader；
+func (b *BufferedBody) NewReader() (io.ReadCloser, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.loaded {
+		b.loaded = true
+		b.err = b.load()
+	}
+	if nil != b.err {
+		return nil, b.err
+	}
+	if nil != b.file {
+		return ioutil.NopCloser(io.NewSectionReader(b.file, 0, b.size)), nil
+	}
+	return ioutil.NopCloser(bytes.NewReader(b.memory)), nil
+}
+
+// Read 作为request.Body读取
+func (b *BufferedBody) Read(p []byte) (int, error) {
+	if nil == b.reader {
+		reader, err := b.NewReader()
+		if nil != err {
+			return 0, err
+		}
+		b.reader = reader
+	}
+	return b.reader.Read(p)
+}
+
+// Close 作为request.Body关闭；缓存数据保留，仍可通过 NewReader 读取；
+func (b *BufferedBody) Close() error {
+	b.reader = nil
+	return nil
+}
+
+// Release 删除缓存的临时文件
+func (b *BufferedBody) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if nil != b.file {
+		_ = b.file.Close()
+		_ = os.Remove(b.file.Name())
+		b.file = nil
+	}
+	b.memory = nil
+	b.loaded, b.err = true, errors.New("request body released")
+}
+
+func (b *BufferedBody) exceeded(size int64) bool {
+	return b.limit > 0 && size > b.limit
+}
+
+func (b *BufferedBody) load() error {
+	if b.exceeded(b.length) {
+		return ErrRequestBodyTooLarge
+	}
+	var source io.Reader = b.source
+	if b.limit > 0 {
+		source = io.LimitReader(b.source, b.limit+1)
+	}
+	memory := new(bytes.Buffer)
+	n, err := io.Copy(memory, io.LimitReader(source, b.bufferSize+1))
+	if nil != err {
+		return err
+	}
+	if n <= b.bufferSize {
+		b.memory, b.size = memory.Bytes(), n
+		return b.checkSize()
+	}
+	// 超出内存阈值，写入临时文件
+	file, err := ioutil.TempFile(b.tempDir, "flux-body-")
+	if nil != err {
+		return fmt.Errorf("create body temp file, error: %w", err)
+	}
+	b.file = file
+	if _, err := file.Write(memory.Bytes()); nil != err {
+		return err
+	}
+	m, err := io.Copy(file, source)
+	if nil != err {
+		return err
+	}
+	b.size = n + m
+	return b.checkSize()
+}
+
+func (b *BufferedBody) checkSize() error {
+	if b.exceeded(b.size) {
+		return ErrRequestBodyTooLarge
+	}
+	return nil
+}
+
+// RepeatableReader Body缓存，允许通过 GetBody 多次读取Body；使用默认的缓存配置，不限制请求体大小；
+func RepeatableReader(next echo.HandlerFunc) echo.HandlerFunc {
+	return RepeatableReaderWith(0, defaultBodyBufferSize, "")(next)
+}
+
+// RepeatableReaderWith 按配置缓存请求体：limit 为默认的请求体最大字节数，可被Endpoint的 body_max_size 属性覆盖；
+// 此中间件在路由之前执行，不校验Content-Length，由路由后按Endpoint的生效限制校验；读取超出限制的请求体时返回错误；
+// bufferSize 为内存缓存的最大字节数；tempDir 为超出内存阈值时写入临时文件的目录；请求处理结束后删除临时文件；
+func RepeatableReaderWith(limit, bufferSize int64, tempDir string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(echo echo.Context) error {
+			request := echo.Request()
+			body := NewBufferedBody(request.Body, request.ContentLength, limit, bufferSize, tempDir)
+			defer body.Release()
+			// 请求体在首次读取时缓存；ParseForm解析后，request.Body无法重读，需要通过GetBody
+			request.GetBody = body.NewReader
+			request.Body = body
+			return next(echo)
+		}
+	}
+}
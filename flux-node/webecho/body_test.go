@@ -0,0 +1,62 @@
+package webecho
+
+import (
+	"bytes"
+	"errors"
+	"github.com/bytepowered/flux/flux-node"
+	assert2 "github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"testing"
+)
+
+func newTestBody(size int, limit, bufferSize int64) *BufferedBody {
+	data := bytes.Repeat([]byte("a"), size)
+	return NewBufferedBody(ioutil.NopCloser(bytes.NewReader(data)), int64(size), limit, bufferSize, "")
+}
+
+func TestBufferedBody_Repeatable(t *testing.T) {
+	assert := assert2.New(t)
+	for _, bufferSize := range []int64{1024, 8} {
+		body := newTestBody(64, 0, bufferSize)
+		for i := 0; i < 2; i++ {
+			reader, err := body.NewReader()
+			assert.NoError(err)
+			data, err := ioutil.ReadAll(reader)
+			assert.NoError(err)
+			assert.Equal(64, len(data))
+		}
+		body.Release()
+	}
+}
+
+func TestBufferedBody_EndpointLimitOverridesDefault(t *testing.T) {
+	assert := assert2.New(t)
+	// 放宽默认限制
+	body := newTestBody(64, 16, 1024)
+	assert.True(body.Exceeded())
+	body.SetBodyLimit(128)
+	assert.False(body.Exceeded())
+	reader, err := body.NewReader()
+	assert.NoError(err)
+	data, _ := ioutil.ReadAll(reader)
+	assert.Equal(64, len(data))
+	// 收紧默认限制
+	body = newTestBody(64, 128, 1024)
+	body.SetBodyLimit(16)
+	assert.True(body.Exceeded())
+	reader, err = body.NewReader()
+	assert.Nil(reader)
+	assert.True(errors.Is(err, flux.ErrRequestBodyTooLarge))
+}
+
+func TestBufferedBody_UnknownLength(t *testing.T) {
+	assert := assert2.New(t)
+	data := bytes.Repeat([]byte("a"), 64)
+	body := NewBufferedBody(ioutil.NopCloser(bytes.NewReader(data)), -1, 32, 8, "")
+	defer body.Release()
+	assert.False(body.Exceeded())
+	_, err := body.NewReader()
+	assert.True(errors.Is(err, flux.ErrRequestBodyTooLarge))
+	_, err = ioutil.ReadAll(body)
+	assert.True(errors.Is(err, flux.ErrRequestBodyTooLarge))
+}
@@ -1,10 +1,8 @@
 package webecho
 
 import (
-	"bytes"
 	"context"
 	"errors"
-	"fmt"
 	"github.com/bytepowered/flux/flux-node"
 	"github.com/bytepowered/flux/flux-node/ext"
 	"github.com/bytepowered/flux/flux-node/internal"
@@ -12,9 +10,8 @@ import (
 	"github.com/bytepowered/flux/flux-pkg"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/labstack/gommon/bytes"
 	"github.com/labstack/gommon/random"
-	"io"
-	"io/ioutil"
 	"net/http"
 	"net/url"
 	"runtime/debug"
@@ -27,6 +24,8 @@ const (
 	ConfigKeyTLSCertFile = "tls_cert_file"
 	ConfigKeyTLSKeyFile  = "tls_key_file"
 	ConfigKeyBodyLimit   = "body_limit"
+	ConfigKeyBodyBuffer  = "body_buffer_size"
+	ConfigKeyBodyTempDir = "body_temp_dir"
 	ConfigKeyCORSEnable  = "cors_enable"
 	ConfigKeyCSRFEnable  = "csrf_enable"
 	ConfigKeyFeatures    = "features"
@@ -49,7 +48,21 @@ func NewWebListener(listenerId string, config *flux.Configuration) flux.WebListe
 func NewWebListenerWith(listenerId string, options *flux.Configuration, identifier flux.WebRequestIdentifier, mws *AdaptMiddleware) flux.WebListener {
 	fluxpkg.Assert("" != listenerId, "empty <listener-id> in web listener configuration")
 	server := echo.New()
-	server.Pre(RepeatableReader)
+	features := options.Sub(ConfigKeyFeatures)
+	// 请求体缓存：小请求体缓存在内存中，超出内存阈值的写入临时文件；BodyLimit 作为默认的请求体大小限制
+	var bodyLimit, bodyBuffer int64 = 0, defaultBodyBufferSize
+	if limit := features.GetString(ConfigKeyBodyLimit); "" != limit {
+		logger.Infof("WebListener(id:%s), feature BODY-LIMIT: enabled, size= %s", listenerId, limit)
+		size, err := bytes.Parse(limit)
+		fluxpkg.AssertNil(err, "invalid <body_limit> in web listener configuration: "+limit)
+		bodyLimit = size
+	}
+	if buffer := features.GetString(ConfigKeyBodyBuffer); "" != buffer {
+		size, err := bytes.Parse(buffer)
+		fluxpkg.AssertNil(err, "invalid <body_buffer_size> in web listener configuration: "+buffer)
+		bodyBuffer = size
+	}
+	server.Pre(RepeatableReaderWith(bodyLimit, bodyBuffer, features.GetString(ConfigKeyBodyTempDir)))
 	server.HideBanner = true
 	server.HidePort = true
 	webListener := &EchoWebListener{
@@ -86,12 +99,6 @@ func NewWebListenerWith(listenerId string, options *flux.Configuration, identifi
 	}
 
 	// Feature
-	features := options.Sub(ConfigKeyFeatures)
-	// 是否设置BodyLimit
-	if limit := features.GetString(ConfigKeyBodyLimit); "" != limit {
-		logger.Infof("WebListener(id:%s), feature BODY-LIMIT: enabled, size= %s", webListener.id, limit)
-		server.Pre(middleware.BodyLimit(limit))
-	}
 	// CORS
 	if enabled := features.GetBool(ConfigKeyCORSEnable); enabled {
 		logger.Infof("WebListener(id:%s), feature CORS: enabled", webListener.id)
@@ -264,29 +271,6 @@ func DefaultIdentifier(ctx interface{}) string {
 	return "fxid_" + random.String(32)
 }
 
-// Body缓存，允许通过 GetBody 多次读取Body
-func RepeatableReader(next echo.HandlerFunc) echo.HandlerFunc {
-	// 包装Http处理错误，统一由HttpErrorHandler处理
-	return func(echo echo.Context) error {
-		request := echo.Request()
-		data, err := ioutil.ReadAll(request.Body)
-		if nil != err {
-			return &flux.ServeError{
-				StatusCode: flux.StatusBadRequest,
-				ErrorCode:  flux.ErrorCodeGatewayInternal,
-				Message:    flux.ErrorMessageRequestPrepare,
-				CauseError: fmt.Errorf("read request body, method: %s, uri:%s, err: %w", request.Method, request.RequestURI, err),
-			}
-		}
-		request.GetBody = func() (io.ReadCloser, error) {
-			return ioutil.NopCloser(bytes.NewBuffer(data)), nil
-		}
-		// 恢复Body，但ParseForm解析后，request.Body无法重读，需要通过GetBody
-		request.Body = ioutil.NopCloser(bytes.NewBuffer(data))
-		return next(echo)
-	}
-}
-
 type AdaptMiddleware struct {
 	BeforeFeature []echo.MiddlewareFunc
 	AfterFeature  []echo.MiddlewareFunc
@@ -21,6 +21,9 @@ type ScriptContext struct {
 	HeaderValues   http.Header `json:"headers"`
 	FormValues     url.Values  `json:"forms"`
 	QueryValues    url.Values  `json:"queries"`
+	// Transporter
+	Arguments  map[string]interface{} `json:"arguments"`
+	Attributes map[string]interface{} `json:"attributes"`
 	// Function
 	GetPathVarFunc   GetVarFunc `json:"getPathVar"`
 	GetQueryVarFunc  GetVarFunc `json:"getQueryVar"`
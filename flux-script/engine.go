@@ -1,6 +1,7 @@
 package fluxscript
 
 import (
+	gocontext "context"
 	"fmt"
 	"github.com/dop251/goja"
 	"reflect"
@@ -50,11 +51,27 @@ func (se *Engine) Remove(scriptId string) {
 
 // EvalScriptId 执行指定ScriptId的脚本，执行指定函数；
 func (se *Engine) EvalScriptId(scriptId string, entryFun string, context interface{}) (v interface{}, err error) {
+	return se.EvalScriptIdContext(gocontext.Background(), scriptId, entryFun, context)
+}
+
+// EvalScriptIdContext 执行指定ScriptId的脚本，执行指定函数；Context被取消或者超时，中断脚本执行并返回错误；
+func (se *Engine) EvalScriptIdContext(ctx gocontext.Context, scriptId string, entryFun string, context interface{}) (v interface{}, err error) {
 	prop, ok := se.scripts.Load(scriptId)
 	if !ok || prop == nil {
 		return nil, fmt.Errorf("script not found, script-id: %s", scriptId)
 	}
 	runtime := goja.New()
+	if nil != ctx.Done() {
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-ctx.Done():
+				runtime.Interrupt(ctx.Err())
+			case <-done:
+			}
+		}()
+	}
 	_, rerr := runtime.RunProgram(prop.(*goja.Program))
 	if nil != rerr {
 		return nil, fmt.Errorf("compile script, error: %w", rerr)
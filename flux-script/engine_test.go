@@ -1,8 +1,10 @@
 package fluxscript
 
 import (
+	"context"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestEvalScript(t *testing.T) {
@@ -77,3 +79,20 @@ function entry(ctx) {
 		asserter.Equal("HelloWorld", v, "eval: return value must match")
 	}
 }
+
+func TestEvalScriptIdContext_Interrupt(t *testing.T) {
+	se := NewEngine()
+	asserter := assert.New(t)
+	id, err := se.Load(`
+function entry(ctx) {
+	while (true) {}
+}
+`)
+	asserter.Nil(err, "load: error must nil")
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = se.EvalScriptIdContext(ctx, id, ScriptEntryFunName, ScriptContext{})
+	asserter.NotNil(err, "eval: must interrupted")
+	asserter.True(time.Since(start) < 2*time.Second, "eval: must interrupted by timeout")
+}
@@ -10,7 +10,10 @@ require (
 	github.com/dlclark/regexp2 v1.4.0 // indirect
 	github.com/dop251/goja v0.0.0-20210317175251-bb14c2267b76
 	github.com/dubbogo/go-zookeeper v1.0.1
+	github.com/dubbogo/gost v1.9.1
+	github.com/fsnotify/fsnotify v1.4.7
 	github.com/go-sourcemap/sourcemap v2.1.3+incompatible // indirect
+	github.com/golang/protobuf v1.3.2
 	github.com/graphql-go/graphql v0.7.9
 	github.com/graphql-go/handler v0.2.3
 	github.com/jinzhu/copier v0.0.0-20190924061706-b57f9002281a // indirect